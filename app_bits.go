@@ -0,0 +1,414 @@
+package cfclient
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultIgnorePatterns are always excluded from the uploaded bits, the same
+// way the cf CLI does it.
+var defaultIgnorePatterns = []string{
+	".cfignore",
+	"/manifest.yml",
+	".gitignore",
+	".git",
+	".hg",
+	".svn",
+	"_darcs",
+	".DS_Store",
+}
+
+// BitsResource describes a file of an application for the resource matching
+// and bits upload endpoints.
+type BitsResource struct {
+	Sha1 string `json:"sha1"`
+	Size int64  `json:"size"`
+	Fn   string `json:"fn,omitempty"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// appFile is a file found on disk which is part of the application bits.
+type appFile struct {
+	BitsResource
+	path string
+}
+
+type ignorePattern struct {
+	glob     string
+	negate   bool
+	anchored bool
+	dirOnly  bool
+}
+
+// cfIgnore holds the patterns of a .cfignore file. Patterns follow the
+// .gitignore syntax: a leading / anchors the pattern to the application
+// root, a trailing / only matches directories and a leading ! re-includes
+// a previously ignored path.
+type cfIgnore []ignorePattern
+
+func newCfIgnore(lines []string) cfIgnore {
+	var ignore cfIgnore
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := ignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasPrefix(line, "**/") {
+			line = line[3:]
+		} else if strings.HasPrefix(line, "/") {
+			p.anchored = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// a pattern containing a slash is relative to the root
+		if strings.Contains(line, "/") {
+			p.anchored = true
+		}
+		p.glob = line
+		ignore = append(ignore, p)
+	}
+	return ignore
+}
+
+func readCfIgnore(dir string) (cfIgnore, error) {
+	lines := append([]string{}, defaultIgnorePatterns...)
+	f, err := os.Open(filepath.Join(dir, ".cfignore"))
+	if os.IsNotExist(err) {
+		return newCfIgnore(lines), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error opening .cfignore")
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error reading .cfignore")
+	}
+	return newCfIgnore(lines), nil
+}
+
+// ignored reports whether the slash separated path, relative to the
+// application root, is excluded by the patterns.
+func (ig cfIgnore) ignored(relPath string, isDir bool) bool {
+	ignored := false
+	for _, p := range ig {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.matches(relPath) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func (p ignorePattern) matches(relPath string) bool {
+	if p.anchored {
+		ok, _ := path.Match(p.glob, relPath)
+		return ok
+	}
+	parts := strings.Split(relPath, "/")
+	for i := range parts {
+		if ok, _ := path.Match(p.glob, strings.Join(parts[i:], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// collectAppFiles walks dir and returns every regular file which is not
+// excluded by .cfignore, along with its SHA1, size and mode.
+func collectAppFiles(dir string) ([]appFile, error) {
+	ignore, err := readCfIgnore(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []appFile
+	err = filepath.Walk(dir, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, fullPath)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if ignore.ignored(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		sum, err := fileSha1(fullPath)
+		if err != nil {
+			return err
+		}
+		files = append(files, appFile{
+			BitsResource: BitsResource{
+				Sha1: sum,
+				Size: info.Size(),
+				Fn:   rel,
+				Mode: fmt.Sprintf("%#o", info.Mode().Perm()),
+			},
+			path: fullPath,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error walking application directory %s", dir)
+	}
+	return files, nil
+}
+
+func fileSha1(fullPath string) (string, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// writeAppZip writes the given files into a zip archive, preserving their
+// modes so that executables stay executable once staged.
+func writeAppZip(w io.Writer, files []appFile) error {
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		mode, err := strconv.ParseUint(file.Mode, 0, 32)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid mode %s for file %s", file.Mode, file.Fn)
+		}
+		header := &zip.FileHeader{
+			Name:   file.Fn,
+			Method: zip.Deflate,
+		}
+		header.SetMode(os.FileMode(mode))
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(file.path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, f)
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Error zipping file %s", file.Fn)
+		}
+	}
+	return zw.Close()
+}
+
+// MatchResources asks the Cloud Controller which of the given resources are
+// already present in its resource cache and returns those.
+func (c *Client) MatchResources(resources []BitsResource) ([]BitsResource, error) {
+	// only the checksum and size are used for matching
+	toMatch := make([]BitsResource, len(resources))
+	for i, resource := range resources {
+		toMatch[i] = BitsResource{Sha1: resource.Sha1, Size: resource.Size}
+	}
+	buf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buf).Encode(toMatch)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error encoding resources")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("PUT", "/v2/resource_match", buf))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error matching resources")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error matching resources, response code: %d", resp.StatusCode)
+	}
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading resource match response")
+	}
+	var matched []BitsResource
+	err = json.Unmarshal(resBody, &matched)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error unmarshalling resource match response")
+	}
+	return matched, nil
+}
+
+// UploadAppBits uploads the content of dir as the bits of the app identified
+// by guid and waits for the upload job to finish. Files listed in the .cfignore
+// file of dir are skipped, as are files already known to the Cloud Controller
+// resource cache.
+func (c *Client) UploadAppBits(guid, dir string) error {
+	zipFile, cached, err := buildAppZip(dir, c.MatchResources)
+	if err != nil {
+		return err
+	}
+	defer removeTempFile(zipFile)
+	return c.uploadAppBits(guid, cached, zipFile)
+}
+
+// buildAppZip zips the files of dir which are not matched by match, and
+// returns the zip along with the matched files. The zip is a temporary file
+// to remove with removeTempFile.
+func buildAppZip(dir string, match func([]BitsResource) ([]BitsResource, error)) (*os.File, []BitsResource, error) {
+	files, err := collectAppFiles(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resources := make([]BitsResource, 0, len(files))
+	for _, file := range files {
+		resources = append(resources, file.BitsResource)
+	}
+	matched, err := match(resources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cached := make(map[string]bool, len(matched))
+	for _, resource := range matched {
+		cached[resource.Sha1] = true
+	}
+	var toUpload []appFile
+	cachedResources := []BitsResource{}
+	for _, file := range files {
+		if cached[file.Sha1] {
+			cachedResources = append(cachedResources, file.BitsResource)
+		} else {
+			toUpload = append(toUpload, file)
+		}
+	}
+
+	zipFile, err := ioutil.TempFile("", "cfclient-bits")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Error creating application zip")
+	}
+	if err := writeAppZip(zipFile, toUpload); err != nil {
+		removeTempFile(zipFile)
+		return nil, nil, errors.Wrap(err, "Error creating application zip")
+	}
+	if _, err := zipFile.Seek(0, 0); err != nil {
+		removeTempFile(zipFile)
+		return nil, nil, err
+	}
+	return zipFile, cachedResources, nil
+}
+
+func removeTempFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// UploadAppZip uploads an already built zip archive as the bits of the app
+// identified by guid and waits for the upload job to finish. No resource
+// matching is performed.
+func (c *Client) UploadAppZip(guid string, zipFile io.Reader) error {
+	return c.uploadAppBits(guid, []BitsResource{}, zipFile)
+}
+
+func (c *Client) uploadAppBits(guid string, resources []BitsResource, zipFile io.Reader) error {
+	resourcesJSON, err := json.Marshal(resources)
+	if err != nil {
+		return errors.Wrap(err, "Error encoding resources")
+	}
+	requestURL := fmt.Sprintf("/v2/apps/%s/bits?async=true", guid)
+	resp, err := c.uploadMultipart("PUT", requestURL, resourcesJSON, "application", "application.zip", zipFile)
+	if err != nil {
+		return errors.Wrapf(err, "Error uploading bits of app %s", guid)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return errors.Errorf("Error uploading bits of app %s, response code: %d", guid, resp.StatusCode)
+	}
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "Error reading upload response")
+	}
+	var job jobResource
+	err = json.Unmarshal(resBody, &job)
+	if err != nil {
+		return errors.Wrap(err, "Error unmarshalling upload job")
+	}
+	return c.waitForV2Job(job)
+}
+
+// uploadMultipart sends the resources JSON, if any, and the content of file
+// as a multipart form, file being named filename in the form field. The form
+// is buffered in a temporary file so that its length is known.
+func (c *Client) uploadMultipart(method, path string, resourcesJSON []byte, field, filename string, file io.Reader) (*http.Response, error) {
+	requestFile, err := ioutil.TempFile("", "cfclient-request")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating upload request")
+	}
+	defer removeTempFile(requestFile)
+
+	writer := multipart.NewWriter(requestFile)
+	if resourcesJSON != nil {
+		if err := writer.WriteField("resources", string(resourcesJSON)); err != nil {
+			return nil, errors.Wrap(err, "Error creating upload request")
+		}
+	}
+	part, err := writer.CreateFormFile(field, filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating upload request")
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, errors.Wrapf(err, "Error copying %s", filename)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "Error creating upload request")
+	}
+	size, err := requestFile.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := requestFile.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, c.Config.ApiAddress+path, requestFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating upload request")
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return c.Do(req)
+}
@@ -0,0 +1,194 @@
+package cfclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func writeTestAppDir() string {
+	dir, err := ioutil.TempDir("", "cfclient-app")
+	So(err, ShouldBeNil)
+	files := map[string]string{
+		"app.js":               "console.log(\"hello\");\n",
+		"lib/cached.js":        "console.log(\"already uploaded\");\n",
+		"manifest.yml":         "applications: []\n",
+		"node_modules/x/a.js":  "ignored\n",
+		"tmp/debug.log":        "ignored\n",
+		"logs/keep.log":        "kept\n",
+		".git/config":          "ignored\n",
+		".cfignore":            "node_modules\n*.log\n!logs/keep.log\n",
+		"bin/start":            "#!/bin/sh\nnode app.js\n",
+		"docs/manifest.yml":    "kept\n",
+		"docs/nested/.gitkeep": "",
+	}
+	for name, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		So(os.MkdirAll(filepath.Dir(fullPath), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(fullPath, []byte(content), 0644), ShouldBeNil)
+	}
+	So(os.Chmod(filepath.Join(dir, "bin", "start"), 0755), ShouldBeNil)
+	return dir
+}
+
+func TestCfIgnore(t *testing.T) {
+	Convey("Match .cfignore patterns", t, func() {
+		ignore := newCfIgnore([]string{
+			"# a comment",
+			"",
+			"/manifest.yml",
+			"*.log",
+			"!important.log",
+			"build/",
+			"config/*.yml",
+			"**/secret",
+		})
+
+		So(ignore.ignored("manifest.yml", false), ShouldBeTrue)
+		So(ignore.ignored("sub/manifest.yml", false), ShouldBeFalse)
+		So(ignore.ignored("debug.log", false), ShouldBeTrue)
+		So(ignore.ignored("a/b/debug.log", false), ShouldBeTrue)
+		So(ignore.ignored("important.log", false), ShouldBeFalse)
+		So(ignore.ignored("build", true), ShouldBeTrue)
+		So(ignore.ignored("build", false), ShouldBeFalse)
+		So(ignore.ignored("config/app.yml", false), ShouldBeTrue)
+		So(ignore.ignored("sub/config/app.yml", false), ShouldBeFalse)
+		So(ignore.ignored("a/b/secret", false), ShouldBeTrue)
+		So(ignore.ignored("app.js", false), ShouldBeFalse)
+	})
+}
+
+func TestCollectAppFiles(t *testing.T) {
+	Convey("Collect app files", t, func() {
+		dir := writeTestAppDir()
+		defer os.RemoveAll(dir)
+
+		files, err := collectAppFiles(dir)
+		So(err, ShouldBeNil)
+
+		byName := map[string]appFile{}
+		for _, file := range files {
+			byName[file.Fn] = file
+		}
+		So(len(files), ShouldEqual, 6)
+		So(byName, ShouldContainKey, "app.js")
+		So(byName, ShouldContainKey, "lib/cached.js")
+		So(byName, ShouldContainKey, "logs/keep.log")
+		So(byName, ShouldContainKey, "bin/start")
+		So(byName, ShouldContainKey, "docs/manifest.yml")
+		So(byName, ShouldContainKey, "docs/nested/.gitkeep")
+
+		So(byName["lib/cached.js"].Sha1, ShouldEqual, "3d7735835e9194bf6b6dfdf967e2b77809f33657")
+		So(byName["lib/cached.js"].Size, ShouldEqual, 33)
+		So(byName["lib/cached.js"].Mode, ShouldEqual, "0644")
+		So(byName["bin/start"].Mode, ShouldEqual, "0755")
+	})
+
+	Convey("Zip app files", t, func() {
+		dir := writeTestAppDir()
+		defer os.RemoveAll(dir)
+
+		files, err := collectAppFiles(dir)
+		So(err, ShouldBeNil)
+
+		buf := bytes.NewBuffer(nil)
+		So(writeAppZip(buf, files), ShouldBeNil)
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		So(err, ShouldBeNil)
+		So(len(zr.File), ShouldEqual, 6)
+		for _, f := range zr.File {
+			if f.Name == "bin/start" {
+				So(f.Mode().Perm(), ShouldEqual, os.FileMode(0755))
+			}
+			if f.Name == "app.js" {
+				So(f.Mode().Perm(), ShouldEqual, os.FileMode(0644))
+			}
+		}
+	})
+}
+
+func TestUploadAppBits(t *testing.T) {
+	Convey("Upload app bits", t, func() {
+		dir := writeTestAppDir()
+		defer os.RemoveAll(dir)
+
+		mocks := []MockRoute{
+			{"PUT", "/v2/resource_match", resourceMatchPayload, "", 200, "", nil},
+			{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/bits", uploadBitsQueuedJobPayload, "", 201, "async=true", nil},
+			{"GET", "/v2/jobs/3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b", uploadBitsFinishedJobPayload, "", 200, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.UploadAppBits("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", dir)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Upload app zip with failed job", t, func() {
+		setup(MockRoute{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/bits", uploadBitsFailedJobPayload, "", 201, "async=true", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.UploadAppZip("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", bytes.NewReader([]byte{}))
+		So(err, ShouldNotBeNil)
+		cfErr, ok := err.(CloudFoundryError)
+		So(ok, ShouldBeTrue)
+		So(cfErr.ErrorCode, ShouldEqual, "CF-AppBitsUploadInvalid")
+		So(cfErr.Code, ShouldEqual, 160001)
+	})
+}
+
+func TestMatchResources(t *testing.T) {
+	Convey("Match resources", t, func() {
+		setup(MockRoute{"PUT", "/v2/resource_match", resourceMatchPayload, "", 200, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		matched, err := client.MatchResources([]BitsResource{
+			{Sha1: "3d7735835e9194bf6b6dfdf967e2b77809f33657", Size: 33, Fn: "lib/cached.js"},
+			{Sha1: "0a4d55a8d778e5022fab701977c5d840bbc486d0", Size: 12, Fn: "app.js"},
+		})
+		So(err, ShouldBeNil)
+		So(len(matched), ShouldEqual, 1)
+		So(matched[0].Sha1, ShouldEqual, "3d7735835e9194bf6b6dfdf967e2b77809f33657")
+	})
+
+	Convey("Match resources with unexpected response code", t, func() {
+		setup(MockRoute{"PUT", "/v2/resource_match", resourceMatchPayload, "", 202, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.MatchResources([]BitsResource{
+			{Sha1: "3d7735835e9194bf6b6dfdf967e2b77809f33657", Size: 33, Fn: "lib/cached.js"},
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "response code: 202")
+	})
+}
@@ -0,0 +1,218 @@
+package cfclient
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCrashWindow    = time.Hour
+	defaultCrashThreshold = 3
+)
+
+// CrashAnalysisOptions configures AnalyzeAppCrashes and AnalyzeCrashEvents.
+// Zero values are replaced by their defaults.
+type CrashAnalysisOptions struct {
+	// Window is the period over which crashes are analyzed, defaults to
+	// one hour.
+	Window time.Duration
+	// Until is the end of the window, defaults to now.
+	Until time.Time
+	// Threshold is the number of crashes of a single instance within the
+	// window from which an app is considered crash looping, defaults to 3.
+	Threshold int
+	// AppGuids restricts the analysis to the given apps. By default the
+	// crashes of every app visible to the user are analyzed.
+	AppGuids []string
+}
+
+// CrashReport is the result of a crash analysis.
+type CrashReport struct {
+	Since time.Time
+	Until time.Time
+	// Apps lists the apps which crashed, the most crashing first.
+	Apps []AppCrashReport
+}
+
+// AppCrashReport summarizes the crashes of an app.
+type AppCrashReport struct {
+	AppGuid string
+	AppName string
+	Crashes int
+	// CrashRate is the number of crashes per hour over the window.
+	CrashRate float64
+	// Instances lists the crashed instances by index.
+	Instances          []InstanceCrashReport
+	TopExitReason      string
+	TopExitDescription string
+	FirstCrash         time.Time
+	LastCrash          time.Time
+	// CrashLooping is set when an instance crashed at least Threshold times.
+	CrashLooping bool
+}
+
+// InstanceCrashReport summarizes the crashes of an app instance.
+type InstanceCrashReport struct {
+	Index               int
+	Crashes             int
+	CrashRate           float64
+	LastCrash           time.Time
+	LastExitDescription string
+}
+
+// CrashLooping returns the reports of the apps flagged as crash looping.
+func (r CrashReport) CrashLooping() []AppCrashReport {
+	var looping []AppCrashReport
+	for _, app := range r.Apps {
+		if app.CrashLooping {
+			looping = append(looping, app)
+		}
+	}
+	return looping
+}
+
+func (o *CrashAnalysisOptions) setDefaults() {
+	if o.Window == 0 {
+		o.Window = defaultCrashWindow
+	}
+	if o.Until.IsZero() {
+		o.Until = time.Now()
+	}
+	if o.Threshold == 0 {
+		o.Threshold = defaultCrashThreshold
+	}
+}
+
+// AnalyzeAppCrashes fetches the app.crash events of the analysis window and
+// builds a crash report out of them, see AnalyzeCrashEvents.
+func (c *Client) AnalyzeAppCrashes(opts CrashAnalysisOptions) (CrashReport, error) {
+	opts.setDefaults()
+	since := opts.Until.Add(-opts.Window)
+	queries := []AppEventQuery{
+		{Filter: FilterTimestamp, Operator: ">", Value: since.UTC().Format(time.RFC3339)},
+	}
+	if len(opts.AppGuids) > 0 {
+		queries = append(queries, AppEventQuery{Filter: FilterActee, Operator: "IN", Value: strings.Join(opts.AppGuids, ",")})
+	}
+	events, err := c.ListAppEventsByQuery(AppCrash, queries)
+	if err != nil {
+		return CrashReport{}, err
+	}
+	return AnalyzeCrashEvents(events, opts), nil
+}
+
+// AnalyzeCrashEvents groups app.crash events by app and instance index over
+// the analysis window and computes crash rates and the most common exit
+// reasons. Events of other types or outside of the window are ignored.
+func AnalyzeCrashEvents(events []AppEventEntity, opts CrashAnalysisOptions) CrashReport {
+	opts.setDefaults()
+	report := CrashReport{Since: opts.Until.Add(-opts.Window), Until: opts.Until}
+	hours := opts.Window.Hours()
+
+	filter := map[string]bool{}
+	for _, guid := range opts.AppGuids {
+		filter[guid] = true
+	}
+
+	type appCrashes struct {
+		report       AppCrashReport
+		instances    map[int]*InstanceCrashReport
+		reasons      map[string]int
+		descriptions map[string]int
+	}
+	apps := map[string]*appCrashes{}
+	for _, event := range events {
+		if event.EventType != AppCrash || event.Timestamp.Before(report.Since) || event.Timestamp.After(report.Until) {
+			continue
+		}
+		if len(filter) > 0 && !filter[event.Actee] {
+			continue
+		}
+		app, ok := apps[event.Actee]
+		if !ok {
+			app = &appCrashes{
+				report:       AppCrashReport{AppGuid: event.Actee, AppName: event.ActeeName},
+				instances:    map[int]*InstanceCrashReport{},
+				reasons:      map[string]int{},
+				descriptions: map[string]int{},
+			}
+			apps[event.Actee] = app
+		}
+		app.report.Crashes++
+		if app.report.FirstCrash.IsZero() || event.Timestamp.Before(app.report.FirstCrash) {
+			app.report.FirstCrash = event.Timestamp
+		}
+		if event.Timestamp.After(app.report.LastCrash) {
+			app.report.LastCrash = event.Timestamp
+		}
+		if event.MetaData.ExitReason != "" {
+			app.reasons[event.MetaData.ExitReason]++
+		}
+		if event.MetaData.ExitDescription != "" {
+			app.descriptions[event.MetaData.ExitDescription]++
+		}
+
+		// older Cloud Controllers report the index in the request metadata
+		index := int(event.MetaData.Index)
+		if index == 0 {
+			index = int(event.MetaData.Request.Index)
+		}
+		instance, ok := app.instances[index]
+		if !ok {
+			instance = &InstanceCrashReport{Index: index}
+			app.instances[index] = instance
+		}
+		instance.Crashes++
+		if !event.Timestamp.Before(instance.LastCrash) {
+			instance.LastCrash = event.Timestamp
+			instance.LastExitDescription = event.MetaData.ExitDescription
+		}
+	}
+
+	for _, app := range apps {
+		app.report.CrashRate = float64(app.report.Crashes) / hours
+		app.report.TopExitReason = mostCommon(app.reasons)
+		app.report.TopExitDescription = mostCommon(app.descriptions)
+		for _, instance := range app.instances {
+			instance.CrashRate = float64(instance.Crashes) / hours
+			if instance.Crashes >= opts.Threshold {
+				app.report.CrashLooping = true
+			}
+			app.report.Instances = append(app.report.Instances, *instance)
+		}
+		sort.Sort(instanceCrashReports(app.report.Instances))
+		report.Apps = append(report.Apps, app.report)
+	}
+	sort.Sort(appCrashReports(report.Apps))
+	return report
+}
+
+// mostCommon returns the key with the highest count, the smallest key on
+// ties so that reports are stable.
+func mostCommon(counts map[string]int) string {
+	var best string
+	for key, count := range counts {
+		if count > counts[best] || (count == counts[best] && key < best) {
+			best = key
+		}
+	}
+	return best
+}
+
+type appCrashReports []AppCrashReport
+
+func (r appCrashReports) Len() int      { return len(r) }
+func (r appCrashReports) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r appCrashReports) Less(i, j int) bool {
+	if r[i].Crashes != r[j].Crashes {
+		return r[i].Crashes > r[j].Crashes
+	}
+	return r[i].AppGuid < r[j].AppGuid
+}
+
+type instanceCrashReports []InstanceCrashReport
+
+func (r instanceCrashReports) Len() int           { return len(r) }
+func (r instanceCrashReports) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r instanceCrashReports) Less(i, j int) bool { return r[i].Index < r[j].Index }
@@ -0,0 +1,104 @@
+package cfclient
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func crashEvent(appGuid, appName string, index int, timestamp, reason, description string) AppEventEntity {
+	event := AppEventEntity{
+		EventType: AppCrash,
+		Actee:     appGuid,
+		ActeeName: appName,
+	}
+	event.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+	event.MetaData.Index = float64(index)
+	event.MetaData.ExitReason = reason
+	event.MetaData.ExitDescription = description
+	return event
+}
+
+func TestAnalyzeCrashEvents(t *testing.T) {
+	Convey("Analyze crash events", t, func() {
+		until, _ := time.Parse(time.RFC3339, "2017-09-12T12:00:00Z")
+		events := []AppEventEntity{
+			crashEvent("app-1", "web", 0, "2017-09-12T11:00:00Z", "CRASHED", "Exited with status 1"),
+			crashEvent("app-1", "web", 0, "2017-09-12T11:10:00Z", "CRASHED", "Exited with status 1"),
+			crashEvent("app-1", "web", 1, "2017-09-12T11:15:00Z", "CRASHED", "Exited with status 137 (out of memory)"),
+			crashEvent("app-1", "web", 0, "2017-09-12T11:20:00Z", "CRASHED", "Exited with status 137 (out of memory)"),
+			crashEvent("app-2", "worker", 2, "2017-09-12T11:30:00Z", "CRASHED", "Instance never healthy"),
+			// outside of the window
+			crashEvent("app-2", "worker", 2, "2017-09-12T09:00:00Z", "CRASHED", "Instance never healthy"),
+			{EventType: AppStart, Actee: "app-3"},
+		}
+
+		report := AnalyzeCrashEvents(events, CrashAnalysisOptions{Window: 2 * time.Hour, Until: until})
+		So(report.Since, ShouldResemble, until.Add(-2*time.Hour))
+		So(len(report.Apps), ShouldEqual, 2)
+
+		app := report.Apps[0]
+		So(app.AppGuid, ShouldEqual, "app-1")
+		So(app.AppName, ShouldEqual, "web")
+		So(app.Crashes, ShouldEqual, 4)
+		So(app.CrashRate, ShouldEqual, 2)
+		So(app.TopExitReason, ShouldEqual, "CRASHED")
+		So(app.TopExitDescription, ShouldEqual, "Exited with status 1")
+		So(app.FirstCrash.Format(time.RFC3339), ShouldEqual, "2017-09-12T11:00:00Z")
+		So(app.LastCrash.Format(time.RFC3339), ShouldEqual, "2017-09-12T11:20:00Z")
+		So(app.CrashLooping, ShouldBeTrue)
+		So(len(app.Instances), ShouldEqual, 2)
+		So(app.Instances[0].Index, ShouldEqual, 0)
+		So(app.Instances[0].Crashes, ShouldEqual, 3)
+		So(app.Instances[0].CrashRate, ShouldEqual, 1.5)
+		So(app.Instances[0].LastExitDescription, ShouldEqual, "Exited with status 137 (out of memory)")
+		So(app.Instances[1].Index, ShouldEqual, 1)
+
+		app = report.Apps[1]
+		So(app.AppGuid, ShouldEqual, "app-2")
+		So(app.Crashes, ShouldEqual, 1)
+		So(app.CrashLooping, ShouldBeFalse)
+
+		looping := report.CrashLooping()
+		So(len(looping), ShouldEqual, 1)
+		So(looping[0].AppGuid, ShouldEqual, "app-1")
+	})
+
+	Convey("Analyze crash events of given apps", t, func() {
+		until, _ := time.Parse(time.RFC3339, "2017-09-12T12:00:00Z")
+		events := []AppEventEntity{
+			crashEvent("app-1", "web", 0, "2017-09-12T11:00:00Z", "CRASHED", "Exited with status 1"),
+			crashEvent("app-2", "worker", 0, "2017-09-12T11:30:00Z", "CRASHED", "Exited with status 1"),
+		}
+		report := AnalyzeCrashEvents(events, CrashAnalysisOptions{Until: until, AppGuids: []string{"app-2"}, Threshold: 1})
+		So(len(report.Apps), ShouldEqual, 1)
+		So(report.Apps[0].AppGuid, ShouldEqual, "app-2")
+		So(report.Apps[0].CrashLooping, ShouldBeTrue)
+	})
+}
+
+func TestAnalyzeAppCrashes(t *testing.T) {
+	Convey("Analyze app crashes", t, func() {
+		setup(MockRoute{"GET", "/v2/events", appCrashEventsPayload, "", 200, "q=type:app.crash&q=timestamp>2017-09-12T10:00:00Z&q=actee IN 9902530c-c634-4864-a189-71d763cb12e2,b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		until, _ := time.Parse(time.RFC3339, "2017-09-12T11:00:00Z")
+		report, err := client.AnalyzeAppCrashes(CrashAnalysisOptions{
+			Until:    until,
+			AppGuids: []string{"9902530c-c634-4864-a189-71d763cb12e2", "b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c"},
+		})
+		So(err, ShouldBeNil)
+		So(len(report.Apps), ShouldEqual, 1)
+		So(report.Apps[0].AppName, ShouldEqual, "test-env")
+		So(report.Apps[0].Instances[0].Index, ShouldEqual, 1)
+		So(report.Apps[0].TopExitDescription, ShouldEqual, "APP/PROC/WEB: Exited with status 1")
+		So(report.CrashLooping(), ShouldBeEmpty)
+	})
+}
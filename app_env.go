@@ -0,0 +1,243 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// AppEnvApply tells how an environment change is applied to a started app.
+type AppEnvApply int
+
+const (
+	// AppEnvNoApply leaves the app running with its previous environment
+	AppEnvNoApply AppEnvApply = iota
+	// AppEnvRestart restarts the app so that it runs with the new environment
+	AppEnvRestart
+	// AppEnvRestage restages the app so that buildpacks see the new
+	// environment too
+	AppEnvRestage
+)
+
+// SetAppEnv adds or updates the given user provided environment variables of
+// the V2 app identified by guid, other variables are kept. It reports
+// whether the environment changed, in which case the app is restarted or
+// restaged according to apply if it is started.
+func (c *Client) SetAppEnv(guid string, vars map[string]interface{}, apply AppEnvApply) (bool, error) {
+	return c.updateAppEnv(guid, apply, func(env map[string]interface{}) {
+		for k, v := range vars {
+			env[k] = v
+		}
+	})
+}
+
+// UnsetAppEnv removes the given user provided environment variables of the
+// V2 app identified by guid. See SetAppEnv for the meaning of apply.
+func (c *Client) UnsetAppEnv(guid string, keys []string, apply AppEnvApply) (bool, error) {
+	return c.updateAppEnv(guid, apply, func(env map[string]interface{}) {
+		for _, k := range keys {
+			delete(env, k)
+		}
+	})
+}
+
+// ReplaceAppEnv replaces all user provided environment variables of the V2
+// app identified by guid with vars. See SetAppEnv for the meaning of apply.
+func (c *Client) ReplaceAppEnv(guid string, vars map[string]interface{}, apply AppEnvApply) (bool, error) {
+	return c.updateAppEnv(guid, apply, func(env map[string]interface{}) {
+		for k := range env {
+			delete(env, k)
+		}
+		for k, v := range vars {
+			env[k] = v
+		}
+	})
+}
+
+func (c *Client) updateAppEnv(guid string, apply AppEnvApply, update func(map[string]interface{})) (bool, error) {
+	app, err := c.GetAppByGuid(guid)
+	if err != nil {
+		return false, err
+	}
+	env := make(map[string]interface{}, len(app.Environment))
+	for k, v := range app.Environment {
+		env[k] = v
+	}
+	update(env)
+
+	// environment_json replaces the whole environment, it is sent even when
+	// empty to remove every variable
+	body, err := json.Marshal(map[string]interface{}{"environment_json": env})
+	if err != nil {
+		return false, errors.Wrap(err, "Error encoding app environment")
+	}
+	// compare the values the way the Cloud Controller returns them
+	var decoded struct {
+		Environment map[string]interface{} `json:"environment_json"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return false, errors.Wrap(err, "Error encoding app environment")
+	}
+	if reflect.DeepEqual(decoded.Environment, app.Environment) || (len(env) == 0 && len(app.Environment) == 0) {
+		return false, nil
+	}
+	buf := bytes.NewBuffer(body)
+	resp, err := c.DoRequest(c.NewRequestWithBody("PUT", "/v2/apps/"+guid, buf))
+	if err != nil {
+		return false, errors.Wrapf(err, "Error updating environment of app %s", guid)
+	}
+	app, err = c.handleAppResp(resp)
+	if err != nil {
+		return false, err
+	}
+	if app.State != AppStarted {
+		return true, nil
+	}
+	switch apply {
+	case AppEnvRestart:
+		_, err = c.RestartV2App(guid)
+	case AppEnvRestage:
+		_, err = c.RestageApp(guid)
+	}
+	return true, err
+}
+
+// v3AppEnvVars is the body of /v3/apps/:guid/environment_variables. In
+// requests, a nil value deletes the variable.
+type v3AppEnvVars struct {
+	Var map[string]*string `json:"var"`
+}
+
+// GetV3AppEnvVars returns the user provided environment variables of the V3
+// app identified by guid.
+func (c *Client) GetV3AppEnvVars(guid string) (map[string]string, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/apps/"+guid+"/environment_variables"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting environment variables of app %s", guid)
+	}
+	return decodeV3AppEnvVars(resp)
+}
+
+// SetV3AppEnv adds or updates the given environment variables of the V3 app
+// identified by guid, other variables are kept. It reports whether the
+// environment changed, in which case the app is restarted if apply is
+// AppEnvRestart and it is started. Restaging a V3 app requires a new build,
+// AppEnvRestage is not supported.
+func (c *Client) SetV3AppEnv(guid string, vars map[string]string, apply AppEnvApply) (bool, error) {
+	return c.updateV3AppEnv(guid, apply, func(env map[string]string) map[string]*string {
+		changes := map[string]*string{}
+		for k, v := range vars {
+			if current, ok := env[k]; !ok || current != v {
+				value := v
+				changes[k] = &value
+			}
+		}
+		return changes
+	})
+}
+
+// UnsetV3AppEnv removes the given environment variables of the V3 app
+// identified by guid. See SetV3AppEnv for the meaning of apply.
+func (c *Client) UnsetV3AppEnv(guid string, keys []string, apply AppEnvApply) (bool, error) {
+	return c.updateV3AppEnv(guid, apply, func(env map[string]string) map[string]*string {
+		changes := map[string]*string{}
+		for _, k := range keys {
+			if _, ok := env[k]; ok {
+				changes[k] = nil
+			}
+		}
+		return changes
+	})
+}
+
+// ReplaceV3AppEnv replaces all environment variables of the V3 app
+// identified by guid with vars. See SetV3AppEnv for the meaning of apply.
+func (c *Client) ReplaceV3AppEnv(guid string, vars map[string]string, apply AppEnvApply) (bool, error) {
+	return c.updateV3AppEnv(guid, apply, func(env map[string]string) map[string]*string {
+		changes := map[string]*string{}
+		for k := range env {
+			if _, ok := vars[k]; !ok {
+				changes[k] = nil
+			}
+		}
+		for k, v := range vars {
+			if current, ok := env[k]; !ok || current != v {
+				value := v
+				changes[k] = &value
+			}
+		}
+		return changes
+	})
+}
+
+func (c *Client) updateV3AppEnv(guid string, apply AppEnvApply, diff func(map[string]string) map[string]*string) (bool, error) {
+	if apply == AppEnvRestage {
+		return false, errors.New("Restaging V3 apps is not supported, create a new build instead")
+	}
+	env, err := c.GetV3AppEnvVars(guid)
+	if err != nil {
+		return false, err
+	}
+	changes := diff(env)
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = json.NewEncoder(buf).Encode(v3AppEnvVars{Var: changes})
+	if err != nil {
+		return false, errors.Wrap(err, "Error encoding environment variables")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("PATCH", "/v3/apps/"+guid+"/environment_variables", buf))
+	if err != nil {
+		return false, errors.Wrapf(err, "Error updating environment variables of app %s", guid)
+	}
+	if _, err := decodeV3AppEnvVars(resp); err != nil {
+		return false, err
+	}
+	if apply != AppEnvRestart {
+		return true, nil
+	}
+	return true, c.restartStartedV3App(guid)
+}
+
+func decodeV3AppEnvVars(resp *http.Response) (map[string]string, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error requesting environment variables, response code: %d", resp.StatusCode)
+	}
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading environment variables")
+	}
+	var envVars v3AppEnvVars
+	err = json.Unmarshal(resBody, &envVars)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error unmarshalling environment variables")
+	}
+	env := make(map[string]string, len(envVars.Var))
+	for k, v := range envVars.Var {
+		if v != nil {
+			env[k] = *v
+		}
+	}
+	return env, nil
+}
+
+// restartStartedV3App restarts the V3 app identified by guid unless it is
+// stopped.
+func (c *Client) restartStartedV3App(guid string) error {
+	app, err := c.GetV3App(guid)
+	if err != nil {
+		return err
+	}
+	if app.State != AppStarted {
+		return nil
+	}
+	_, err = c.RestartApp(guid)
+	return err
+}
@@ -0,0 +1,155 @@
+package cfclient
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSetAppEnv(t *testing.T) {
+	Convey("Set app env", t, func() {
+		body := `{"environment_json":{"BAZ":"qux","FOO":"bar"}}`
+		mocks := []MockRoute{
+			{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 200, "inline-relations-depth=2", nil},
+			{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 201, "", &body},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		changed, err := client.SetAppEnv("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", map[string]interface{}{"BAZ": "qux"}, AppEnvRestart)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+	})
+
+	Convey("Set unchanged app env", t, func() {
+		setup(MockRoute{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 200, "inline-relations-depth=2", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		changed, err := client.SetAppEnv("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", map[string]interface{}{"FOO": "bar"}, AppEnvRestart)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeFalse)
+	})
+}
+
+func TestUnsetAppEnv(t *testing.T) {
+	Convey("Unset app env and restage", t, func() {
+		body := `{"environment_json":{}}`
+		mocks := []MockRoute{
+			{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 200, "inline-relations-depth=2", nil},
+			{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", updateAppPayload, "", 201, "", &body},
+			{"POST", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/restage", updateAppPayload, "", 201, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		changed, err := client.UnsetAppEnv("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", []string{"FOO", "MISSING"}, AppEnvRestage)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+	})
+}
+
+func TestReplaceAppEnv(t *testing.T) {
+	Convey("Replace app env", t, func() {
+		body := `{"environment_json":{"LEVEL":3}}`
+		mocks := []MockRoute{
+			{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 200, "inline-relations-depth=2", nil},
+			{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 201, "", &body},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		changed, err := client.ReplaceAppEnv("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", map[string]interface{}{"LEVEL": 3}, AppEnvNoApply)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+	})
+}
+
+func TestV3AppEnv(t *testing.T) {
+	Convey("Get V3 app env", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/environment_variables", v3AppEnvVarsPayload, "", 200, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		env, err := client.GetV3AppEnvVars("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(env, ShouldResemble, map[string]string{"RAILS_ENV": "production", "DEBUG": "true"})
+	})
+
+	Convey("Set V3 app env", t, func() {
+		body := `{"var":{"DEBUG":"false"}}`
+		mocks := []MockRoute{
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/environment_variables", v3AppEnvVarsPayload, "", 200, "", nil},
+			{"PATCH", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/environment_variables", v3AppEnvVarsPayload, "", 200, "", &body},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		changed, err := client.SetV3AppEnv("1cb006ee-fb05-47e1-b541-c34179ddc446", map[string]string{"DEBUG": "false", "RAILS_ENV": "production"}, AppEnvNoApply)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+
+		changed, err = client.UnsetV3AppEnv("1cb006ee-fb05-47e1-b541-c34179ddc446", []string{"MISSING"}, AppEnvNoApply)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeFalse)
+
+		_, err = client.SetV3AppEnv("1cb006ee-fb05-47e1-b541-c34179ddc446", map[string]string{"DEBUG": "false"}, AppEnvRestage)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Replace V3 app env and restart", t, func() {
+		body := `{"var":{"DEBUG":null,"NEW":"1","RAILS_ENV":null}}`
+		mocks := []MockRoute{
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/environment_variables", v3AppEnvVarsPayload, "", 200, "", nil},
+			{"PATCH", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/environment_variables", v3AppEnvVarsUpdatedPayload, "", 200, "", &body},
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446", v3AppStartedPayload, "", 200, "", nil},
+			{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/actions/restart", v3AppStartedPayload, "", 200, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		changed, err := client.ReplaceV3AppEnv("1cb006ee-fb05-47e1-b541-c34179ddc446", map[string]string{"NEW": "1"}, AppEnvRestart)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+	})
+}
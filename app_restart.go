@@ -0,0 +1,149 @@
+package cfclient
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RollingRestartOptions configures RollingRestart. Zero values are replaced
+// by their defaults.
+type RollingRestartOptions struct {
+	// MaxUnavailable is the number of instances restarted at once, defaults
+	// to 1.
+	MaxUnavailable int
+	// Timeout is the maximum time to wait for the replacements of a batch
+	// of instances to be running, defaults to 5 minutes.
+	Timeout time.Duration
+	// Interval is the time between two polls of the instances, defaults to
+	// 1 second.
+	Interval time.Duration
+	// IgnoreCrashes keeps waiting when a replacement crashes instead of
+	// aborting the restart with an AppCrashedError.
+	IgnoreCrashes bool
+	// Progress is called after every batch of restarted instances.
+	Progress func(RollingRestartProgress)
+}
+
+// RollingRestartProgress is the state of a rolling restart after a batch.
+type RollingRestartProgress struct {
+	// Indexes are the indexes of the instances of the batch.
+	Indexes []int
+	// Restarted is the number of instances restarted so far.
+	Restarted int
+	// Total is the number of instances to restart.
+	Total int
+}
+
+func (o *RollingRestartOptions) setDefaults() {
+	if o.MaxUnavailable <= 0 {
+		o.MaxUnavailable = 1
+	}
+	if o.Timeout == 0 {
+		o.Timeout = defaultWaitTimeout
+	}
+	if o.Interval == 0 {
+		o.Interval = defaultWaitInterval
+	}
+}
+
+// rollingInstance tracks an instance killed by a rolling restart.
+type rollingInstance struct {
+	// since is when the killed instance started
+	since time.Time
+	// down is set once the instance was seen not running after the kill
+	down bool
+}
+
+// RollingRestart restarts the instances of the V2 app identified by guid
+// without downtime. Instances are killed MaxUnavailable at a time, in index
+// order, and the next batch is only killed once the replacements of the
+// previous one are running. It stops at the first failing batch, leaving the
+// remaining instances untouched.
+func (c *Client) RollingRestart(guid string, opts RollingRestartOptions) error {
+	opts.setDefaults()
+	instances, err := c.GetAppInstances(guid)
+	if err != nil {
+		return err
+	}
+	indexes := make([]int, 0, len(instances))
+	for index := range instances {
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid index %s of app %s", index, guid)
+		}
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	for start := 0; start < len(indexes); start += opts.MaxUnavailable {
+		end := start + opts.MaxUnavailable
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		if start > 0 {
+			instances, err = c.GetAppInstances(guid)
+			if err != nil {
+				return err
+			}
+		}
+		batch := map[string]*rollingInstance{}
+		for _, i := range indexes[start:end] {
+			index := strconv.Itoa(i)
+			batch[index] = &rollingInstance{since: instances[index].Since.Time}
+			if err := c.KillAppInstance(guid, index); err != nil {
+				return err
+			}
+		}
+		if err := c.waitForReplacements(guid, batch, opts); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			opts.Progress(RollingRestartProgress{
+				Indexes:   indexes[start:end],
+				Restarted: end,
+				Total:     len(indexes),
+			})
+		}
+	}
+	return nil
+}
+
+// waitForReplacements polls the instances of the app until every instance
+// of batch was replaced by a running one.
+func (c *Client) waitForReplacements(guid string, batch map[string]*rollingInstance, opts RollingRestartOptions) error {
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		time.Sleep(opts.Interval)
+		instances, err := c.GetAppInstances(guid)
+		if err != nil {
+			return err
+		}
+		done := true
+		for index, r := range batch {
+			instance, ok := instances[index]
+			if ok && instance.State == AppInstanceRunning {
+				if r.down || instance.Since.After(r.since) {
+					continue
+				}
+			} else {
+				// the killed instance may still be reported running for
+				// a while, it is only replaced once it went down
+				r.down = true
+			}
+			done = false
+			if ok && !opts.IgnoreCrashes && (instance.State == AppInstanceCrashed || instance.State == AppInstanceFlapping) {
+				i, _ := strconv.Atoi(index)
+				return AppCrashedError{AppGuid: guid, Index: i}
+			}
+		}
+		if done {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("Timed out after %s waiting for instances of app %s to be running", opts.Timeout, guid)
+		}
+	}
+}
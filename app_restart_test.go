@@ -0,0 +1,201 @@
+package cfclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeInstances simulates the instances of an app being killed and
+// replaced by the Cloud Controller.
+type fakeInstances struct {
+	sync.Mutex
+	instances map[string]AppInstance
+	// pending counts the polls left before a killed instance is running
+	pending map[string]int
+	// stale is the number of polls a killed instance is still reported
+	// running with its original since, staleLeft counts them down
+	stale     int
+	staleLeft map[string]int
+	killed    []string
+	crash     bool
+	// maxDown is the maximum number of instances killed at once
+	maxDown int
+}
+
+func handleFakeInstances(guid string, count int, crash bool) *fakeInstances {
+	f := &fakeInstances{
+		instances: map[string]AppInstance{},
+		pending:   map[string]int{},
+		staleLeft: map[string]int{},
+		crash:     crash,
+	}
+	for i := 0; i < count; i++ {
+		f.instances[strconv.Itoa(i)] = AppInstance{State: AppInstanceRunning, Since: sinceTime{time.Unix(1000, 0)}}
+	}
+	mux.HandleFunc("/v2/apps/"+guid+"/instances", func(w http.ResponseWriter, r *http.Request) {
+		f.Lock()
+		defer f.Unlock()
+		raw := map[string]map[string]interface{}{}
+		for index, instance := range f.instances {
+			if f.staleLeft[index] > 0 {
+				f.staleLeft[index]--
+				if f.staleLeft[index] == 0 {
+					f.instances[index] = f.killedInstance()
+				}
+			} else if f.pending[index] > 0 {
+				f.pending[index]--
+				if f.pending[index] == 0 && !f.crash {
+					instance = AppInstance{State: AppInstanceRunning, Since: sinceTime{time.Unix(2000, 0)}}
+					f.instances[index] = instance
+				}
+			}
+			raw[index] = map[string]interface{}{"state": instance.State, "since": instance.Since.Unix()}
+		}
+		json.NewEncoder(w).Encode(raw)
+	})
+	mux.HandleFunc("/v2/apps/"+guid+"/instances/", func(w http.ResponseWriter, r *http.Request) {
+		f.Lock()
+		defer f.Unlock()
+		index := strings.TrimPrefix(r.URL.Path, "/v2/apps/"+guid+"/instances/")
+		f.killed = append(f.killed, index)
+		if f.stale > 0 {
+			f.staleLeft[index] = f.stale
+		} else {
+			f.instances[index] = f.killedInstance()
+		}
+		f.pending[index] = 2
+		down := 0
+		for index, pending := range f.pending {
+			if pending > 0 || f.staleLeft[index] > 0 {
+				down++
+			}
+		}
+		if down > f.maxDown {
+			f.maxDown = down
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return f
+}
+
+func (f *fakeInstances) killedInstance() AppInstance {
+	if f.crash {
+		return AppInstance{State: AppInstanceCrashed, Since: sinceTime{time.Unix(2000, 0)}}
+	}
+	return AppInstance{State: AppInstanceStarting, Since: sinceTime{time.Unix(2000, 0)}}
+}
+
+func TestRollingRestart(t *testing.T) {
+	Convey("Rolling restart", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		fake := handleFakeInstances("9902530c-c634-4864-a189-71d763cb12e2", 3, false)
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		var progress []RollingRestartProgress
+		err = client.RollingRestart("9902530c-c634-4864-a189-71d763cb12e2", RollingRestartOptions{
+			Interval: time.Millisecond,
+			Progress: func(p RollingRestartProgress) { progress = append(progress, p) },
+		})
+		So(err, ShouldBeNil)
+		So(fake.killed, ShouldResemble, []string{"0", "1", "2"})
+		So(fake.maxDown, ShouldEqual, 1)
+		So(len(progress), ShouldEqual, 3)
+		So(progress[2], ShouldResemble, RollingRestartProgress{Indexes: []int{2}, Restarted: 3, Total: 3})
+	})
+
+	Convey("Rolling restart with killed instances reported running", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		fake := handleFakeInstances("9902530c-c634-4864-a189-71d763cb12e2", 3, false)
+		fake.stale = 2
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.RollingRestart("9902530c-c634-4864-a189-71d763cb12e2", RollingRestartOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(fake.killed, ShouldResemble, []string{"0", "1", "2"})
+		So(fake.maxDown, ShouldEqual, 1)
+	})
+
+	Convey("Rolling restart with max unavailable", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		fake := handleFakeInstances("9902530c-c634-4864-a189-71d763cb12e2", 3, false)
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		var progress []RollingRestartProgress
+		err = client.RollingRestart("9902530c-c634-4864-a189-71d763cb12e2", RollingRestartOptions{
+			MaxUnavailable: 2,
+			Interval:       time.Millisecond,
+			Progress:       func(p RollingRestartProgress) { progress = append(progress, p) },
+		})
+		So(err, ShouldBeNil)
+		So(fake.killed, ShouldResemble, []string{"0", "1", "2"})
+		So(fake.maxDown, ShouldEqual, 2)
+		So(len(progress), ShouldEqual, 2)
+		So(progress[0].Indexes, ShouldResemble, []int{0, 1})
+	})
+
+	Convey("Rolling restart aborted on crash", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		fake := handleFakeInstances("9902530c-c634-4864-a189-71d763cb12e2", 2, true)
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.RollingRestart("9902530c-c634-4864-a189-71d763cb12e2", RollingRestartOptions{Interval: time.Millisecond})
+		So(err, ShouldResemble, AppCrashedError{AppGuid: "9902530c-c634-4864-a189-71d763cb12e2", Index: 0})
+		So(fake.killed, ShouldResemble, []string{"0"})
+	})
+
+	Convey("Rolling restart timing out", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		handleFakeInstances("9902530c-c634-4864-a189-71d763cb12e2", 2, true)
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.RollingRestart("9902530c-c634-4864-a189-71d763cb12e2", RollingRestartOptions{
+			Interval:      time.Millisecond,
+			Timeout:       10 * time.Millisecond,
+			IgnoreCrashes: true,
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Timed out")
+	})
+}
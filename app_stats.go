@@ -0,0 +1,173 @@
+package cfclient
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultStatsInterval    = 10 * time.Second
+	defaultStatsConcurrency = 4
+)
+
+// StatsWatcherOptions configures a StatsWatcher. Zero values are replaced by
+// their defaults.
+type StatsWatcherOptions struct {
+	// Interval is the time between two polls of every app, defaults to 10
+	// seconds.
+	Interval time.Duration
+	// Concurrency is the maximum number of stats requests in flight,
+	// defaults to 4.
+	Concurrency int
+}
+
+// StatsSample is the usage of an app instance at one point in time. Samples
+// which could not be collected only carry the AppGuid and Err.
+type StatsSample struct {
+	AppGuid string
+	Index   int
+	State   string
+	// Time is when the Cloud Controller measured the usage.
+	Time time.Time
+	// CPU is the share of a core used by the instance.
+	CPU float64
+	// CPUDelta is the change of CPU since the previous sample of the
+	// instance, it is zero for the first sample.
+	CPUDelta float64
+	Mem      int
+	MemQuota int
+	// MemUtilization is Mem over MemQuota, zero when there is no quota.
+	MemUtilization float64
+	Disk           int
+	DiskQuota      int
+	// DiskUtilization is Disk over DiskQuota, zero when there is no quota.
+	DiskUtilization float64
+	Uptime          int
+	Err             error
+}
+
+// StatsWatcher polls the stats of a set of V2 apps and streams them as
+// samples.
+type StatsWatcher struct {
+	client   *Client
+	appGuids []string
+	opts     StatsWatcherOptions
+
+	mu      sync.Mutex
+	lastCPU map[string]float64
+}
+
+// NewStatsWatcher returns a StatsWatcher for the apps identified by appGuids.
+func (c *Client) NewStatsWatcher(appGuids []string, opts StatsWatcherOptions) *StatsWatcher {
+	if opts.Interval == 0 {
+		opts.Interval = defaultStatsInterval
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultStatsConcurrency
+	}
+	return &StatsWatcher{
+		client:   c,
+		appGuids: appGuids,
+		opts:     opts,
+		lastCPU:  map[string]float64{},
+	}
+}
+
+// Watch polls the apps right away and then on every interval until ctx is
+// done. Samples are sent on the returned channel, which is closed once the
+// watcher stopped. The channel must be drained for polling to go on.
+func (w *StatsWatcher) Watch(ctx context.Context) <-chan StatsSample {
+	samples := make(chan StatsSample)
+	go func() {
+		defer close(samples)
+		ticker := time.NewTicker(w.opts.Interval)
+		defer ticker.Stop()
+		for {
+			w.poll(ctx, samples)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return samples
+}
+
+// poll collects the stats of every app, at most Concurrency at a time, and
+// returns once they are all sent.
+func (w *StatsWatcher) poll(ctx context.Context, samples chan<- StatsSample) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, w.opts.Concurrency)
+	for _, guid := range w.appGuids {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(guid string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			for _, sample := range w.sample(guid) {
+				select {
+				case <-ctx.Done():
+					return
+				case samples <- sample:
+				}
+			}
+		}(guid)
+	}
+	wg.Wait()
+}
+
+func (w *StatsWatcher) sample(guid string) []StatsSample {
+	stats, err := w.client.GetAppStats(guid)
+	if err != nil {
+		return []StatsSample{{AppGuid: guid, Err: err}}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	samples := make([]StatsSample, 0, len(stats))
+	for index, instance := range stats {
+		i, _ := strconv.Atoi(index)
+		sample := StatsSample{
+			AppGuid:   guid,
+			Index:     i,
+			State:     instance.State,
+			Time:      instance.Stats.Usage.Time.Time,
+			CPU:       instance.Stats.Usage.CPU,
+			Mem:       instance.Stats.Usage.Mem,
+			MemQuota:  instance.Stats.MemQuota,
+			Disk:      instance.Stats.Usage.Disk,
+			DiskQuota: instance.Stats.DiskQuota,
+			Uptime:    instance.Stats.Uptime,
+		}
+		if sample.MemQuota > 0 {
+			sample.MemUtilization = float64(sample.Mem) / float64(sample.MemQuota)
+		}
+		if sample.DiskQuota > 0 {
+			sample.DiskUtilization = float64(sample.Disk) / float64(sample.DiskQuota)
+		}
+		key := guid + "/" + index
+		if last, ok := w.lastCPU[key]; ok {
+			sample.CPUDelta = sample.CPU - last
+		}
+		w.lastCPU[key] = sample.CPU
+		samples = append(samples, sample)
+	}
+	sort.Sort(statsSamples(samples))
+	return samples
+}
+
+type statsSamples []StatsSample
+
+func (s statsSamples) Len() int           { return len(s) }
+func (s statsSamples) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s statsSamples) Less(i, j int) bool { return s[i].Index < s[j].Index }
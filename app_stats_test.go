@@ -0,0 +1,88 @@
+package cfclient
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+)
+
+func TestStatsWatcher(t *testing.T) {
+	Convey("Watch app stats", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+
+		var polls int32
+		mux.HandleFunc("/v2/apps/9902530c-c634-4864-a189-71d763cb12e2/stats", func(w http.ResponseWriter, r *http.Request) {
+			payload := appStatsPayload
+			if atomic.AddInt32(&polls, 1) > 1 {
+				payload = strings.Replace(payload, "0.36580239597146486", "0.46580239597146486", 1)
+			}
+			w.Write([]byte(payload))
+		})
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watcher := client.NewStatsWatcher([]string{"9902530c-c634-4864-a189-71d763cb12e2"}, StatsWatcherOptions{Interval: 10 * time.Millisecond})
+		samples := watcher.Watch(ctx)
+
+		sample := <-samples
+		So(sample.Err, ShouldBeNil)
+		So(sample.AppGuid, ShouldEqual, "9902530c-c634-4864-a189-71d763cb12e2")
+		So(sample.Index, ShouldEqual, 0)
+		So(sample.State, ShouldEqual, "RUNNING")
+		So(sample.Time.Format(time.RFC3339), ShouldEqual, "2016-09-17T15:46:17Z")
+		So(sample.CPU, ShouldEqual, 0.36580239597146486)
+		So(sample.CPUDelta, ShouldEqual, 0)
+		So(sample.Mem, ShouldEqual, 518123520)
+		So(sample.MemQuota, ShouldEqual, 536870912)
+		So(sample.MemUtilization, ShouldAlmostEqual, 0.965, 0.001)
+		So(sample.DiskUtilization, ShouldAlmostEqual, 0.141, 0.001)
+		So(sample.Uptime, ShouldEqual, 411118)
+
+		for i := 1; i < 4; i++ {
+			sample = <-samples
+			So(sample.Index, ShouldEqual, i)
+		}
+
+		sample = <-samples
+		So(sample.Index, ShouldEqual, 0)
+		So(sample.CPUDelta, ShouldAlmostEqual, 0.1, 0.000001)
+
+		cancel()
+		for range samples {
+		}
+	})
+
+	Convey("Watch stats of a missing app", t, func() {
+		setup(MockRoute{"GET", "/v2/apps/missing/stats", serviceInstanceNotFoundPayload, "", 404, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		samples := client.NewStatsWatcher([]string{"missing"}, StatsWatcherOptions{}).Watch(ctx)
+		sample := <-samples
+		So(sample.AppGuid, ShouldEqual, "missing")
+		So(sample.Err, ShouldNotBeNil)
+
+		cancel()
+		_, ok := <-samples
+		So(ok, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,196 @@
+package cfclient
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// AppInstanceRunning is the state of an instance serving requests
+	AppInstanceRunning = "RUNNING"
+	// AppInstanceStarting is the state of an instance which is booting
+	AppInstanceStarting = "STARTING"
+	// AppInstanceCrashed is the state of an instance which exited unexpectedly
+	AppInstanceCrashed = "CRASHED"
+	// AppInstanceFlapping is the state of an instance which keeps crashing
+	AppInstanceFlapping = "FLAPPING"
+	// AppInstanceDown is the state of an instance which is not running
+	AppInstanceDown = "DOWN"
+)
+
+const (
+	defaultWaitTimeout     = 5 * time.Minute
+	defaultWaitInterval    = time.Second
+	defaultWaitMaxInterval = 10 * time.Second
+)
+
+// WaitForAppOptions configures WaitForAppRunning. Zero values are replaced by
+// their defaults.
+type WaitForAppOptions struct {
+	// Timeout is the maximum time to wait, defaults to 5 minutes.
+	Timeout time.Duration
+	// Interval is the initial time between two polls, defaults to 1 second.
+	// It doubles after every poll up to MaxInterval.
+	Interval time.Duration
+	// MaxInterval caps the polling interval, defaults to 10 seconds.
+	MaxInterval time.Duration
+	// Since is the time from which app.crash events make the wait fail,
+	// defaults to the time WaitForAppRunning is called.
+	Since time.Time
+	// Progress is called after every poll with the state of the app.
+	Progress func(AppProgress)
+}
+
+// AppProgress is the state of an app observed while waiting for it.
+type AppProgress struct {
+	// PackageState is PENDING while the app is staging.
+	PackageState string
+	// Instances maps instance indexes to their state, it is empty until
+	// the app is staged.
+	Instances map[string]AppInstance
+	// Running is the number of instances in the RUNNING state.
+	Running int
+	// Desired is the number of instances the app should run.
+	Desired int
+}
+
+// AppStagingError is returned when the app failed to stage.
+type AppStagingError struct {
+	AppGuid     string
+	Reason      string
+	Description string
+}
+
+func (e AppStagingError) Error() string {
+	return fmt.Sprintf("cfclient: app %s failed to stage: %s: %s", e.AppGuid, e.Reason, e.Description)
+}
+
+// AppCrashedError is returned when an instance of the app crashed.
+type AppCrashedError struct {
+	AppGuid string
+	// Index of the crashed instance.
+	Index int
+	// Reason and ExitDescription are only known when the crash was
+	// reported through an app.crash event.
+	Reason          string
+	ExitDescription string
+}
+
+func (e AppCrashedError) Error() string {
+	if e.ExitDescription != "" {
+		return fmt.Sprintf("cfclient: instance %d of app %s crashed: %s", e.Index, e.AppGuid, e.ExitDescription)
+	}
+	return fmt.Sprintf("cfclient: instance %d of app %s crashed", e.Index, e.AppGuid)
+}
+
+func (o *WaitForAppOptions) setDefaults() {
+	if o.Timeout == 0 {
+		o.Timeout = defaultWaitTimeout
+	}
+	if o.Interval == 0 {
+		o.Interval = defaultWaitInterval
+	}
+	if o.MaxInterval == 0 {
+		o.MaxInterval = defaultWaitMaxInterval
+	}
+	if o.MaxInterval < o.Interval {
+		o.MaxInterval = o.Interval
+	}
+	if o.Since.IsZero() {
+		o.Since = time.Now()
+	}
+}
+
+// WaitForAppRunning polls the V2 app identified by guid until all of its
+// instances are running. It returns an AppStagingError as soon as staging
+// fails and an AppCrashedError as soon as an instance crashes.
+func (c *Client) WaitForAppRunning(guid string, opts WaitForAppOptions) error {
+	opts.setDefaults()
+	deadline := time.Now().Add(opts.Timeout)
+	interval := opts.Interval
+	for {
+		done, err := c.checkAppRunning(guid, opts)
+		if done || err != nil {
+			return err
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return errors.Errorf("Timed out after %s waiting for app %s to be running", opts.Timeout, guid)
+		}
+		time.Sleep(interval)
+		interval *= 2
+		if interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+func (c *Client) checkAppRunning(guid string, opts WaitForAppOptions) (bool, error) {
+	app, err := c.GetAppByGuid(guid)
+	if err != nil {
+		return false, err
+	}
+	if app.PackageState == "FAILED" {
+		return false, AppStagingError{
+			AppGuid:     guid,
+			Reason:      app.StagingFailedReason,
+			Description: app.StagingFailedDescription,
+		}
+	}
+	if app.State == AppStopped {
+		return false, errors.Errorf("App %s is stopped", guid)
+	}
+	if err := c.checkAppCrashEvents(guid, opts.Since); err != nil {
+		return false, err
+	}
+
+	progress := AppProgress{
+		PackageState: app.PackageState,
+		Instances:    map[string]AppInstance{},
+		Desired:      app.Instances,
+	}
+	if app.PackageState == "STAGED" {
+		instances, err := c.GetAppInstances(guid)
+		if err != nil {
+			if cfErr, ok := errors.Cause(err).(CloudFoundryError); !ok || cfErr.ErrorCode != "CF-NotStaged" {
+				return false, err
+			}
+		}
+		for index, instance := range instances {
+			progress.Instances[index] = instance
+			switch instance.State {
+			case AppInstanceRunning:
+				progress.Running++
+			case AppInstanceCrashed, AppInstanceFlapping:
+				i, _ := strconv.Atoi(index)
+				return false, AppCrashedError{AppGuid: guid, Index: i}
+			}
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(progress)
+	}
+	return app.PackageState == "STAGED" && progress.Running >= progress.Desired, nil
+}
+
+func (c *Client) checkAppCrashEvents(guid string, since time.Time) error {
+	events, err := c.ListAppEventsByQuery(AppCrash, []AppEventQuery{
+		{Filter: FilterActee, Operator: ":", Value: guid},
+		{Filter: FilterTimestamp, Operator: ">", Value: since.UTC().Format(time.RFC3339)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Error requesting crash events of app %s", guid)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	event := events[len(events)-1]
+	return AppCrashedError{
+		AppGuid:         guid,
+		Index:           int(event.MetaData.Index),
+		Reason:          event.MetaData.ExitReason,
+		ExitDescription: event.MetaData.ExitDescription,
+	}
+}
@@ -0,0 +1,136 @@
+package cfclient
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const crashEventsQuery = "q=type:app.crash&q=actee:9902530c-c634-4864-a189-71d763cb12e2&q=timestamp>2017-09-12T10:00:00Z"
+
+func TestWaitForAppRunning(t *testing.T) {
+	since := time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC)
+
+	Convey("App with all instances running", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2", appPayload, "", 200, "inline-relations-depth=2", nil},
+			{"GET", "/v2/events", emptyEventsPayload, "", 200, crashEventsQuery, nil},
+			{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2/instances", appInstancePayload, "", 200, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		var progress []AppProgress
+		err = client.WaitForAppRunning("9902530c-c634-4864-a189-71d763cb12e2", WaitForAppOptions{
+			Since: since,
+			Progress: func(p AppProgress) {
+				progress = append(progress, p)
+			},
+		})
+		So(err, ShouldBeNil)
+		So(len(progress), ShouldEqual, 1)
+		So(progress[0].PackageState, ShouldEqual, "STAGED")
+		So(progress[0].Running, ShouldEqual, 2)
+		So(progress[0].Desired, ShouldEqual, 1)
+		So(progress[0].Instances["0"].State, ShouldEqual, AppInstanceRunning)
+	})
+
+	Convey("App failing to stage", t, func() {
+		setup(MockRoute{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2", appStagingFailedPayload, "", 200, "inline-relations-depth=2", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.WaitForAppRunning("9902530c-c634-4864-a189-71d763cb12e2", WaitForAppOptions{Since: since})
+		So(err, ShouldNotBeNil)
+		stagingErr, ok := err.(AppStagingError)
+		So(ok, ShouldBeTrue)
+		So(stagingErr.Reason, ShouldEqual, "BuildpackCompileFailed")
+		So(stagingErr.Description, ShouldEqual, "App staging failed in the buildpack compile phase")
+	})
+
+	Convey("App with a crash event", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2", appPayload, "", 200, "inline-relations-depth=2", nil},
+			{"GET", "/v2/events", appCrashEventsPayload, "", 200, crashEventsQuery, nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.WaitForAppRunning("9902530c-c634-4864-a189-71d763cb12e2", WaitForAppOptions{Since: since})
+		So(err, ShouldNotBeNil)
+		crashErr, ok := err.(AppCrashedError)
+		So(ok, ShouldBeTrue)
+		So(crashErr.Index, ShouldEqual, 1)
+		So(crashErr.Reason, ShouldEqual, "CRASHED")
+		So(crashErr.ExitDescription, ShouldEqual, "APP/PROC/WEB: Exited with status 1")
+	})
+
+	Convey("App with a crashed instance", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2", appPayload, "", 200, "inline-relations-depth=2", nil},
+			{"GET", "/v2/events", emptyEventsPayload, "", 200, crashEventsQuery, nil},
+			{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2/instances", appInstanceCrashedPayload, "", 200, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.WaitForAppRunning("9902530c-c634-4864-a189-71d763cb12e2", WaitForAppOptions{Since: since})
+		So(err, ShouldNotBeNil)
+		crashErr, ok := err.(AppCrashedError)
+		So(ok, ShouldBeTrue)
+		So(crashErr.Index, ShouldEqual, 1)
+	})
+
+	Convey("App not running before the timeout", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2", appPayload, "", 200, "inline-relations-depth=2", nil},
+			{"GET", "/v2/events", emptyEventsPayload, "", 200, crashEventsQuery, nil},
+			{"GET", "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2/instances", appInstanceStartingPayload, "", 200, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		polls := 0
+		err = client.WaitForAppRunning("9902530c-c634-4864-a189-71d763cb12e2", WaitForAppOptions{
+			Since:    since,
+			Timeout:  50 * time.Millisecond,
+			Interval: 10 * time.Millisecond,
+			Progress: func(p AppProgress) {
+				polls++
+			},
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Timed out")
+		So(polls, ShouldBeGreaterThan, 1)
+	})
+}
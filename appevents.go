@@ -3,6 +3,7 @@ package cfclient
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net/url"
 	"time"
 
 	"github.com/pkg/errors"
@@ -82,9 +83,11 @@ type AppEventEntity struct {
 	Timestamp time.Time `json:"timestamp"`
 	MetaData  struct {
 		//app.crash event fields
-		ExitDescription string `json:"exit_description,omitempty"`
-		ExitReason      string `json:"reason,omitempty"`
-		ExitStatus      string `json:"exit_status,omitempty"`
+		ExitDescription string  `json:"exit_description,omitempty"`
+		ExitReason      string  `json:"reason,omitempty"`
+		ExitStatus      string  `json:"exit_status,omitempty"`
+		Instance        string  `json:"instance,omitempty"`
+		Index           float64 `json:"index,omitempty"`
 
 		Request struct {
 			Name              string  `json:"name,omitempty"`
@@ -131,7 +134,11 @@ func (c *Client) ListAppEventsByQuery(eventType string, queries []AppEventQuery)
 			if !stringInSlice(eventQuery.Operator, ValidOperators) {
 				return nil, errors.New("Unsupported query operator type " + eventQuery.Operator)
 			}
-			query += "&q=" + eventQuery.Filter + eventQuery.Operator + eventQuery.Value
+			operator := eventQuery.Operator
+			if operator == "IN" {
+				operator = url.QueryEscape(" IN ")
+			}
+			query += "&q=" + eventQuery.Filter + operator + eventQuery.Value
 		}
 	}
 
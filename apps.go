@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"strconv"
 	"time"
@@ -12,6 +13,13 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// AppStarted is the desired state of a started app
+	AppStarted = "STARTED"
+	// AppStopped is the desired state of a stopped app
+	AppStopped = "STOPPED"
+)
+
 type DropletRequest struct {
 	Data struct {
 		GUID string `json:"guid,omitempty"`
@@ -183,6 +191,52 @@ type AppSummary struct {
 	DockerCredentials        map[string]interface{} `json:"docker_credentials_json"`
 }
 
+// AppCreateRequest is the body of a V2 app creation request.
+type AppCreateRequest struct {
+	Name                    string                 `json:"name"`
+	SpaceGuid               string                 `json:"space_guid"`
+	Memory                  int                    `json:"memory,omitempty"`
+	Instances               int                    `json:"instances,omitempty"`
+	DiskQuota               int                    `json:"disk_quota,omitempty"`
+	StackGuid               string                 `json:"stack_guid,omitempty"`
+	State                   string                 `json:"state,omitempty"`
+	Command                 string                 `json:"command,omitempty"`
+	Buildpack               string                 `json:"buildpack,omitempty"`
+	HealthCheckHttpEndpoint string                 `json:"health_check_http_endpoint,omitempty"`
+	HealthCheckType         string                 `json:"health_check_type,omitempty"`
+	HealthCheckTimeout      int                    `json:"health_check_timeout,omitempty"`
+	Diego                   bool                   `json:"diego,omitempty"`
+	EnableSSH               bool                   `json:"enable_ssh,omitempty"`
+	DockerImage             string                 `json:"docker_image,omitempty"`
+	DockerCredentials       map[string]interface{} `json:"docker_credentials,omitempty"`
+	Environment             map[string]interface{} `json:"environment_json,omitempty"`
+	Ports                   []int                  `json:"ports,omitempty"`
+}
+
+// AppUpdateRequest is the body of a V2 app update request. Only the fields
+// which are set are sent, so numeric and boolean fields are pointers to allow
+// updating them to their zero value (e.g. scaling an app to 0 instances).
+type AppUpdateRequest struct {
+	Name                    string                 `json:"name,omitempty"`
+	SpaceGuid               string                 `json:"space_guid,omitempty"`
+	Memory                  *int                   `json:"memory,omitempty"`
+	Instances               *int                   `json:"instances,omitempty"`
+	DiskQuota               *int                   `json:"disk_quota,omitempty"`
+	StackGuid               string                 `json:"stack_guid,omitempty"`
+	State                   string                 `json:"state,omitempty"`
+	Command                 string                 `json:"command,omitempty"`
+	Buildpack               string                 `json:"buildpack,omitempty"`
+	HealthCheckHttpEndpoint string                 `json:"health_check_http_endpoint,omitempty"`
+	HealthCheckType         string                 `json:"health_check_type,omitempty"`
+	HealthCheckTimeout      *int                   `json:"health_check_timeout,omitempty"`
+	Diego                   *bool                  `json:"diego,omitempty"`
+	EnableSSH               *bool                  `json:"enable_ssh,omitempty"`
+	DockerImage             string                 `json:"docker_image,omitempty"`
+	DockerCredentials       map[string]interface{} `json:"docker_credentials,omitempty"`
+	Environment             map[string]interface{} `json:"environment_json,omitempty"`
+	Ports                   []int                  `json:"ports,omitempty"`
+}
+
 type AppEnv struct {
 	// These can have arbitrary JSON so need to map to interface{}
 	Environment    map[string]interface{} `json:"environment_json"`
@@ -404,7 +458,7 @@ func (c *Client) KillAppInstance(guid string, index string) error {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 204 {
-		return errors.Wrapf(err, "Error stopping app %s at index %s", guid, index)
+		return errors.Errorf("Error stopping app %s at index %s, response code: %d", guid, index, resp.StatusCode)
 	}
 	return nil
 }
@@ -437,8 +491,113 @@ func (c *Client) AppByGuid(guid string) (App, error) {
 	return c.GetAppByGuid(guid)
 }
 
+// CreateApp creates a new V2 app from the given request and returns it.
+func (c *Client) CreateApp(req AppCreateRequest) (App, error) {
+	buf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buf).Encode(req)
+	if err != nil {
+		return App{}, errors.Wrap(err, "Error encoding app create request")
+	}
+	r := c.NewRequestWithBody("POST", "/v2/apps", buf)
+	resp, err := c.DoRequest(r)
+	if err != nil {
+		return App{}, errors.Wrapf(err, "Error creating app %s", req.Name)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		resp.Body.Close()
+		return App{}, errors.Errorf("Error creating app %s, response code: %d", req.Name, resp.StatusCode)
+	}
+	return c.handleAppResp(resp)
+}
+
+// UpdateApp updates the V2 app identified by guid with the fields set in req
+// and returns the updated app.
+func (c *Client) UpdateApp(guid string, req AppUpdateRequest) (App, error) {
+	buf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buf).Encode(req)
+	if err != nil {
+		return App{}, errors.Wrap(err, "Error encoding app update request")
+	}
+	r := c.NewRequestWithBody("PUT", "/v2/apps/"+guid, buf)
+	resp, err := c.DoRequest(r)
+	if err != nil {
+		return App{}, errors.Wrapf(err, "Error updating app %s", guid)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		resp.Body.Close()
+		return App{}, errors.Errorf("Error updating app %s, response code: %d", guid, resp.StatusCode)
+	}
+	return c.handleAppResp(resp)
+}
+
+// DeleteApp deletes the V2 app identified by guid.
+func (c *Client) DeleteApp(guid string) error {
+	resp, err := c.DoRequest(c.NewRequest("DELETE", "/v2/apps/"+guid))
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting app %s", guid)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return errors.Errorf("Error deleting app %s, response code: %d", guid, resp.StatusCode)
+	}
+	return nil
+}
+
+// RestageApp restages the V2 app identified by guid. Staging happens
+// asynchronously, the returned app has its package state set to PENDING.
+func (c *Client) RestageApp(guid string) (App, error) {
+	resp, err := c.DoRequest(c.NewRequest("POST", fmt.Sprintf("/v2/apps/%s/restage", guid)))
+	if err != nil {
+		return App{}, errors.Wrapf(err, "Error restaging app %s", guid)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		resp.Body.Close()
+		return App{}, errors.Errorf("Error restaging app %s, response code: %d", guid, resp.StatusCode)
+	}
+	return c.handleAppResp(resp)
+}
+
+// StartV2App sets the state of the V2 app identified by guid to STARTED.
+func (c *Client) StartV2App(guid string) (App, error) {
+	return c.UpdateApp(guid, AppUpdateRequest{State: AppStarted})
+}
+
+// StopV2App sets the state of the V2 app identified by guid to STOPPED.
+func (c *Client) StopV2App(guid string) (App, error) {
+	return c.UpdateApp(guid, AppUpdateRequest{State: AppStopped})
+}
+
+// RestartV2App stops and then starts the V2 app identified by guid.
+func (c *Client) RestartV2App(guid string) (App, error) {
+	if _, err := c.StopV2App(guid); err != nil {
+		return App{}, err
+	}
+	return c.StartV2App(guid)
+}
+
+func (c *Client) handleAppResp(resp *http.Response) (App, error) {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return App{}, errors.Wrap(err, "Error reading app response body")
+	}
+	var appResource AppResource
+	err = json.Unmarshal(body, &appResource)
+	if err != nil {
+		return App{}, errors.Wrap(err, "Error unmarshalling app")
+	}
+	app := appResource.Entity
+	app.Guid = appResource.Meta.Guid
+	app.CreatedAt = appResource.Meta.CreatedAt
+	app.UpdatedAt = appResource.Meta.UpdatedAt
+	app.c = c
+	return app, nil
+}
+
 //CreateV3DockerBuild creates a build to stage the docker image. Needs to be associated
 //with an existing package
+//
+//Deprecated: use CreateV3Build, or StageApp which also waits for staging.
 func (c *Client) CreateV3DockerBuild(pkgGUID string) (bld V3DockerBuildResponse, err error) {
 	v3Build := V3DockerBuild{}
 	v3Build.Package.GUID = pkgGUID
@@ -492,37 +651,28 @@ func (c *Client) CreateV3DockerPackage(appGUID, image string) (pkg V3DockerPacka
 	return pkg, nil
 }
 
+//AssignDropletToApp sets the current droplet of the app and returns the app.
+//
+//Deprecated: use SetCurrentV3Droplet.
 func (c *Client) AssignDropletToApp(appGUID, dropletGUID string) (V3DockerAppResponse, error) {
 	app := V3DockerAppResponse{}
-	droplet := DropletRequest{}
-	droplet.Data.GUID = dropletGUID
-
-	b := new(bytes.Buffer)
-	json.NewEncoder(b).Encode(droplet)
-	r := c.NewRequestWithBody("PATCH", "/v3/apps/"+appGUID+"/relationships/current_droplet", b)
-	resp, err := c.DoRequest(r)
-	fmt.Println(r)
-
-	if err != nil {
-		fmt.Println(err)
-		return V3DockerAppResponse{}, errors.Wrap(err, "Error requesting droplet")
+	if err := c.SetCurrentV3Droplet(appGUID, dropletGUID); err != nil {
+		return app, err
 	}
-	defer resp.Body.Close()
-	resBody, err := ioutil.ReadAll(resp.Body)
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/apps/"+appGUID))
 	if err != nil {
-		return V3DockerAppResponse{}, errors.Wrap(err, "Error reading app response")
+		return app, errors.Wrap(err, "Error requesting app")
 	}
-
-	err = json.Unmarshal(resBody, &app)
-	if err != nil {
-		return V3DockerAppResponse{}, errors.Wrap(err, "Error unmarshalling app response")
+	if err := decodeV3Response(resp, http.StatusOK, &app); err != nil {
+		return app, errors.Wrap(err, "Error requesting app")
 	}
-	return V3DockerAppResponse{}, nil
-
+	return app, nil
 }
 
 //GetV3DockerBuild checks to see if a given build has successfulyl staged, so it can
 //get the droplet guid, and apply it to the application
+//
+//Deprecated: use GetV3Build, or WaitForBuild which reports staging failures.
 func (c *Client) GetV3BuildInfo(bldGUID string) (bld V3DockerBuildResponse, err error) {
 	r := c.NewRequest("GET", "/v3/builds/"+bldGUID)
 	resp, err := c.DoRequest(r)
@@ -545,6 +695,8 @@ func (c *Client) GetV3BuildInfo(bldGUID string) (bld V3DockerBuildResponse, err
 
 //CreateV3DockerApp takes an appname, and a GUID for space. It will then
 //create the app object and return the GUID.
+//
+//Deprecated: use CreateV3App, which supports both lifecycles.
 func (c *Client) CreateV3DockerApp(appName, spaceGuid string) (app V3DockerAppResponse, err error) {
 
 	v3AppSpec := V3DockerApp{}
@@ -572,6 +724,10 @@ func (c *Client) CreateV3DockerApp(appName, spaceGuid string) (app V3DockerAppRe
 
 }
 
+//StartApp starts the V3 app identified by appGUID. The returned app is
+//always empty.
+//
+//Deprecated: use StartV3App, which returns the started app.
 func (c *Client) StartApp(appGUID string) (app V3DockerAppResponse, err error) {
 	cfUpdateRequest := c.NewRequest("POST", "/v3/apps/"+appGUID+"/actions/start")
 	_, err = c.DoRequest(cfUpdateRequest)
@@ -584,6 +740,8 @@ func (c *Client) StartApp(appGUID string) (app V3DockerAppResponse, err error) {
 
 //CreateV3DockerAppWithEnv takes an appname, a guid for the space and a map of strings for the environment vars.
 // It will then create the app object
+//
+//Deprecated: use CreateV3App, which supports both lifecycles.
 func (c *Client) CreateV3DockerAppWithEnv(appName, spaceGuid string, vars map[string]string) (app V3DockerAppResponse, err error) {
 
 	v3AppSpec := V3DockerApp{}
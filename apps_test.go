@@ -1,6 +1,7 @@
 package cfclient
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -314,3 +315,140 @@ func TestAppSpace(t *testing.T) {
 		So(space.Guid, ShouldEqual, "a72fa1e8-c694-47b3-85f2-55f61fd00d73")
 	})
 }
+
+func TestCreateApp(t *testing.T) {
+	Convey("Create app", t, func() {
+		body := `{"name":"my-app","space_guid":"a72fa1e8-c694-47b3-85f2-55f61fd00d73","memory":512,"instances":2,"command":"./start.sh","health_check_http_endpoint":"/health","health_check_type":"http","environment_json":{"FOO":"bar"}}`
+		setup(MockRoute{"POST", "/v2/apps", createAppPayload, "", 201, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.CreateApp(AppCreateRequest{
+			Name:                    "my-app",
+			SpaceGuid:               "a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+			Memory:                  512,
+			Instances:               2,
+			Command:                 "./start.sh",
+			HealthCheckType:         "http",
+			HealthCheckHttpEndpoint: "/health",
+			Environment:             map[string]interface{}{"FOO": "bar"},
+		})
+		So(err, ShouldBeNil)
+
+		So(app.Guid, ShouldEqual, "b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(app.CreatedAt, ShouldEqual, "2017-09-12T09:41:26Z")
+		So(app.Name, ShouldEqual, "my-app")
+		So(app.Memory, ShouldEqual, 512)
+		So(app.Instances, ShouldEqual, 2)
+		So(app.State, ShouldEqual, "STOPPED")
+		So(app.HealthCheckType, ShouldEqual, "http")
+		So(app.Environment["FOO"], ShouldEqual, "bar")
+	})
+}
+
+func TestUpdateApp(t *testing.T) {
+	Convey("Update app", t, func() {
+		setup(MockRoute{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", updateAppPayload, "", 201, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		memory, instances := 1024, 0
+		app, err := client.UpdateApp("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", AppUpdateRequest{
+			Memory:    &memory,
+			Instances: &instances,
+		})
+		So(err, ShouldBeNil)
+
+		So(app.Guid, ShouldEqual, "b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(app.UpdatedAt, ShouldEqual, "2017-09-12T09:45:02Z")
+		So(app.Memory, ShouldEqual, 1024)
+		So(app.Instances, ShouldEqual, 0)
+	})
+
+	Convey("Update app request only contains set fields", t, func() {
+		instances := 0
+		b, err := json.Marshal(AppUpdateRequest{State: AppStopped, Instances: &instances})
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `{"instances":0,"state":"STOPPED"}`)
+	})
+}
+
+func TestStartStopV2App(t *testing.T) {
+	Convey("Start app", t, func() {
+		setup(MockRoute{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", updateAppPayload, "", 201, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.StartV2App("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldBeNil)
+		So(app.State, ShouldEqual, AppStarted)
+
+		app, err = client.RestartV2App("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldBeNil)
+		So(app.State, ShouldEqual, AppStarted)
+	})
+
+	Convey("Stop app", t, func() {
+		setup(MockRoute{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 201, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.StopV2App("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldBeNil)
+		So(app.State, ShouldEqual, AppStopped)
+	})
+}
+
+func TestRestageApp(t *testing.T) {
+	Convey("Restage app", t, func() {
+		setup(MockRoute{"POST", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/restage", createAppPayload, "", 201, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.RestageApp("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldBeNil)
+		So(app.Guid, ShouldEqual, "b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(app.PackageState, ShouldEqual, "PENDING")
+	})
+}
+
+func TestDeleteApp(t *testing.T) {
+	Convey("Delete app", t, func() {
+		setup(MockRoute{"DELETE", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", "", "", 204, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DeleteApp("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldBeNil)
+	})
+}
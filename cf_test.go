@@ -107,6 +107,14 @@ func setupMultiple(mockEndpoints []MockRoute, t *testing.T) {
 			r.Put(endpoint, func(req *http.Request) (int, string) {
 				testUserAgent(req.Header.Get("User-Agent"), userAgent, t)
 				testQueryString(req.URL.RawQuery, queryString, t)
+				testPostQuery(req, postFormBody, t)
+				return status, output
+			})
+		} else if method == "PATCH" {
+			r.Patch(endpoint, func(req *http.Request) (int, string) {
+				testUserAgent(req.Header.Get("User-Agent"), userAgent, t)
+				testQueryString(req.URL.RawQuery, queryString, t)
+				testPostQuery(req, postFormBody, t)
 				return status, output
 			})
 		}
@@ -261,12 +261,37 @@ func (c *Client) DoRequest(r *request) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", c.Config.UserAgent)
 	if r.body != nil {
 		req.Header.Set("Content-type", "application/json")
 	}
+	return c.Do(req)
+}
+
+// DoRequestWithoutRedirects runs a request with our client but returns
+// redirect responses instead of following them, so that the bearer token
+// is not forwarded to the redirect target.
+func (c *Client) DoRequestWithoutRedirects(r *request) (*http.Response, error) {
+	req, err := r.toHTTP()
+	if err != nil {
+		return nil, err
+	}
+	httpClient := *c.Config.HttpClient
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return c.do(&httpClient, req)
+}
+
+// Do runs a raw HTTP request with our client, for requests whose body is not
+// JSON encoded such as multipart uploads.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.do(c.Config.HttpClient, req)
+}
+
+func (c *Client) do(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", c.Config.UserAgent)
 
-	resp, err := c.Config.HttpClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
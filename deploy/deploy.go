@@ -0,0 +1,460 @@
+// Package deploy implements blue-green deployments of V2 apps on top of
+// cfclient. The new version of an app is created next to the running one and
+// only receives the routes of the running version once it is healthy, so
+// that there is no downtime.
+//
+// A deployment goes through a fixed sequence of steps. Its progress is
+// recorded in a State which can be persisted and given back to Resume to
+// continue a deployment after a failure, or to Rollback to restore the
+// running version.
+package deploy
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/pkg/errors"
+)
+
+// Client is the subset of *cfclient.Client used by a deployment.
+type Client interface {
+	GetAppByGuid(guid string) (cfclient.App, error)
+	ListAppsByQuery(query url.Values) ([]cfclient.App, error)
+	CreateApp(req cfclient.AppCreateRequest) (cfclient.App, error)
+	UpdateApp(guid string, req cfclient.AppUpdateRequest) (cfclient.App, error)
+	DeleteApp(guid string) error
+	UploadAppBits(guid, dir string) error
+	StartV2App(guid string) (cfclient.App, error)
+	StopV2App(guid string) (cfclient.App, error)
+	WaitForAppRunning(guid string, opts cfclient.WaitForAppOptions) error
+	GetAppRoutes(guid string) ([]cfclient.Route, error)
+	MapRoute(routeMap cfclient.RouteMap) (cfclient.MappedRoute, error)
+	UnmapRoute(routeMap cfclient.RouteMap) error
+	ListServiceBindingsByQuery(query url.Values) ([]cfclient.ServiceBinding, error)
+	CreateServiceBinding(req cfclient.ServiceBindingRequest) (cfclient.ServiceBinding, error)
+}
+
+var _ Client = (*cfclient.Client)(nil)
+
+// Step is a step of a deployment.
+type Step string
+
+const (
+	// StepRenameOld renames the running version to free its name
+	StepRenameOld Step = "rename-old"
+	// StepCreateNew creates the new version with the settings of the old one
+	StepCreateNew Step = "create-new"
+	// StepUploadBits uploads the bits of the new version
+	StepUploadBits Step = "upload-bits"
+	// StepBindServices binds the service instances of the old version to the
+	// new one
+	StepBindServices Step = "bind-services"
+	// StepStartNew starts the new version
+	StepStartNew Step = "start-new"
+	// StepWaitHealthy waits for all instances of the new version to run
+	StepWaitHealthy Step = "wait-healthy"
+	// StepMapRoutes maps the routes of the old version to the new one
+	StepMapRoutes Step = "map-routes"
+	// StepUnmapOld removes the routes from the old version
+	StepUnmapOld Step = "unmap-old"
+	// StepRetireOld deletes or stops the old version
+	StepRetireOld Step = "retire-old"
+)
+
+// Steps lists the steps of a deployment in the order they run.
+var Steps = []Step{
+	StepRenameOld,
+	StepCreateNew,
+	StepUploadBits,
+	StepBindServices,
+	StepStartNew,
+	StepWaitHealthy,
+	StepMapRoutes,
+	StepUnmapOld,
+	StepRetireOld,
+}
+
+// EventType tells what happened to a step.
+type EventType string
+
+const (
+	EventStarted    EventType = "started"
+	EventCompleted  EventType = "completed"
+	EventFailed     EventType = "failed"
+	EventRolledBack EventType = "rolled-back"
+)
+
+// Event is emitted to Config.OnEvent whenever a step changes.
+type Event struct {
+	Step Step
+	Type EventType
+	// Err is set for failed events.
+	Err error
+}
+
+// StepError is returned when a step of a deployment fails.
+type StepError struct {
+	Step Step
+	Err  error
+}
+
+func (e StepError) Error() string {
+	return fmt.Sprintf("deploy: step %s failed: %s", e.Step, e.Err)
+}
+
+// Config describes a deployment.
+type Config struct {
+	// AppGuid identifies the running version of the app.
+	AppGuid string
+	// Dir contains the bits of the new version. It is ignored for docker apps.
+	Dir string
+	// DockerImage is the image of the new version of a docker app, it
+	// defaults to the image of the running version.
+	DockerImage string
+	// DockerCredentials authenticate against the registry of the docker
+	// image, they default to the credentials of the running version. The
+	// Cloud Controller may redact the password it returns, in which case they
+	// have to be given.
+	DockerCredentials map[string]interface{}
+	// Environment is merged over the environment of the running version.
+	Environment map[string]interface{}
+	// VenerableSuffix is appended to the name of the running version while
+	// the new one takes its name, defaults to "-venerable".
+	VenerableSuffix string
+	// KeepOld stops the old version instead of deleting it.
+	KeepOld bool
+	// AutoRollback rolls back the deployment as soon as a step fails.
+	AutoRollback bool
+	// Wait configures the wait for the new version to be healthy.
+	Wait cfclient.WaitForAppOptions
+	// OnEvent is called on every step transition.
+	OnEvent func(Event)
+}
+
+// State records the progress of a deployment. It is JSON serializable so it
+// can be persisted between runs.
+type State struct {
+	OldAppGuid string   `json:"old_app_guid"`
+	AppName    string   `json:"app_name,omitempty"`
+	NewAppGuid string   `json:"new_app_guid,omitempty"`
+	RouteGuids []string `json:"route_guids,omitempty"`
+	Completed  []Step   `json:"completed,omitempty"`
+}
+
+func (s State) completed(step Step) bool {
+	for _, done := range s.Completed {
+		if done == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Deployment is a blue-green deployment of an app.
+type Deployment struct {
+	client Client
+	config Config
+	state  State
+}
+
+// New prepares the deployment described by config.
+func New(client Client, config Config) *Deployment {
+	return Resume(client, config, State{OldAppGuid: config.AppGuid})
+}
+
+// Resume prepares a deployment which continues from the given state, as
+// returned by State after a failure.
+func Resume(client Client, config Config, state State) *Deployment {
+	if config.VenerableSuffix == "" {
+		config.VenerableSuffix = "-venerable"
+	}
+	if state.OldAppGuid == "" {
+		state.OldAppGuid = config.AppGuid
+	}
+	return &Deployment{client: client, config: config, state: state}
+}
+
+// State returns the progress of the deployment.
+func (d *Deployment) State() State {
+	return d.state
+}
+
+// Done reports whether every step of the deployment completed.
+func (d *Deployment) Done() bool {
+	return d.state.completed(StepRetireOld)
+}
+
+// Run executes the steps which have not completed yet. When a step fails a
+// StepError is returned and the deployment can either be run again to retry
+// from the failed step, or rolled back.
+func (d *Deployment) Run() error {
+	for _, step := range Steps {
+		if d.state.completed(step) {
+			continue
+		}
+		d.emit(Event{Step: step, Type: EventStarted})
+		if err := d.runStep(step); err != nil {
+			d.emit(Event{Step: step, Type: EventFailed, Err: err})
+			stepErr := StepError{Step: step, Err: err}
+			if d.config.AutoRollback {
+				if rollbackErr := d.Rollback(); rollbackErr != nil {
+					return errors.Wrapf(rollbackErr, "Error rolling back after %s", stepErr)
+				}
+			}
+			return stepErr
+		}
+		d.state.Completed = append(d.state.Completed, step)
+		d.emit(Event{Step: step, Type: EventCompleted})
+	}
+	return nil
+}
+
+// Rollback restores the old version: its routes are mapped back, the new
+// version is deleted and the old version gets its name back. A completed
+// deployment cannot be rolled back since the old version has been retired.
+func (d *Deployment) Rollback() error {
+	if d.Done() {
+		return errors.New("deploy: cannot roll back a completed deployment")
+	}
+	if len(d.state.RouteGuids) > 0 {
+		if err := d.mapRoutes(d.state.OldAppGuid); err != nil {
+			return StepError{Step: StepUnmapOld, Err: err}
+		}
+		d.emit(Event{Step: StepUnmapOld, Type: EventRolledBack})
+	}
+	if d.state.NewAppGuid != "" {
+		if err := d.client.DeleteApp(d.state.NewAppGuid); err != nil {
+			return StepError{Step: StepCreateNew, Err: err}
+		}
+		d.state.NewAppGuid = ""
+		d.emit(Event{Step: StepCreateNew, Type: EventRolledBack})
+	}
+	if d.state.completed(StepRenameOld) {
+		_, err := d.client.UpdateApp(d.state.OldAppGuid, cfclient.AppUpdateRequest{Name: d.state.AppName})
+		if err != nil {
+			return StepError{Step: StepRenameOld, Err: err}
+		}
+		d.emit(Event{Step: StepRenameOld, Type: EventRolledBack})
+	}
+	d.state.RouteGuids = nil
+	d.state.Completed = nil
+	return nil
+}
+
+func (d *Deployment) emit(event Event) {
+	if d.config.OnEvent != nil {
+		d.config.OnEvent(event)
+	}
+}
+
+func (d *Deployment) runStep(step Step) error {
+	switch step {
+	case StepRenameOld:
+		return d.renameOld()
+	case StepCreateNew:
+		return d.createNew()
+	case StepUploadBits:
+		if d.config.Dir == "" {
+			return nil
+		}
+		return d.client.UploadAppBits(d.state.NewAppGuid, d.config.Dir)
+	case StepBindServices:
+		return d.bindServices()
+	case StepStartNew:
+		_, err := d.client.StartV2App(d.state.NewAppGuid)
+		return err
+	case StepWaitHealthy:
+		return d.client.WaitForAppRunning(d.state.NewAppGuid, d.config.Wait)
+	case StepMapRoutes:
+		return d.mapNewRoutes()
+	case StepUnmapOld:
+		return d.unmapOldRoutes()
+	case StepRetireOld:
+		if d.config.KeepOld {
+			_, err := d.client.StopV2App(d.state.OldAppGuid)
+			return err
+		}
+		return d.client.DeleteApp(d.state.OldAppGuid)
+	}
+	return errors.Errorf("Unknown step %s", step)
+}
+
+func (d *Deployment) renameOld() error {
+	old, err := d.client.GetAppByGuid(d.state.OldAppGuid)
+	if err != nil {
+		return err
+	}
+	if old.DockerImage == "" && d.config.Dir == "" {
+		return errors.Errorf("No bits given for the new version of app %s", old.Name)
+	}
+	// a previous attempt may have renamed the app without recording it
+	if d.state.AppName == "" {
+		d.state.AppName = strings.TrimSuffix(old.Name, d.config.VenerableSuffix)
+	}
+	venerable := d.state.AppName + d.config.VenerableSuffix
+	if old.Name == venerable {
+		return nil
+	}
+	_, err = d.client.UpdateApp(old.Guid, cfclient.AppUpdateRequest{Name: venerable})
+	return err
+}
+
+func (d *Deployment) createNew() error {
+	old, err := d.client.GetAppByGuid(d.state.OldAppGuid)
+	if err != nil {
+		return err
+	}
+	// a previous attempt may have created the app without recording it
+	existing, err := d.client.ListAppsByQuery(url.Values{"q": {
+		"name:" + d.state.AppName,
+		"space_guid:" + old.SpaceGuid,
+	}})
+	if err != nil {
+		return err
+	}
+	for _, app := range existing {
+		if app.Guid != old.Guid {
+			d.state.NewAppGuid = app.Guid
+			return nil
+		}
+	}
+	env := map[string]interface{}{}
+	for k, v := range old.Environment {
+		env[k] = v
+	}
+	for k, v := range d.config.Environment {
+		env[k] = v
+	}
+	req := cfclient.AppCreateRequest{
+		Name:                    d.state.AppName,
+		SpaceGuid:               old.SpaceGuid,
+		Memory:                  old.Memory,
+		Instances:               old.Instances,
+		DiskQuota:               old.DiskQuota,
+		StackGuid:               old.StackGuid,
+		State:                   cfclient.AppStopped,
+		Command:                 old.Command,
+		Buildpack:               old.Buildpack,
+		HealthCheckHttpEndpoint: old.HealthCheckHttpEndpoint,
+		HealthCheckType:         old.HealthCheckType,
+		HealthCheckTimeout:      old.HealthCheckTimeout,
+		Diego:                   old.Diego,
+		EnableSSH:               old.EnableSSH,
+		DockerImage:             old.DockerImage,
+		DockerCredentials:       old.DockerCredentials,
+		Environment:             env,
+		Ports:                   old.Ports,
+	}
+	if d.config.DockerImage != "" {
+		req.DockerImage = d.config.DockerImage
+	}
+	if d.config.DockerCredentials != nil {
+		req.DockerCredentials = d.config.DockerCredentials
+	}
+	app, err := d.client.CreateApp(req)
+	if err != nil {
+		return err
+	}
+	d.state.NewAppGuid = app.Guid
+	return nil
+}
+
+// bindServices binds the service instances bound to the old version which
+// are not bound to the new one yet. Binding parameters cannot be read back
+// from the Cloud Controller, so they are not carried over.
+func (d *Deployment) bindServices() error {
+	oldBindings, err := d.client.ListServiceBindingsByQuery(url.Values{"q": {"app_guid:" + d.state.OldAppGuid}})
+	if err != nil {
+		return err
+	}
+	newBindings, err := d.client.ListServiceBindingsByQuery(url.Values{"q": {"app_guid:" + d.state.NewAppGuid}})
+	if err != nil {
+		return err
+	}
+	bound := map[string]bool{}
+	for _, binding := range newBindings {
+		bound[binding.ServiceInstanceGuid] = true
+	}
+	for _, binding := range oldBindings {
+		if bound[binding.ServiceInstanceGuid] {
+			continue
+		}
+		_, err := d.client.CreateServiceBinding(cfclient.ServiceBindingRequest{
+			AppGuid:             d.state.NewAppGuid,
+			ServiceInstanceGuid: binding.ServiceInstanceGuid,
+		})
+		if err != nil {
+			return err
+		}
+		bound[binding.ServiceInstanceGuid] = true
+	}
+	return nil
+}
+
+func (d *Deployment) mapNewRoutes() error {
+	routes, err := d.client.GetAppRoutes(d.state.OldAppGuid)
+	if err != nil {
+		return err
+	}
+	// keep the routes of a previous attempt, the old version may already
+	// have lost some of them
+	known := map[string]bool{}
+	for _, guid := range d.state.RouteGuids {
+		known[guid] = true
+	}
+	for _, route := range routes {
+		if !known[route.Guid] {
+			d.state.RouteGuids = append(d.state.RouteGuids, route.Guid)
+		}
+	}
+	return d.mapRoutes(d.state.NewAppGuid)
+}
+
+// mapRoutes maps the recorded routes to the app which are not mapped yet.
+func (d *Deployment) mapRoutes(appGuid string) error {
+	mapped, err := d.routeSet(appGuid)
+	if err != nil {
+		return err
+	}
+	for _, routeGuid := range d.state.RouteGuids {
+		if mapped[routeGuid] {
+			continue
+		}
+		_, err := d.client.MapRoute(cfclient.RouteMap{AppGUID: appGuid, RouteGUID: routeGuid})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Deployment) unmapOldRoutes() error {
+	mapped, err := d.routeSet(d.state.OldAppGuid)
+	if err != nil {
+		return err
+	}
+	for _, routeGuid := range d.state.RouteGuids {
+		if !mapped[routeGuid] {
+			continue
+		}
+		err := d.client.UnmapRoute(cfclient.RouteMap{AppGUID: d.state.OldAppGuid, RouteGUID: routeGuid})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Deployment) routeSet(appGuid string) (map[string]bool, error) {
+	routes, err := d.client.GetAppRoutes(appGuid)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		set[route.Guid] = true
+	}
+	return set, nil
+}
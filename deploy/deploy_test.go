@@ -0,0 +1,369 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeClient keeps apps, route mappings and service bindings in memory.
+type fakeClient struct {
+	apps   map[string]cfclient.App
+	routes map[string][]string
+	// bindings maps app guids to the guids of their service instances
+	bindings map[string][]string
+	created  int
+	// failures maps a method name to the number of times it should fail
+	failures map[string]int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		apps: map[string]cfclient.App{
+			"old-guid": {
+				Guid:        "old-guid",
+				Name:        "my-app",
+				SpaceGuid:   "space-guid",
+				Memory:      256,
+				Instances:   2,
+				State:       cfclient.AppStarted,
+				Environment: map[string]interface{}{"FOO": "bar", "LEVEL": "info"},
+			},
+		},
+		routes:   map[string][]string{"old-guid": {"route-1", "route-2"}},
+		bindings: map[string][]string{"old-guid": {"db-guid", "cache-guid"}},
+		failures: map[string]int{},
+	}
+}
+
+func (f *fakeClient) fail(method string) error {
+	if f.failures[method] > 0 {
+		f.failures[method]--
+		return errors.Errorf("%s failed", method)
+	}
+	return nil
+}
+
+func (f *fakeClient) GetAppByGuid(guid string) (cfclient.App, error) {
+	app, ok := f.apps[guid]
+	if !ok {
+		return app, errors.Errorf("app %s not found", guid)
+	}
+	return app, nil
+}
+
+func (f *fakeClient) ListAppsByQuery(query url.Values) ([]cfclient.App, error) {
+	var apps []cfclient.App
+	for _, app := range f.apps {
+		matches := true
+		for _, q := range query["q"] {
+			switch {
+			case strings.HasPrefix(q, "name:"):
+				matches = matches && app.Name == strings.TrimPrefix(q, "name:")
+			case strings.HasPrefix(q, "space_guid:"):
+				matches = matches && app.SpaceGuid == strings.TrimPrefix(q, "space_guid:")
+			}
+		}
+		if matches {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+func (f *fakeClient) CreateApp(req cfclient.AppCreateRequest) (cfclient.App, error) {
+	if err := f.fail("CreateApp"); err != nil {
+		return cfclient.App{}, err
+	}
+	f.created++
+	app := cfclient.App{
+		Guid:              fmt.Sprintf("new-guid-%d", f.created),
+		Name:              req.Name,
+		SpaceGuid:         req.SpaceGuid,
+		Memory:            req.Memory,
+		Instances:         req.Instances,
+		State:             req.State,
+		DockerImage:       req.DockerImage,
+		DockerCredentials: req.DockerCredentials,
+		Environment:       req.Environment,
+	}
+	f.apps[app.Guid] = app
+	return app, nil
+}
+
+func (f *fakeClient) UpdateApp(guid string, req cfclient.AppUpdateRequest) (cfclient.App, error) {
+	app := f.apps[guid]
+	if req.Name != "" {
+		app.Name = req.Name
+	}
+	f.apps[guid] = app
+	return app, nil
+}
+
+func (f *fakeClient) DeleteApp(guid string) error {
+	if err := f.fail("DeleteApp"); err != nil {
+		return err
+	}
+	delete(f.apps, guid)
+	delete(f.routes, guid)
+	return nil
+}
+
+func (f *fakeClient) UploadAppBits(guid, dir string) error {
+	return f.fail("UploadAppBits")
+}
+
+func (f *fakeClient) setState(guid, state string) (cfclient.App, error) {
+	app := f.apps[guid]
+	app.State = state
+	f.apps[guid] = app
+	return app, nil
+}
+
+func (f *fakeClient) StartV2App(guid string) (cfclient.App, error) {
+	return f.setState(guid, cfclient.AppStarted)
+}
+
+func (f *fakeClient) StopV2App(guid string) (cfclient.App, error) {
+	return f.setState(guid, cfclient.AppStopped)
+}
+
+func (f *fakeClient) WaitForAppRunning(guid string, opts cfclient.WaitForAppOptions) error {
+	return f.fail("WaitForAppRunning")
+}
+
+func (f *fakeClient) GetAppRoutes(guid string) ([]cfclient.Route, error) {
+	var routes []cfclient.Route
+	for _, routeGuid := range f.routes[guid] {
+		routes = append(routes, cfclient.Route{Guid: routeGuid})
+	}
+	return routes, nil
+}
+
+func (f *fakeClient) MapRoute(routeMap cfclient.RouteMap) (cfclient.MappedRoute, error) {
+	if err := f.fail("MapRoute"); err != nil {
+		return cfclient.MappedRoute{}, err
+	}
+	f.routes[routeMap.AppGUID] = append(f.routes[routeMap.AppGUID], routeMap.RouteGUID)
+	return cfclient.MappedRoute{}, nil
+}
+
+func (f *fakeClient) UnmapRoute(routeMap cfclient.RouteMap) error {
+	var kept []string
+	for _, routeGuid := range f.routes[routeMap.AppGUID] {
+		if routeGuid != routeMap.RouteGUID {
+			kept = append(kept, routeGuid)
+		}
+	}
+	f.routes[routeMap.AppGUID] = kept
+	return nil
+}
+
+func (f *fakeClient) ListServiceBindingsByQuery(query url.Values) ([]cfclient.ServiceBinding, error) {
+	appGuid := strings.TrimPrefix(query.Get("q"), "app_guid:")
+	var bindings []cfclient.ServiceBinding
+	for _, instanceGuid := range f.bindings[appGuid] {
+		bindings = append(bindings, cfclient.ServiceBinding{AppGuid: appGuid, ServiceInstanceGuid: instanceGuid})
+	}
+	return bindings, nil
+}
+
+func (f *fakeClient) CreateServiceBinding(req cfclient.ServiceBindingRequest) (cfclient.ServiceBinding, error) {
+	if err := f.fail("CreateServiceBinding"); err != nil {
+		return cfclient.ServiceBinding{}, err
+	}
+	f.bindings[req.AppGuid] = append(f.bindings[req.AppGuid], req.ServiceInstanceGuid)
+	return cfclient.ServiceBinding{AppGuid: req.AppGuid, ServiceInstanceGuid: req.ServiceInstanceGuid}, nil
+}
+
+func TestDeploy(t *testing.T) {
+	Convey("Deploy app", t, func() {
+		client := newFakeClient()
+		var events []Event
+		config := Config{
+			AppGuid:     "old-guid",
+			Dir:         "app-dir",
+			Environment: map[string]interface{}{"LEVEL": "debug"},
+			OnEvent:     func(e Event) { events = append(events, e) },
+		}
+		d := New(client, config)
+		err := d.Run()
+		So(err, ShouldBeNil)
+		So(d.Done(), ShouldBeTrue)
+
+		So(client.apps, ShouldNotContainKey, "old-guid")
+		app := client.apps["new-guid-1"]
+		So(app.Name, ShouldEqual, "my-app")
+		So(app.Memory, ShouldEqual, 256)
+		So(app.Instances, ShouldEqual, 2)
+		So(app.State, ShouldEqual, cfclient.AppStarted)
+		So(app.Environment, ShouldResemble, map[string]interface{}{"FOO": "bar", "LEVEL": "debug"})
+		So(client.routes["new-guid-1"], ShouldResemble, []string{"route-1", "route-2"})
+		So(client.bindings["new-guid-1"], ShouldResemble, []string{"db-guid", "cache-guid"})
+
+		So(len(events), ShouldEqual, 2*len(Steps))
+		So(events[0], ShouldResemble, Event{Step: StepRenameOld, Type: EventStarted})
+		So(events[len(events)-1], ShouldResemble, Event{Step: StepRetireOld, Type: EventCompleted})
+	})
+
+	Convey("Deploy app keeping the old version", t, func() {
+		client := newFakeClient()
+		d := New(client, Config{AppGuid: "old-guid", Dir: "app-dir", KeepOld: true})
+		err := d.Run()
+		So(err, ShouldBeNil)
+		So(client.apps["old-guid"].Name, ShouldEqual, "my-app-venerable")
+		So(client.apps["old-guid"].State, ShouldEqual, cfclient.AppStopped)
+		So(client.routes["old-guid"], ShouldBeEmpty)
+	})
+
+	Convey("Deploy docker app from a private registry", t, func() {
+		client := newFakeClient()
+		old := client.apps["old-guid"]
+		old.DockerImage = "registry.example.com/my-app:1"
+		old.DockerCredentials = map[string]interface{}{"username": "deployer", "password": "secret"}
+		client.apps["old-guid"] = old
+		d := New(client, Config{AppGuid: "old-guid", DockerImage: "registry.example.com/my-app:2"})
+		err := d.Run()
+		So(err, ShouldBeNil)
+		app := client.apps["new-guid-1"]
+		So(app.DockerImage, ShouldEqual, "registry.example.com/my-app:2")
+		So(app.DockerCredentials, ShouldResemble, old.DockerCredentials)
+
+		client = newFakeClient()
+		client.apps["old-guid"] = old
+		credentials := map[string]interface{}{"username": "deployer", "password": "rotated"}
+		d = New(client, Config{AppGuid: "old-guid", DockerCredentials: credentials})
+		err = d.Run()
+		So(err, ShouldBeNil)
+		So(client.apps["new-guid-1"].DockerCredentials, ShouldResemble, credentials)
+	})
+
+	Convey("Deploy app without bits", t, func() {
+		client := newFakeClient()
+		d := New(client, Config{AppGuid: "old-guid"})
+		err := d.Run()
+		So(err, ShouldNotBeNil)
+		So(err.(StepError).Step, ShouldEqual, StepRenameOld)
+		So(client.apps["old-guid"].Name, ShouldEqual, "my-app")
+	})
+
+	Convey("Resume failed deployment", t, func() {
+		client := newFakeClient()
+		client.failures["MapRoute"] = 2
+		d := New(client, Config{AppGuid: "old-guid", Dir: "app-dir"})
+		err := d.Run()
+		So(err, ShouldNotBeNil)
+		So(err.(StepError).Step, ShouldEqual, StepMapRoutes)
+		So(client.apps["old-guid"].Name, ShouldEqual, "my-app-venerable")
+
+		// the state survives a round trip through JSON
+		b, err := json.Marshal(d.State())
+		So(err, ShouldBeNil)
+		var state State
+		err = json.Unmarshal(b, &state)
+		So(err, ShouldBeNil)
+		So(state.Completed, ShouldResemble, []Step{StepRenameOld, StepCreateNew, StepUploadBits, StepBindServices, StepStartNew, StepWaitHealthy})
+
+		d = Resume(client, Config{Dir: "app-dir"}, state)
+		err = d.Run()
+		So(err, ShouldNotBeNil)
+		err = d.Run()
+		So(err, ShouldBeNil)
+		So(client.created, ShouldEqual, 1)
+		So(client.apps, ShouldNotContainKey, "old-guid")
+		So(client.routes["new-guid-1"], ShouldResemble, []string{"route-1", "route-2"})
+	})
+
+	Convey("Restart deployment whose state was lost after renaming", t, func() {
+		client := newFakeClient()
+		client.failures["CreateApp"] = 1
+		config := Config{AppGuid: "old-guid", Dir: "app-dir", KeepOld: true}
+		err := New(client, config).Run()
+		So(err, ShouldNotBeNil)
+		So(client.apps["old-guid"].Name, ShouldEqual, "my-app-venerable")
+
+		d := New(client, config)
+		err = d.Run()
+		So(err, ShouldBeNil)
+		So(d.State().AppName, ShouldEqual, "my-app")
+		So(client.apps["old-guid"].Name, ShouldEqual, "my-app-venerable")
+		So(client.apps["new-guid-1"].Name, ShouldEqual, "my-app")
+	})
+
+	Convey("Restart deployment whose state was lost after creating the new version", t, func() {
+		client := newFakeClient()
+		client.failures["UploadAppBits"] = 1
+		config := Config{AppGuid: "old-guid", Dir: "app-dir"}
+		err := New(client, config).Run()
+		So(err, ShouldNotBeNil)
+		So(err.(StepError).Step, ShouldEqual, StepUploadBits)
+
+		d := New(client, config)
+		err = d.Run()
+		So(err, ShouldBeNil)
+		So(client.created, ShouldEqual, 1)
+		So(d.State().NewAppGuid, ShouldEqual, "new-guid-1")
+		So(client.routes["new-guid-1"], ShouldResemble, []string{"route-1", "route-2"})
+	})
+
+	Convey("Retry binding services", t, func() {
+		client := newFakeClient()
+		client.failures["CreateServiceBinding"] = 1
+		d := New(client, Config{AppGuid: "old-guid", Dir: "app-dir"})
+		err := d.Run()
+		So(err, ShouldNotBeNil)
+		So(err.(StepError).Step, ShouldEqual, StepBindServices)
+		So(client.apps["new-guid-1"].State, ShouldEqual, cfclient.AppStopped)
+
+		err = d.Run()
+		So(err, ShouldBeNil)
+		So(client.bindings["new-guid-1"], ShouldResemble, []string{"db-guid", "cache-guid"})
+	})
+
+	Convey("Roll back failed deployment", t, func() {
+		client := newFakeClient()
+		client.failures["WaitForAppRunning"] = 1
+		var events []Event
+		d := New(client, Config{
+			AppGuid: "old-guid",
+			Dir:     "app-dir",
+			OnEvent: func(e Event) { events = append(events, e) },
+		})
+		err := d.Run()
+		So(err, ShouldNotBeNil)
+		So(err.(StepError).Step, ShouldEqual, StepWaitHealthy)
+		So(events[len(events)-1].Type, ShouldEqual, EventFailed)
+
+		err = d.Rollback()
+		So(err, ShouldBeNil)
+		So(client.apps, ShouldNotContainKey, "new-guid-1")
+		So(client.apps["old-guid"].Name, ShouldEqual, "my-app")
+		So(client.routes["old-guid"], ShouldResemble, []string{"route-1", "route-2"})
+		So(d.State().Completed, ShouldBeEmpty)
+	})
+
+	Convey("Roll back automatically after routes were moved", t, func() {
+		client := newFakeClient()
+		client.failures["DeleteApp"] = 1
+		d := New(client, Config{AppGuid: "old-guid", Dir: "app-dir", AutoRollback: true})
+		err := d.Run()
+		So(err, ShouldNotBeNil)
+		So(err.(StepError).Step, ShouldEqual, StepRetireOld)
+		So(client.routes["old-guid"], ShouldResemble, []string{"route-1", "route-2"})
+		So(client.apps["old-guid"].Name, ShouldEqual, "my-app")
+		So(client.apps, ShouldNotContainKey, "new-guid-1")
+	})
+
+	Convey("Completed deployment cannot be rolled back", t, func() {
+		client := newFakeClient()
+		d := New(client, Config{AppGuid: "old-guid", Dir: "app-dir"})
+		So(d.Run(), ShouldBeNil)
+		So(d.Rollback(), ShouldNotBeNil)
+	})
+}
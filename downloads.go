@@ -0,0 +1,133 @@
+package cfclient
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// DownloadAppBits streams the package bits of the V2 app identified by guid
+// into w. If the download is redirected to the blobstore the bearer token is
+// not forwarded. When the response carries a Content-MD5 header the
+// downloaded content is verified against it; on mismatch an error is returned
+// after the content has been written, so callers writing to disk should
+// discard the file.
+func (c *Client) DownloadAppBits(guid string, w io.Writer) error {
+	return c.downloadBlob(fmt.Sprintf("/v2/apps/%s/download", guid), w, nil)
+}
+
+// DownloadDroplet streams the current droplet of the V2 app identified by
+// guid into w. See DownloadAppBits for redirect and checksum handling.
+func (c *Client) DownloadDroplet(guid string, w io.Writer) error {
+	return c.downloadBlob(fmt.Sprintf("/v2/apps/%s/droplet/download", guid), w, nil)
+}
+
+// DownloadV3Droplet streams the V3 droplet identified by guid into w and
+// verifies it against the checksum recorded by the Cloud Controller. See
+// DownloadAppBits for redirect handling.
+func (c *Client) DownloadV3Droplet(guid string, w io.Writer) error {
+	droplet, err := c.GetV3Droplet(guid)
+	if err != nil {
+		return err
+	}
+	checksum := droplet.Checksum
+	if checksum != nil && checksum.Value == "" {
+		checksum = nil
+	}
+	return c.downloadBlob(fmt.Sprintf("/v3/droplets/%s/download", guid), w, checksum)
+}
+
+// downloadBlob fetches requestURL from the Cloud Controller and follows a
+// redirect to the blobstore with an unauthenticated client.
+func (c *Client) downloadBlob(requestURL string, w io.Writer, checksum *V3Checksum) error {
+	resp, err := c.DoRequestWithoutRedirects(c.NewRequest("GET", requestURL))
+	if err != nil {
+		return errors.Wrapf(err, "Error requesting %s", requestURL)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+		location, err := resp.Location()
+		if err != nil {
+			return errors.Wrapf(err, "Error reading redirect location of %s", requestURL)
+		}
+		resp, err = c.blobstoreGet(location.String())
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+	default:
+		return errors.Errorf("Error downloading %s, response code: %d", requestURL, resp.StatusCode)
+	}
+
+	if checksum == nil {
+		if contentMD5 := resp.Header.Get("Content-MD5"); contentMD5 != "" {
+			sum, err := base64.StdEncoding.DecodeString(contentMD5)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid Content-MD5 header %s", contentMD5)
+			}
+			checksum = &V3Checksum{Type: "md5", Value: fmt.Sprintf("%x", sum)}
+		}
+	}
+
+	var h hash.Hash
+	if checksum != nil {
+		switch checksum.Type {
+		case "md5":
+			h = md5.New()
+		case "sha1":
+			h = sha1.New()
+		case "sha256":
+			h = sha256.New()
+		default:
+			return errors.Errorf("Unsupported checksum type %s", checksum.Type)
+		}
+		w = io.MultiWriter(w, h)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return errors.Wrapf(err, "Error downloading %s", requestURL)
+	}
+
+	if h != nil {
+		if actual := fmt.Sprintf("%x", h.Sum(nil)); actual != checksum.Value {
+			return errors.Errorf("Checksum mismatch downloading %s: expected %s %s, got %s", requestURL, checksum.Type, checksum.Value, actual)
+		}
+	}
+	return nil
+}
+
+// blobstoreGet downloads from the blobstore without the Cloud Controller
+// credentials, the redirect URL is signed.
+func (c *Client) blobstoreGet(location string) (*http.Response, error) {
+	transport := shallowDefaultTransport()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.Config.SkipSslValidation}
+	httpClient := &http.Client{
+		Transport: transport,
+		Timeout:   c.Config.HttpClient.Timeout,
+	}
+	req, err := http.NewRequest("GET", location, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating blobstore request")
+	}
+	req.Header.Set("User-Agent", c.Config.UserAgent)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error requesting blobstore")
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("Error downloading from blobstore, response code: %d", resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,125 @@
+package cfclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDownloadAppBits(t *testing.T) {
+	Convey("Download app bits streamed by the Cloud Controller", t, func() {
+		setup(MockRoute{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/download", "app-bits", "", 200, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		buf := bytes.NewBuffer(nil)
+		err = client.DownloadAppBits("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", buf)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "app-bits")
+	})
+}
+
+func TestDownloadDroplet(t *testing.T) {
+	Convey("Download droplet redirected to the blobstore", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+
+		var blobstoreAuth string
+		blobstore := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			blobstoreAuth = r.Header.Get("Authorization")
+			w.Header().Set("Content-MD5", "T1dQo9mMadR6tERexrqt1g==")
+			w.Write([]byte("droplet-content"))
+		}))
+		defer blobstore.Close()
+
+		var ccAuth string
+		mux.HandleFunc("/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/droplet/download", func(w http.ResponseWriter, r *http.Request) {
+			ccAuth = r.Header.Get("Authorization")
+			http.Redirect(w, r, blobstore.URL+"/droplets/signed", http.StatusFound)
+		})
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		buf := bytes.NewBuffer(nil)
+		err = client.DownloadDroplet("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", buf)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "droplet-content")
+		So(ccAuth, ShouldEqual, "Bearer foobar")
+		So(blobstoreAuth, ShouldEqual, "")
+	})
+
+	Convey("Download droplet with wrong Content-MD5", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+
+		mux.HandleFunc("/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/droplet/download", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-MD5", "T1dQo9mMadR6tERexrqt1g==")
+			w.Write([]byte("tampered-content"))
+		})
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DownloadDroplet("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", bytes.NewBuffer(nil))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Checksum mismatch")
+	})
+}
+
+func TestDownloadV3Droplet(t *testing.T) {
+	Convey("Download V3 droplet and verify its checksum", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16", v3DropletPayload, "", 200, "", nil},
+			{"GET", "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16/download", "droplet-content", "", 200, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		buf := bytes.NewBuffer(nil)
+		err = client.DownloadV3Droplet("585bc3c1-3743-497d-88b0-403ad6b56d16", buf)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "droplet-content")
+	})
+
+	Convey("Download V3 droplet with checksum mismatch", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16", v3DropletPayload, "", 200, "", nil},
+			{"GET", "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16/download", "corrupted", "", 200, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DownloadV3Droplet("585bc3c1-3743-497d-88b0-403ad6b56d16", bytes.NewBuffer(nil))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Checksum mismatch")
+	})
+}
@@ -0,0 +1,290 @@
+package cfclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/noaa/consumer"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	// FirehoseAll streams every envelope of the firehose
+	FirehoseAll FirehoseFilter = iota
+	// FirehoseLogs only streams the log messages
+	FirehoseLogs
+	// FirehoseMetrics only streams the metrics
+	FirehoseMetrics
+)
+
+const defaultFirehoseBufferSize = 1000
+
+// FirehoseFilter restricts the envelopes streamed by the firehose.
+type FirehoseFilter int
+
+// FirehoseOptions configures FirehoseWithOptions. Zero values are replaced
+// by their defaults.
+type FirehoseOptions struct {
+	Filter FirehoseFilter
+	// BufferSize is the number of envelopes buffered for a slow consumer,
+	// defaults to 1000. Envelopes received while the buffer is full are
+	// dropped so that Doppler does not disconnect the subscription.
+	BufferSize int
+	// MinRetryDelay is the delay before the first reconnection, it doubles
+	// on every attempt up to MaxRetryDelay. Defaults to 500 milliseconds.
+	MinRetryDelay time.Duration
+	// MaxRetryDelay defaults to 1 minute.
+	MaxRetryDelay time.Duration
+	// MaxRetryCount is the number of consecutive reconnections after which
+	// streaming stops, defaults to 1000.
+	MaxRetryCount int
+}
+
+// FirehoseEnvelope is an envelope of the firehose. The field matching
+// EventType is set.
+type FirehoseEnvelope struct {
+	// EventType is one of LogMessage, ContainerMetric, CounterEvent,
+	// ValueMetric, HttpStartStop and Error.
+	EventType  string
+	Origin     string
+	Deployment string
+	Job        string
+	Index      string
+	Ip         string
+	Timestamp  time.Time
+	Tags       map[string]string
+
+	LogMessage      *LogMessage
+	ContainerMetric *ContainerMetric
+	CounterEvent    *CounterEvent
+	ValueMetric     *ValueMetric
+	HttpStartStop   *HttpStartStop
+	Error           *FirehoseError
+}
+
+// ContainerMetric is the resource usage of an app instance.
+type ContainerMetric struct {
+	AppGuid          string
+	InstanceIndex    int
+	CpuPercentage    float64
+	MemoryBytes      uint64
+	DiskBytes        uint64
+	MemoryBytesQuota uint64
+	DiskBytesQuota   uint64
+}
+
+// CounterEvent is an increment of a counter emitted by a platform component.
+type CounterEvent struct {
+	Name  string
+	Delta uint64
+	Total uint64
+}
+
+// ValueMetric is a measurement emitted by a platform component.
+type ValueMetric struct {
+	Name  string
+	Value float64
+	Unit  string
+}
+
+// HttpStartStop is an HTTP request handled by the routers or an app.
+type HttpStartStop struct {
+	StartTimestamp time.Time
+	StopTimestamp  time.Time
+	RequestId      string
+	// PeerType is Client for requests made and Server for requests served.
+	PeerType      string
+	Method        string
+	Uri           string
+	RemoteAddress string
+	UserAgent     string
+	StatusCode    int
+	ContentLength int64
+	AppGuid       string
+	InstanceIndex int
+	InstanceId    string
+	Forwarded     []string
+}
+
+// FirehoseError is an error reported by a platform component.
+type FirehoseError struct {
+	Source  string
+	Code    int
+	Message string
+}
+
+// FirehoseSlowConsumerError is sent on the error channel of the firehose
+// when envelopes are dropped because they are not read fast enough.
+type FirehoseSlowConsumerError struct {
+	// Dropped is the number of envelopes dropped since streaming started.
+	Dropped uint64
+}
+
+func (e FirehoseSlowConsumerError) Error() string {
+	return fmt.Sprintf("cfclient: firehose consumer too slow, %d envelopes dropped", e.Dropped)
+}
+
+func newFirehoseEnvelope(e *events.Envelope) FirehoseEnvelope {
+	envelope := FirehoseEnvelope{
+		EventType:  e.GetEventType().String(),
+		Origin:     e.GetOrigin(),
+		Deployment: e.GetDeployment(),
+		Job:        e.GetJob(),
+		Index:      e.GetIndex(),
+		Ip:         e.GetIp(),
+		Timestamp:  time.Unix(0, e.GetTimestamp()),
+		Tags:       e.GetTags(),
+	}
+	switch e.GetEventType() {
+	case events.Envelope_LogMessage:
+		m := newLogMessage(e.GetLogMessage())
+		envelope.LogMessage = &m
+	case events.Envelope_ContainerMetric:
+		m := e.GetContainerMetric()
+		envelope.ContainerMetric = &ContainerMetric{
+			AppGuid:          m.GetApplicationId(),
+			InstanceIndex:    int(m.GetInstanceIndex()),
+			CpuPercentage:    m.GetCpuPercentage(),
+			MemoryBytes:      m.GetMemoryBytes(),
+			DiskBytes:        m.GetDiskBytes(),
+			MemoryBytesQuota: m.GetMemoryBytesQuota(),
+			DiskBytesQuota:   m.GetDiskBytesQuota(),
+		}
+	case events.Envelope_CounterEvent:
+		m := e.GetCounterEvent()
+		envelope.CounterEvent = &CounterEvent{Name: m.GetName(), Delta: m.GetDelta(), Total: m.GetTotal()}
+	case events.Envelope_ValueMetric:
+		m := e.GetValueMetric()
+		envelope.ValueMetric = &ValueMetric{Name: m.GetName(), Value: m.GetValue(), Unit: m.GetUnit()}
+	case events.Envelope_HttpStartStop:
+		m := e.GetHttpStartStop()
+		envelope.HttpStartStop = &HttpStartStop{
+			StartTimestamp: time.Unix(0, m.GetStartTimestamp()),
+			StopTimestamp:  time.Unix(0, m.GetStopTimestamp()),
+			RequestId:      uuidString(m.GetRequestId()),
+			PeerType:       m.GetPeerType().String(),
+			Method:         m.GetMethod().String(),
+			Uri:            m.GetUri(),
+			RemoteAddress:  m.GetRemoteAddress(),
+			UserAgent:      m.GetUserAgent(),
+			StatusCode:     int(m.GetStatusCode()),
+			ContentLength:  m.GetContentLength(),
+			AppGuid:        uuidString(m.GetApplicationId()),
+			InstanceIndex:  int(m.GetInstanceIndex()),
+			InstanceId:     m.GetInstanceId(),
+			Forwarded:      m.GetForwarded(),
+		}
+	case events.Envelope_Error:
+		m := e.GetError()
+		envelope.Error = &FirehoseError{Source: m.GetSource(), Code: int(m.GetCode()), Message: m.GetMessage()}
+	}
+	return envelope
+}
+
+// uuidString formats the UUIDs of dropsonde, which are encoded as two
+// little endian integers.
+func uuidString(u *events.UUID) string {
+	if u == nil {
+		return ""
+	}
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], u.GetLow())
+	binary.LittleEndian.PutUint64(b[8:], u.GetHigh())
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Firehose streams the envelopes of the whole platform until ctx is done,
+// see FirehoseWithOptions.
+func (c *Client) Firehose(ctx context.Context, subscriptionId string) (<-chan FirehoseEnvelope, <-chan error) {
+	return c.FirehoseWithOptions(ctx, subscriptionId, FirehoseOptions{})
+}
+
+// FirehoseWithOptions streams the envelopes of the whole platform until ctx
+// is done. It requires a token with the doppler.firehose scope. Doppler
+// shards the firehose among the consumers sharing a subscriptionId, so that
+// each envelope is only received by one of them.
+//
+// The connection is reestablished with an exponential backoff when it drops;
+// the errors which caused the reconnections are sent on the error channel,
+// along with FirehoseSlowConsumerError when envelopes are dropped. Both
+// channels are closed once streaming stopped.
+func (c *Client) FirehoseWithOptions(ctx context.Context, subscriptionId string, opts FirehoseOptions) (<-chan FirehoseEnvelope, <-chan error) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultFirehoseBufferSize
+	}
+	envelopes := make(chan FirehoseEnvelope, opts.BufferSize)
+	errs := make(chan error, 1)
+	cons, err := c.newLogConsumer()
+	if err == nil {
+		var token string
+		token, err = c.GetToken()
+		if err == nil {
+			if opts.MinRetryDelay > 0 {
+				cons.SetMinRetryDelay(opts.MinRetryDelay)
+			}
+			if opts.MaxRetryDelay > 0 {
+				cons.SetMaxRetryDelay(opts.MaxRetryDelay)
+			}
+			if opts.MaxRetryCount > 0 {
+				cons.SetMaxRetryCount(opts.MaxRetryCount)
+			}
+			var consEnvelopes <-chan *events.Envelope
+			var consErrs <-chan error
+			switch opts.Filter {
+			case FirehoseLogs:
+				consEnvelopes, consErrs = cons.FilteredFirehose(subscriptionId, token, consumer.LogMessages)
+			case FirehoseMetrics:
+				consEnvelopes, consErrs = cons.FilteredFirehose(subscriptionId, token, consumer.Metrics)
+			default:
+				consEnvelopes, consErrs = cons.Firehose(subscriptionId, token)
+			}
+			go forwardFirehose(ctx, cons, consEnvelopes, consErrs, envelopes, errs)
+			return envelopes, errs
+		}
+	}
+	errs <- err
+	close(errs)
+	close(envelopes)
+	return envelopes, errs
+}
+
+func forwardFirehose(ctx context.Context, cons *consumer.Consumer, consEnvelopes <-chan *events.Envelope, consErrs <-chan error, envelopes chan<- FirehoseEnvelope, errs chan<- error) {
+	defer close(errs)
+	defer close(envelopes)
+	var dropped uint64
+	done := ctx.Done()
+	// the consumer channels are drained until closed so that it can stop
+	for consEnvelopes != nil || consErrs != nil {
+		select {
+		case <-done:
+			done = nil
+			cons.Close()
+		case e, ok := <-consEnvelopes:
+			if !ok {
+				consEnvelopes = nil
+			} else if done != nil {
+				select {
+				case envelopes <- newFirehoseEnvelope(e):
+				default:
+					dropped++
+					select {
+					case errs <- FirehoseSlowConsumerError{Dropped: dropped}:
+					default:
+					}
+				}
+			}
+		case err, ok := <-consErrs:
+			if !ok {
+				consErrs = nil
+			} else if err != nil && done != nil {
+				select {
+				case errs <- errors.Wrap(err, "Error streaming the firehose"):
+				case <-ctx.Done():
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package cfclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+)
+
+func testFirehoseEnvelopes() []*events.Envelope {
+	envelope := func(eventType events.Envelope_EventType) *events.Envelope {
+		return &events.Envelope{
+			Origin:     proto.String("rep"),
+			EventType:  eventType.Enum(),
+			Timestamp:  proto.Int64(1550000000000000000),
+			Deployment: proto.String("cf"),
+			Job:        proto.String("diego-cell"),
+			Index:      proto.String("5f8b1c3e-0c32-4d1f-9e36-5b8d2ac9a1b4"),
+			Ip:         proto.String("10.0.16.5"),
+		}
+	}
+	containerMetric := envelope(events.Envelope_ContainerMetric)
+	containerMetric.ContainerMetric = &events.ContainerMetric{
+		ApplicationId:    proto.String("9902530c-c634-4864-a189-71d763cb12e2"),
+		InstanceIndex:    proto.Int32(1),
+		CpuPercentage:    proto.Float64(12.5),
+		MemoryBytes:      proto.Uint64(518123520),
+		DiskBytes:        proto.Uint64(151150592),
+		MemoryBytesQuota: proto.Uint64(536870912),
+		DiskBytesQuota:   proto.Uint64(1073741824),
+	}
+	httpStartStop := envelope(events.Envelope_HttpStartStop)
+	httpStartStop.HttpStartStop = &events.HttpStartStop{
+		StartTimestamp: proto.Int64(1550000000000000000),
+		StopTimestamp:  proto.Int64(1550000000500000000),
+		RequestId:      &events.UUID{Low: proto.Uint64(0x0706050403020100), High: proto.Uint64(0x0f0e0d0c0b0a0908)},
+		PeerType:       events.PeerType_Server.Enum(),
+		Method:         events.Method_GET.Enum(),
+		Uri:            proto.String("https://example-app.example.com/"),
+		RemoteAddress:  proto.String("10.0.0.1:51234"),
+		UserAgent:      proto.String("curl/7.54.0"),
+		StatusCode:     proto.Int32(200),
+		ContentLength:  proto.Int64(42),
+		InstanceIndex:  proto.Int32(1),
+		Forwarded:      []string{"203.0.113.7"},
+	}
+	valueMetric := envelope(events.Envelope_ValueMetric)
+	valueMetric.ValueMetric = &events.ValueMetric{
+		Name:  proto.String("numCPUS"),
+		Value: proto.Float64(4),
+		Unit:  proto.String("count"),
+	}
+	counterEvent := envelope(events.Envelope_CounterEvent)
+	counterEvent.CounterEvent = &events.CounterEvent{
+		Name:  proto.String("requests"),
+		Delta: proto.Uint64(2),
+		Total: proto.Uint64(42),
+	}
+	return []*events.Envelope{containerMetric, httpStartStop, valueMetric, counterEvent}
+}
+
+// firehoseStandIn streams the given envelopes on the firehose, dropping the
+// connection after each of them so that clients have to reconnect.
+func firehoseStandIn(t *testing.T, envelopes []*events.Envelope, filter *string) *httptest.Server {
+	var connections int32
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/firehose/metrics-collector" || r.Header.Get("Authorization") != "bearer foobar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if filter != nil {
+			*filter = r.URL.Query().Get("filter-type")
+		}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Error upgrading firehose: %s", err)
+			return
+		}
+		defer ws.Close()
+		if atomic.AddInt32(&connections, 1) > 1 {
+			ws.ReadMessage()
+			return
+		}
+		for _, envelope := range envelopes {
+			data, _ := proto.Marshal(envelope)
+			ws.WriteMessage(websocket.BinaryMessage, data)
+		}
+	}))
+}
+
+func newFirehoseTestClient(doppler *httptest.Server) *Client {
+	c := &Config{
+		ApiAddress: server.URL,
+		Token:      "foobar",
+	}
+	client, err := NewClient(c)
+	So(err, ShouldBeNil)
+	client.Endpoint.DopplerEndpoint = strings.Replace(doppler.URL, "http", "ws", 1)
+	return client
+}
+
+func TestFirehose(t *testing.T) {
+	Convey("Stream the firehose", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		var filter string
+		doppler := firehoseStandIn(t, testFirehoseEnvelopes(), &filter)
+		defer doppler.Close()
+		client := newFirehoseTestClient(doppler)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		envelopes, errs := client.FirehoseWithOptions(ctx, "metrics-collector", FirehoseOptions{
+			Filter:        FirehoseMetrics,
+			MinRetryDelay: 10 * time.Millisecond,
+		})
+
+		var received []FirehoseEnvelope
+		var reconnections int
+		for len(received) < 4 || reconnections == 0 {
+			select {
+			case envelope := <-envelopes:
+				received = append(received, envelope)
+			case err := <-errs:
+				So(err, ShouldNotBeNil)
+				reconnections++
+			case <-time.After(5 * time.Second):
+				panic("Timed out streaming the firehose")
+			}
+		}
+		So(filter, ShouldEqual, "metrics")
+
+		So(received[0].EventType, ShouldEqual, "ContainerMetric")
+		So(received[0].Job, ShouldEqual, "diego-cell")
+		So(received[0].Timestamp, ShouldResemble, time.Unix(1550000000, 0))
+		So(received[0].ContainerMetric, ShouldResemble, &ContainerMetric{
+			AppGuid:          "9902530c-c634-4864-a189-71d763cb12e2",
+			InstanceIndex:    1,
+			CpuPercentage:    12.5,
+			MemoryBytes:      518123520,
+			DiskBytes:        151150592,
+			MemoryBytesQuota: 536870912,
+			DiskBytesQuota:   1073741824,
+		})
+
+		So(received[1].EventType, ShouldEqual, "HttpStartStop")
+		httpStartStop := received[1].HttpStartStop
+		So(httpStartStop.RequestId, ShouldEqual, "00010203-0405-0607-0809-0a0b0c0d0e0f")
+		So(httpStartStop.PeerType, ShouldEqual, "Server")
+		So(httpStartStop.Method, ShouldEqual, "GET")
+		So(httpStartStop.StatusCode, ShouldEqual, 200)
+		So(httpStartStop.StopTimestamp.Sub(httpStartStop.StartTimestamp), ShouldEqual, 500*time.Millisecond)
+		So(httpStartStop.AppGuid, ShouldEqual, "")
+		So(httpStartStop.Forwarded, ShouldResemble, []string{"203.0.113.7"})
+
+		So(received[2].ValueMetric, ShouldResemble, &ValueMetric{Name: "numCPUS", Value: 4, Unit: "count"})
+		So(received[3].CounterEvent, ShouldResemble, &CounterEvent{Name: "requests", Delta: 2, Total: 42})
+
+		cancel()
+		for range envelopes {
+		}
+		for range errs {
+		}
+	})
+
+	Convey("Drop envelopes for slow consumers", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		doppler := firehoseStandIn(t, testFirehoseEnvelopes(), nil)
+		defer doppler.Close()
+		client := newFirehoseTestClient(doppler)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		envelopes, errs := client.FirehoseWithOptions(ctx, "metrics-collector", FirehoseOptions{BufferSize: 1})
+
+		for {
+			err := <-errs
+			if slow, ok := err.(FirehoseSlowConsumerError); ok {
+				So(slow.Dropped, ShouldBeGreaterThan, 0)
+				break
+			}
+		}
+		envelope := <-envelopes
+		So(envelope.EventType, ShouldEqual, "ContainerMetric")
+
+		cancel()
+		for range envelopes {
+		}
+		for range errs {
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package cfclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// v2JobPollInterval is the time between two polls of a V2 job
+	v2JobPollInterval = time.Second
+	// v2JobTimeout is the maximum time to wait for a V2 job to finish
+	v2JobTimeout = 10 * time.Minute
+)
+
+const (
+	// JobProcessing is the state of V3 jobs in progress
+	JobProcessing = "PROCESSING"
+	// JobPolling is the state of V3 jobs waiting on a service broker
+	JobPolling = "POLLING"
+	// JobComplete is the state of V3 jobs which succeeded
+	JobComplete = "COMPLETE"
+	// JobFailed is the state of V3 jobs which failed
+	JobFailed = "FAILED"
+)
+
+const (
+	defaultJobTimeout  = 10 * time.Minute
+	defaultJobInterval = time.Second
+)
+
+// jobResource is a V2 asynchronous job as returned by /v2/jobs/:guid and by
+// the endpoints which run asynchronously, e.g. /v2/apps/:guid/bits?async=true.
+type jobResource struct {
+	Meta   Meta      `json:"metadata"`
+	Entity jobEntity `json:"entity"`
+}
+
+type jobEntity struct {
+	Guid         string            `json:"guid"`
+	Status       string            `json:"status"`
+	Error        string            `json:"error"`
+	ErrorDetails CloudFoundryError `json:"error_details"`
+}
+
+func (c *Client) getV2Job(requestURL string) (jobResource, error) {
+	var job jobResource
+	resp, err := c.DoRequest(c.NewRequest("GET", requestURL))
+	if err != nil {
+		return job, errors.Wrap(err, "Error requesting job")
+	}
+	defer resp.Body.Close()
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return job, errors.Wrap(err, "Error reading job response body")
+	}
+	err = json.Unmarshal(resBody, &job)
+	if err != nil {
+		return job, errors.Wrap(err, "Error unmarshalling job")
+	}
+	return job, nil
+}
+
+// waitForV2Job polls the given V2 job until it has finished. A failed job is
+// reported through the CloudFoundryError contained in its error details.
+func (c *Client) waitForV2Job(job jobResource) error {
+	deadline := time.Now().Add(v2JobTimeout)
+	for {
+		switch job.Entity.Status {
+		case "finished":
+			return nil
+		case "failed":
+			if job.Entity.ErrorDetails.ErrorCode != "" {
+				return job.Entity.ErrorDetails
+			}
+			return errors.Errorf("Job %s failed: %s", job.Meta.Guid, job.Entity.Error)
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("Timed out after %s waiting for job %s", v2JobTimeout, job.Meta.Guid)
+		}
+		time.Sleep(v2JobPollInterval)
+
+		var err error
+		job, err = c.getV2Job("/v2/jobs/" + job.Meta.Guid)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Job is a V3 asynchronous job, as referenced by the Location header of the
+// V3 endpoints which answer 202, e.g. app deletion or apply_manifest.
+type Job struct {
+	GUID string `json:"guid"`
+	// Operation tells what the job does, e.g. app.delete.
+	Operation string `json:"operation"`
+	// State is one of JobProcessing, JobPolling, JobComplete and JobFailed.
+	State string `json:"state"`
+	// Errors is only set when the job failed.
+	Errors []V3Error `json:"errors"`
+	// Warnings may be set whatever the state of the job.
+	Warnings  []JobWarning      `json:"warnings"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Links     map[string]V3Link `json:"links"`
+}
+
+// JobWarning is a warning reported by a V3 job.
+type JobWarning struct {
+	Detail string `json:"detail"`
+}
+
+// V3Error is an error as reported by the V3 API.
+type V3Error struct {
+	Code   int    `json:"code"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// CloudFoundryError returns e as the V2 error type.
+func (e V3Error) CloudFoundryError() CloudFoundryError {
+	return CloudFoundryError{Code: e.Code, ErrorCode: e.Title, Description: e.Detail}
+}
+
+// WaitForJobOptions configures WaitForJob. Zero values are replaced by their
+// defaults.
+type WaitForJobOptions struct {
+	// Timeout defaults to 10 minutes.
+	Timeout time.Duration
+	// Interval is the time between two polls of the job, defaults to 1
+	// second.
+	Interval time.Duration
+}
+
+// JobFailedError is returned by WaitForJob when the job failed. Its cause,
+// as returned by errors.Cause, is the first error of the job as a
+// CloudFoundryError.
+type JobFailedError struct {
+	JobGUID   string
+	Operation string
+	Errors    []CloudFoundryError
+	Warnings  []string
+}
+
+func (e JobFailedError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("cfclient: job %s (%s) failed", e.JobGUID, e.Operation)
+	}
+	return fmt.Sprintf("cfclient: job %s (%s) failed: %s", e.JobGUID, e.Operation, e.Errors[0].Description)
+}
+
+// Cause returns the first error of the job, or a generic one when the job
+// failed without reporting any.
+func (e JobFailedError) Cause() error {
+	if len(e.Errors) == 0 {
+		return errors.Errorf("Job %s failed", e.JobGUID)
+	}
+	return e.Errors[0]
+}
+
+// GetJob returns the V3 job at location, which is either the Location
+// header of an asynchronous call or the guid of the job.
+func (c *Client) GetJob(location string) (*Job, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid job location %s", location)
+	}
+	requestURL := u.RequestURI()
+	if !strings.Contains(location, "/") {
+		requestURL = "/v3/jobs/" + location
+	}
+	resp, err := c.DoRequest(c.NewRequest("GET", requestURL))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting job %s", location)
+	}
+	var job Job
+	if err := decodeV3Response(resp, http.StatusOK, &job); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting job %s", location)
+	}
+	return &job, nil
+}
+
+// WaitForJob polls the V3 job at location, see GetJob, until it is COMPLETE
+// or FAILED. A failed job is returned along with a JobFailedError; the
+// warnings of a completed job are left in Job.Warnings.
+func (c *Client) WaitForJob(location string, opts WaitForJobOptions) (*Job, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultJobTimeout
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultJobInterval
+	}
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		job, err := c.GetJob(location)
+		if err != nil {
+			return nil, err
+		}
+		switch job.State {
+		case JobComplete:
+			return job, nil
+		case JobFailed:
+			failed := JobFailedError{JobGUID: job.GUID, Operation: job.Operation}
+			for _, e := range job.Errors {
+				failed.Errors = append(failed.Errors, e.CloudFoundryError())
+			}
+			for _, w := range job.Warnings {
+				failed.Warnings = append(failed.Warnings, w.Detail)
+			}
+			return job, failed
+		}
+		if time.Now().After(deadline) {
+			return job, errors.Errorf("Timed out after %s waiting for job %s, state: %s", opts.Timeout, job.GUID, job.State)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
+
+// waitForJobLocation waits for the job at location with the default
+// WaitForJobOptions, if the Cloud Controller returned one.
+func (c *Client) waitForJobLocation(location string) error {
+	if location == "" {
+		return nil
+	}
+	_, err := c.WaitForJob(location, WaitForJobOptions{})
+	return err
+}
@@ -0,0 +1,119 @@
+package cfclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testJobGuid = "c33a5caf-77e0-4d6e-b587-5555d339bc9a"
+
+func TestGetJob(t *testing.T) {
+	Convey("Get job by location and guid", t, func() {
+		setup(MockRoute{"GET", "/v3/jobs/" + testJobGuid, v3AppDeleteJobProcessingPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		job, err := client.GetJob(server.URL + "/v3/jobs/" + testJobGuid)
+		So(err, ShouldBeNil)
+		So(job.GUID, ShouldEqual, testJobGuid)
+		So(job.Operation, ShouldEqual, "app.delete")
+		So(job.State, ShouldEqual, JobProcessing)
+
+		job, err = client.GetJob(testJobGuid)
+		So(err, ShouldBeNil)
+		So(job.GUID, ShouldEqual, testJobGuid)
+	})
+}
+
+func TestWaitForJob(t *testing.T) {
+	Convey("Wait for job to complete", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		polls := 0
+		mux.HandleFunc("/v3/jobs/"+testJobGuid, func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			if polls < 3 {
+				w.Write([]byte(v3AppDeleteJobProcessingPayload))
+				return
+			}
+			w.Write([]byte(v3AppDeleteJobCompletePayload))
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		job, err := client.WaitForJob(server.URL+"/v3/jobs/"+testJobGuid, WaitForJobOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(polls, ShouldEqual, 3)
+		So(job.State, ShouldEqual, JobComplete)
+		So(len(job.Warnings), ShouldEqual, 1)
+		So(job.Warnings[0].Detail, ShouldEqual, "The app had bound service instances which were unbound")
+	})
+
+	Convey("Wait for failing job", t, func() {
+		setup(MockRoute{"GET", "/v3/jobs/" + testJobGuid, v3AppDeleteJobFailedPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		job, err := client.WaitForJob(testJobGuid, WaitForJobOptions{})
+		So(err, ShouldNotBeNil)
+		So(job.State, ShouldEqual, JobFailed)
+		jobErr, ok := err.(JobFailedError)
+		So(ok, ShouldBeTrue)
+		So(jobErr.JobGUID, ShouldEqual, testJobGuid)
+		So(jobErr.Operation, ShouldEqual, "app.delete")
+		So(len(jobErr.Errors), ShouldEqual, 2)
+		So(jobErr.Warnings, ShouldResemble, []string{"The service broker is slow to respond"})
+		So(err.Error(), ShouldContainSubstring, "my-db")
+		cfErr, ok := errors.Cause(err).(CloudFoundryError)
+		So(ok, ShouldBeTrue)
+		So(cfErr.ErrorCode, ShouldEqual, "CF-AsyncServiceBindingOperationInProgress")
+	})
+
+	Convey("Time out waiting for job", t, func() {
+		setup(MockRoute{"GET", "/v3/jobs/" + testJobGuid, v3AppDeleteJobProcessingPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		job, err := client.WaitForJob(testJobGuid, WaitForJobOptions{Timeout: 5 * time.Millisecond, Interval: time.Millisecond})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Timed out")
+		So(job.State, ShouldEqual, JobProcessing)
+	})
+
+	Convey("Fail getting job", t, func() {
+		setup(MockRoute{"GET", "/v3/jobs/" + testJobGuid, "", "", http.StatusNotFound, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.WaitForJob(testJobGuid, WaitForJobOptions{})
+		So(err, ShouldNotBeNil)
+	})
+}
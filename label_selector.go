@@ -0,0 +1,145 @@
+package cfclient
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxLabelKeyPrefixLength = 253
+	maxLabelNameLength      = 63
+)
+
+var (
+	labelNameRegexp   = regexp.MustCompile(`^[a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+	labelPrefixRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// LabelSelector builds the label_selector filter of the V3 list endpoints.
+// Requirements are ANDed, e.g.
+//
+//	NewLabelSelector().In("env", "prod", "stage").NotExists("deprecated").Equal("team", "payments")
+//
+// selects the resources labelled env=prod or env=stage and team=payments
+// which are not labelled deprecated. The first invalid key or value is
+// reported by Err and String.
+type LabelSelector struct {
+	requirements []string
+	err          error
+}
+
+// NewLabelSelector returns a selector without requirements, which selects
+// every resource.
+func NewLabelSelector() *LabelSelector {
+	return &LabelSelector{}
+}
+
+// Exists requires the label key to be set, whatever its value.
+func (s *LabelSelector) Exists(key string) *LabelSelector {
+	return s.add(key, nil, key)
+}
+
+// NotExists requires the label key not to be set.
+func (s *LabelSelector) NotExists(key string) *LabelSelector {
+	return s.add(key, nil, "!"+key)
+}
+
+// Equal requires the label key to be set to value.
+func (s *LabelSelector) Equal(key, value string) *LabelSelector {
+	return s.add(key, []string{value}, key+"="+value)
+}
+
+// NotEqual requires the label key not to be set to value, which includes
+// not being set at all.
+func (s *LabelSelector) NotEqual(key, value string) *LabelSelector {
+	return s.add(key, []string{value}, key+"!="+value)
+}
+
+// In requires the label key to be set to one of values.
+func (s *LabelSelector) In(key string, values ...string) *LabelSelector {
+	if len(values) == 0 && s.err == nil {
+		s.err = errors.Errorf("Label selector requirement %s in () has no values", key)
+	}
+	return s.add(key, values, key+" in ("+strings.Join(values, ",")+")")
+}
+
+// NotIn requires the label key not to be set to any of values.
+func (s *LabelSelector) NotIn(key string, values ...string) *LabelSelector {
+	if len(values) == 0 && s.err == nil {
+		s.err = errors.Errorf("Label selector requirement %s notin () has no values", key)
+	}
+	return s.add(key, values, key+" notin ("+strings.Join(values, ",")+")")
+}
+
+func (s *LabelSelector) add(key string, values []string, requirement string) *LabelSelector {
+	if s.err != nil {
+		return s
+	}
+	if err := validateLabelKey(key); err != nil {
+		s.err = err
+		return s
+	}
+	for _, value := range values {
+		if err := validateLabelValue(value); err != nil {
+			s.err = err
+			return s
+		}
+	}
+	s.requirements = append(s.requirements, requirement)
+	return s
+}
+
+// Err returns the first invalid requirement added to the selector.
+func (s *LabelSelector) Err() error {
+	return s.err
+}
+
+// String returns the selector as expected by the label_selector filter, or
+// an empty string if a requirement is invalid.
+func (s *LabelSelector) String() string {
+	if s.err != nil {
+		return ""
+	}
+	return strings.Join(s.requirements, ",")
+}
+
+// Query returns the selector as a label_selector query to pass to the list
+// calls accepting a query.
+func (s *LabelSelector) Query() (url.Values, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return url.Values{"label_selector": {s.String()}}, nil
+}
+
+// validateLabelKey checks key against the format of label and annotation
+// keys, an optional DNS subdomain prefix followed by a slash and a name.
+func validateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > maxLabelKeyPrefixLength || !labelPrefixRegexp.MatchString(prefix) {
+			return errors.Errorf("Invalid label key %q: the prefix must be a DNS subdomain of at most %d characters", key, maxLabelKeyPrefixLength)
+		}
+	}
+	if len(name) > maxLabelNameLength || !labelNameRegexp.MatchString(name) {
+		return errors.Errorf("Invalid label key %q: the name must be at most %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", key, maxLabelNameLength)
+	}
+	return nil
+}
+
+// validateLabelValue checks value against the format of label values, which
+// may be empty.
+func validateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxLabelNameLength || !labelNameRegexp.MatchString(value) {
+		return errors.Errorf("Invalid label value %q: it must be at most %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", value, maxLabelNameLength)
+	}
+	return nil
+}
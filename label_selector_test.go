@@ -0,0 +1,61 @@
+package cfclient
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLabelSelector(t *testing.T) {
+	Convey("Build label selector", t, func() {
+		selector := NewLabelSelector().
+			In("env", "prod", "stage").
+			NotExists("deprecated").
+			Equal("team", "payments").
+			NotEqual("tier", "free").
+			NotIn("region", "eu", "us").
+			Exists("example.com/owner")
+		So(selector.Err(), ShouldBeNil)
+		So(selector.String(), ShouldEqual, "env in (prod,stage),!deprecated,team=payments,tier!=free,region notin (eu,us),example.com/owner")
+
+		query, err := selector.Query()
+		So(err, ShouldBeNil)
+		So(query.Encode(), ShouldEqual, "label_selector=env+in+%28prod%2Cstage%29%2C%21deprecated%2Cteam%3Dpayments%2Ctier%21%3Dfree%2Cregion+notin+%28eu%2Cus%29%2Cexample.com%2Fowner")
+	})
+
+	Convey("Empty label selector", t, func() {
+		So(NewLabelSelector().String(), ShouldEqual, "")
+	})
+
+	Convey("Reject invalid label selectors", t, func() {
+		invalid := []*LabelSelector{
+			NewLabelSelector().Exists(""),
+			NewLabelSelector().Exists("-team"),
+			NewLabelSelector().Exists("Example.com/team"),
+			NewLabelSelector().Exists("example.com/"),
+			NewLabelSelector().Exists(strings.Repeat("a", 64)),
+			NewLabelSelector().Equal("team", "pay ments"),
+			NewLabelSelector().In("env", "prod", "stage,dev"),
+			NewLabelSelector().In("env"),
+			NewLabelSelector().NotIn("env"),
+		}
+		for _, selector := range invalid {
+			So(selector.Err(), ShouldNotBeNil)
+			So(selector.String(), ShouldEqual, "")
+			_, err := selector.Query()
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Keep the first label selector error", t, func() {
+		selector := NewLabelSelector().Equal("team", "pay ments").Exists("-team").Exists("valid")
+		So(selector.Err().Error(), ShouldContainSubstring, "pay ments")
+	})
+
+	Convey("Accept empty label values", t, func() {
+		selector := NewLabelSelector().Equal("team", "")
+		So(selector.Err(), ShouldBeNil)
+		So(selector.String(), ShouldEqual, "team=")
+	})
+}
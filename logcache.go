@@ -0,0 +1,398 @@
+package cfclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvelopeLog is the type of envelopes carrying log lines
+	EnvelopeLog = "LOG"
+	// EnvelopeCounter is the type of envelopes carrying counters
+	EnvelopeCounter = "COUNTER"
+	// EnvelopeGauge is the type of envelopes carrying gauges such as the
+	// container metrics
+	EnvelopeGauge = "GAUGE"
+	// EnvelopeTimer is the type of envelopes carrying timings such as the
+	// HTTP requests
+	EnvelopeTimer = "TIMER"
+	// EnvelopeEvent is the type of envelopes carrying events
+	EnvelopeEvent = "EVENT"
+)
+
+// defaultLogCacheLimit is the maximum number of envelopes Log Cache returns
+// per read.
+const defaultLogCacheLimit = 1000
+
+// LogCacheClient reads envelopes and queries metrics from Log Cache.
+type LogCacheClient struct {
+	// Endpoint is the URL of Log Cache, as advertised by the Cloud
+	// Controller.
+	Endpoint string
+	client   *Client
+}
+
+// Envelope is a loggregator v2 envelope. Exactly one of Log, Counter, Gauge,
+// Timer and Event is set.
+type Envelope struct {
+	Timestamp  time.Time
+	SourceId   string
+	InstanceId string
+	Tags       map[string]string
+	Log        *EnvelopeLogMessage
+	Counter    *EnvelopeCounterValue
+	Gauge      map[string]EnvelopeGaugeValue
+	Timer      *EnvelopeTimerValue
+	Event      *EnvelopeEventValue
+}
+
+// EnvelopeLogMessage is the payload of LOG envelopes.
+type EnvelopeLogMessage struct {
+	Payload string
+	// Type is either LogMessageOut or LogMessageErr.
+	Type string
+}
+
+// EnvelopeCounterValue is the payload of COUNTER envelopes.
+type EnvelopeCounterValue struct {
+	Name  string
+	Delta uint64
+	Total uint64
+}
+
+// EnvelopeGaugeValue is a metric of a GAUGE envelope.
+type EnvelopeGaugeValue struct {
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+}
+
+// EnvelopeTimerValue is the payload of TIMER envelopes.
+type EnvelopeTimerValue struct {
+	Name  string
+	Start time.Time
+	Stop  time.Time
+}
+
+// EnvelopeEventValue is the payload of EVENT envelopes.
+type EnvelopeEventValue struct {
+	Title string
+	Body  string
+}
+
+// envelopeJSON is an envelope as encoded by the Log Cache gateway, which
+// encodes 64 bits integers as strings and bytes as base64.
+type envelopeJSON struct {
+	Timestamp  int64             `json:"timestamp,string"`
+	SourceId   string            `json:"source_id"`
+	InstanceId string            `json:"instance_id"`
+	Tags       map[string]string `json:"tags"`
+	Log        *struct {
+		Payload []byte `json:"payload"`
+		Type    string `json:"type"`
+	} `json:"log"`
+	Counter *struct {
+		Name  string `json:"name"`
+		Delta uint64 `json:"delta,string"`
+		Total uint64 `json:"total,string"`
+	} `json:"counter"`
+	Gauge *struct {
+		Metrics map[string]EnvelopeGaugeValue `json:"metrics"`
+	} `json:"gauge"`
+	Timer *struct {
+		Name  string `json:"name"`
+		Start int64  `json:"start,string"`
+		Stop  int64  `json:"stop,string"`
+	} `json:"timer"`
+	Event *EnvelopeEventValue `json:"event"`
+}
+
+func (e envelopeJSON) envelope() Envelope {
+	envelope := Envelope{
+		Timestamp:  time.Unix(0, e.Timestamp),
+		SourceId:   e.SourceId,
+		InstanceId: e.InstanceId,
+		Tags:       e.Tags,
+		Event:      e.Event,
+	}
+	if e.Log != nil {
+		logType := e.Log.Type
+		if logType == "" {
+			logType = LogMessageOut
+		}
+		envelope.Log = &EnvelopeLogMessage{Payload: string(e.Log.Payload), Type: logType}
+	}
+	if e.Counter != nil {
+		envelope.Counter = &EnvelopeCounterValue{Name: e.Counter.Name, Delta: e.Counter.Delta, Total: e.Counter.Total}
+	}
+	if e.Gauge != nil {
+		envelope.Gauge = e.Gauge.Metrics
+	}
+	if e.Timer != nil {
+		envelope.Timer = &EnvelopeTimerValue{
+			Name:  e.Timer.Name,
+			Start: time.Unix(0, e.Timer.Start),
+			Stop:  time.Unix(0, e.Timer.Stop),
+		}
+	}
+	return envelope
+}
+
+// LogCacheReadOptions filters the envelopes read from Log Cache.
+type LogCacheReadOptions struct {
+	// StartTime is the time of the oldest envelope to read, inclusive.
+	StartTime time.Time
+	// EndTime is the time of the most recent envelope to read, exclusive.
+	// It defaults to now.
+	EndTime time.Time
+	// EnvelopeTypes restricts the read to the given types, see the
+	// Envelope* constants.
+	EnvelopeTypes []string
+	// Limit is the maximum number of envelopes to return, Log Cache
+	// returns at most 1000 envelopes per read.
+	Limit int
+	// Descending returns the most recent envelopes first.
+	Descending bool
+}
+
+// NewLogCacheClient returns a client for the Log Cache advertised in the
+// links of the Cloud Controller root.
+func (c *Client) NewLogCacheClient() (*LogCacheClient, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error requesting root links")
+	}
+	var root struct {
+		Links map[string]*struct {
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	if err := decodeBody(resp, &root); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshalling root links")
+	}
+	link := root.Links["log_cache"]
+	if link == nil || link.Href == "" {
+		return nil, errors.New("No Log Cache advertised by the Cloud Controller")
+	}
+	return &LogCacheClient{Endpoint: strings.TrimSuffix(link.Href, "/"), client: c}, nil
+}
+
+// Read returns the envelopes of the given source, an app guid or a platform
+// component name, matching opts. Log Cache returns a single page of at most
+// 1000 envelopes, use ReadAll to read a whole time range.
+func (l *LogCacheClient) Read(sourceId string, opts LogCacheReadOptions) ([]Envelope, error) {
+	query := url.Values{}
+	if !opts.StartTime.IsZero() {
+		query.Set("start_time", strconv.FormatInt(opts.StartTime.UnixNano(), 10))
+	}
+	if !opts.EndTime.IsZero() {
+		query.Set("end_time", strconv.FormatInt(opts.EndTime.UnixNano(), 10))
+	}
+	for _, envelopeType := range opts.EnvelopeTypes {
+		query.Add("envelope_types", envelopeType)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Descending {
+		query.Set("descending", "true")
+	}
+
+	var result struct {
+		Envelopes struct {
+			Batch []envelopeJSON `json:"batch"`
+		} `json:"envelopes"`
+	}
+	err := l.get("/api/v1/read/"+sourceId, query, &result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error reading envelopes of %s", sourceId)
+	}
+	envelopes := make([]Envelope, 0, len(result.Envelopes.Batch))
+	for _, e := range result.Envelopes.Batch {
+		envelopes = append(envelopes, e.envelope())
+	}
+	return envelopes, nil
+}
+
+// ReadAll returns all the envelopes of the given source matching opts,
+// oldest first. It pages through the time range by timestamp, reading Limit
+// envelopes at a time, 1000 by default. Descending is ignored.
+func (l *LogCacheClient) ReadAll(sourceId string, opts LogCacheReadOptions) ([]Envelope, error) {
+	if opts.Limit <= 0 || opts.Limit > defaultLogCacheLimit {
+		opts.Limit = defaultLogCacheLimit
+	}
+	opts.Descending = false
+	if opts.EndTime.IsZero() {
+		opts.EndTime = time.Now()
+	}
+	var envelopes []Envelope
+	for {
+		page, err := l.Read(sourceId, opts)
+		if err != nil {
+			return nil, err
+		}
+		envelopes = append(envelopes, page...)
+		if len(page) < opts.Limit {
+			return envelopes, nil
+		}
+		// envelopes are returned oldest first, resume right after the last one
+		opts.StartTime = page[len(page)-1].Timestamp.Add(time.Nanosecond)
+	}
+}
+
+// PromQLResult is the result of a PromQL query. Instant queries return a
+// vector, with a single point per series, or a scalar, with a single series
+// without labels. Range queries return a matrix.
+type PromQLResult struct {
+	// ResultType is one of vector, matrix or scalar.
+	ResultType string
+	Series     []PromQLSeries
+}
+
+// PromQLSeries is a labelled series of points.
+type PromQLSeries struct {
+	Metric map[string]string
+	Points []PromQLPoint
+}
+
+// PromQLPoint is the value of a series at a point in time.
+type PromQLPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// UnmarshalJSON decodes the [<unix time>, "<value>"] pairs of the Prometheus
+// API.
+func (p *PromQLPoint) UnmarshalJSON(b []byte) error {
+	var pair []interface{}
+	if err := json.Unmarshal(b, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return errors.Errorf("Invalid PromQL point %s", b)
+	}
+	t, ok := pair[0].(float64)
+	s, ok2 := pair[1].(string)
+	if !ok || !ok2 {
+		return errors.Errorf("Invalid PromQL point %s", b)
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid PromQL point %s", b)
+	}
+	// Prometheus times have a millisecond precision
+	p.Time = time.Unix(0, int64(t*1e3+0.5)*int64(time.Millisecond))
+	p.Value = value
+	return nil
+}
+
+type promQLResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// PromQL evaluates query at time t, now if t is zero.
+func (l *LogCacheClient) PromQL(query string, t time.Time) (PromQLResult, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	if !t.IsZero() {
+		params.Set("time", promQLTime(t))
+	}
+	return l.promQL("/api/v1/query", params)
+}
+
+// PromQLRange evaluates query over the given time range, every step.
+func (l *LogCacheClient) PromQLRange(query string, start, end time.Time, step time.Duration) (PromQLResult, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", promQLTime(start))
+	params.Set("end", promQLTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	return l.promQL("/api/v1/query_range", params)
+}
+
+func promQLTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
+
+func (l *LogCacheClient) promQL(path string, params url.Values) (PromQLResult, error) {
+	var resp promQLResponse
+	err := l.get(path, params, &resp)
+	if err == nil && resp.Status != "success" {
+		err = errors.Errorf("%s: %s", resp.ErrorType, resp.Error)
+	}
+	if err != nil {
+		return PromQLResult{}, errors.Wrapf(err, "Error evaluating PromQL query %q", params.Get("query"))
+	}
+
+	result := PromQLResult{ResultType: resp.Data.ResultType}
+	switch resp.Data.ResultType {
+	case "scalar":
+		var point PromQLPoint
+		err = json.Unmarshal(resp.Data.Result, &point)
+		result.Series = []PromQLSeries{{Points: []PromQLPoint{point}}}
+	case "vector":
+		var samples []struct {
+			Metric map[string]string `json:"metric"`
+			Value  PromQLPoint       `json:"value"`
+		}
+		err = json.Unmarshal(resp.Data.Result, &samples)
+		for _, sample := range samples {
+			result.Series = append(result.Series, PromQLSeries{Metric: sample.Metric, Points: []PromQLPoint{sample.Value}})
+		}
+	case "matrix":
+		var series []struct {
+			Metric map[string]string `json:"metric"`
+			Values []PromQLPoint     `json:"values"`
+		}
+		err = json.Unmarshal(resp.Data.Result, &series)
+		for _, s := range series {
+			result.Series = append(result.Series, PromQLSeries{Metric: s.Metric, Points: s.Values})
+		}
+	default:
+		err = errors.Errorf("Unsupported result type %s", resp.Data.ResultType)
+	}
+	if err != nil {
+		return PromQLResult{}, errors.Wrapf(err, "Error unmarshalling PromQL result of %q", params.Get("query"))
+	}
+	return result, nil
+}
+
+// get sends an authenticated GET request to Log Cache and decodes the JSON
+// response into out. Log Cache errors are not Cloud Controller errors, they
+// are returned as is.
+func (l *LogCacheClient) get(path string, query url.Values, out interface{}) error {
+	requestURL := l.Endpoint + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", l.client.Config.UserAgent)
+	resp, err := l.client.Config.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Log Cache response code: %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
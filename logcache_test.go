@@ -0,0 +1,138 @@
+package cfclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// logCacheStandIn serves the reads and PromQL queries of the Log Cache tests.
+func logCacheStandIn(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/read/9902530c-c634-4864-a189-71d763cb12e2", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer foobar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("end_time") != "1550000010000000000" || query.Get("limit") != "2" || len(query["envelope_types"]) != 3 {
+			t.Errorf("Unexpected Log Cache query %s", r.URL.RawQuery)
+		}
+		switch query.Get("start_time") {
+		case "1549999990000000000":
+			w.Write([]byte(logCacheFirstPagePayload))
+		case "1550000001000000001":
+			w.Write([]byte(logCacheSecondPagePayload))
+		default:
+			t.Errorf("Unexpected start time %s", query.Get("start_time"))
+		}
+	})
+	mux.HandleFunc("/api/v1/query", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "cpu{source_id=\"9902530c-c634-4864-a189-71d763cb12e2\"}":
+			if r.URL.Query().Get("time") != "1550000000.123" {
+				t.Errorf("Unexpected PromQL time %s", r.URL.Query().Get("time"))
+			}
+			w.Write([]byte(promQLVectorPayload))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(promQLErrorPayload))
+		}
+	})
+	mux.HandleFunc("/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("start") != "1550000000.000" || query.Get("end") != "1550000060.000" || query.Get("step") != "60" {
+			t.Errorf("Unexpected PromQL range %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(promQLMatrixPayload))
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestLogCacheClient(t *testing.T) (*LogCacheClient, func()) {
+	logCache := logCacheStandIn(t)
+	setup(MockRoute{"GET", "/", fmt.Sprintf(logCacheRootPayload, logCache.URL), "", 200, "", nil}, t)
+	c := &Config{
+		ApiAddress: server.URL,
+		Token:      "foobar",
+	}
+	client, err := NewClient(c)
+	So(err, ShouldBeNil)
+	logCacheClient, err := client.NewLogCacheClient()
+	So(err, ShouldBeNil)
+	So(logCacheClient.Endpoint, ShouldEqual, logCache.URL)
+	return logCacheClient, func() {
+		teardown()
+		logCache.Close()
+	}
+}
+
+func TestLogCacheRead(t *testing.T) {
+	Convey("Read envelopes", t, func() {
+		client, teardown := newTestLogCacheClient(t)
+		defer teardown()
+
+		opts := LogCacheReadOptions{
+			StartTime:     time.Unix(1549999990, 0),
+			EndTime:       time.Unix(1550000010, 0),
+			EnvelopeTypes: []string{EnvelopeLog, EnvelopeGauge, EnvelopeCounter},
+			Limit:         2,
+		}
+		envelopes, err := client.Read("9902530c-c634-4864-a189-71d763cb12e2", opts)
+		So(err, ShouldBeNil)
+		So(len(envelopes), ShouldEqual, 2)
+		So(envelopes[0].Timestamp, ShouldResemble, time.Unix(1550000000, 0))
+		So(envelopes[0].InstanceId, ShouldEqual, "0")
+		So(envelopes[0].Tags["source_type"], ShouldEqual, "APP/PROC/WEB")
+		So(envelopes[0].Log, ShouldResemble, &EnvelopeLogMessage{Payload: "hello world", Type: LogMessageOut})
+		So(envelopes[1].Gauge["memory"], ShouldResemble, EnvelopeGaugeValue{Unit: "bytes", Value: 518123520})
+
+		envelopes, err = client.ReadAll("9902530c-c634-4864-a189-71d763cb12e2", opts)
+		So(err, ShouldBeNil)
+		So(len(envelopes), ShouldEqual, 3)
+		So(envelopes[2].Counter, ShouldResemble, &EnvelopeCounterValue{Name: "requests", Delta: 2, Total: 42})
+	})
+
+	Convey("Log Cache not advertised", t, func() {
+		setup(MockRoute{"GET", "/", `{"links": {}}`, "", 200, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+		_, err = client.NewLogCacheClient()
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestLogCachePromQL(t *testing.T) {
+	Convey("PromQL queries", t, func() {
+		client, teardown := newTestLogCacheClient(t)
+		defer teardown()
+
+		result, err := client.PromQL(`cpu{source_id="9902530c-c634-4864-a189-71d763cb12e2"}`, time.Unix(1550000000, 123000000))
+		So(err, ShouldBeNil)
+		So(result.ResultType, ShouldEqual, "vector")
+		So(len(result.Series), ShouldEqual, 2)
+		So(result.Series[1].Metric["instance_id"], ShouldEqual, "1")
+		So(result.Series[1].Points, ShouldResemble, []PromQLPoint{{Time: time.Unix(1550000000, 123000000), Value: 0.5}})
+
+		result, err = client.PromQLRange("memory", time.Unix(1550000000, 0), time.Unix(1550000060, 0), time.Minute)
+		So(err, ShouldBeNil)
+		So(result.ResultType, ShouldEqual, "matrix")
+		So(result.Series[0].Points, ShouldResemble, []PromQLPoint{
+			{Time: time.Unix(1550000000, 0), Value: 518123520},
+			{Time: time.Unix(1550000060, 0), Value: 530731008},
+		})
+
+		_, err = client.PromQL("cpu{", time.Time{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "unexpected end of input")
+	})
+}
@@ -0,0 +1,148 @@
+package cfclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/cloudfoundry/noaa"
+	"github.com/cloudfoundry/noaa/consumer"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	// LogMessageOut is the type of messages written to stdout
+	LogMessageOut = "OUT"
+	// LogMessageErr is the type of messages written to stderr
+	LogMessageErr = "ERR"
+)
+
+// LogMessage is a log line emitted by an app or by a platform component on
+// behalf of the app.
+type LogMessage struct {
+	AppGuid string
+	Message string
+	// MessageType is either LogMessageOut or LogMessageErr.
+	MessageType string
+	Timestamp   time.Time
+	// SourceType is the emitter of the message, e.g. APP/PROC/WEB, RTR or
+	// STG.
+	SourceType string
+	// SourceInstance is the index of the app instance or of the platform
+	// component which emitted the message.
+	SourceInstance string
+}
+
+func newLogMessage(m *events.LogMessage) LogMessage {
+	return LogMessage{
+		AppGuid:        m.GetAppId(),
+		Message:        string(m.GetMessage()),
+		MessageType:    m.GetMessageType().String(),
+		Timestamp:      time.Unix(0, m.GetTimestamp()),
+		SourceType:     m.GetSourceType(),
+		SourceInstance: m.GetSourceInstance(),
+	}
+}
+
+// logTokenRefresher lets the Doppler consumer fetch a new token when the
+// current one expired.
+type logTokenRefresher struct {
+	client *Client
+}
+
+func (r logTokenRefresher) RefreshAuthToken() (string, error) {
+	return r.client.GetToken()
+}
+
+func (c *Client) newLogConsumer() (*consumer.Consumer, error) {
+	if c.Endpoint.DopplerEndpoint == "" {
+		return nil, errors.New("No Doppler endpoint advertised by the Cloud Controller")
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.Config.SkipSslValidation}
+	if tp, ok := c.Config.HttpClient.Transport.(*http.Transport); ok && tp.TLSClientConfig != nil {
+		tlsConfig = tp.TLSClientConfig
+	}
+	cons := consumer.New(c.Endpoint.DopplerEndpoint, tlsConfig, nil)
+	cons.RefreshTokenFrom(logTokenRefresher{c})
+	return cons, nil
+}
+
+// RecentLogs returns the logs of the app identified by appGuid which Doppler
+// still buffers, oldest first.
+func (c *Client) RecentLogs(appGuid string) ([]LogMessage, error) {
+	cons, err := c.newLogConsumer()
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.GetToken()
+	if err != nil {
+		return nil, err
+	}
+	messages, err := cons.RecentLogs(appGuid, token)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting recent logs of app %s", appGuid)
+	}
+	logs := make([]LogMessage, 0, len(messages))
+	for _, m := range noaa.SortRecent(messages) {
+		logs = append(logs, newLogMessage(m))
+	}
+	return logs, nil
+}
+
+// TailLogs streams the logs of the app identified by appGuid as they are
+// emitted, until ctx is done. The connection to Doppler is reestablished when
+// it drops; the errors which caused the reconnections are sent on the error
+// channel. Both channels are closed once streaming stopped and must be
+// drained until then.
+func (c *Client) TailLogs(ctx context.Context, appGuid string) (<-chan LogMessage, <-chan error) {
+	logs := make(chan LogMessage)
+	errs := make(chan error, 1)
+	cons, err := c.newLogConsumer()
+	if err == nil {
+		var token string
+		token, err = c.GetToken()
+		if err == nil {
+			go c.tailLogs(ctx, cons, appGuid, token, logs, errs)
+			return logs, errs
+		}
+	}
+	errs <- err
+	close(errs)
+	close(logs)
+	return logs, errs
+}
+
+func (c *Client) tailLogs(ctx context.Context, cons *consumer.Consumer, appGuid, token string, logs chan<- LogMessage, errs chan<- error) {
+	defer close(errs)
+	defer close(logs)
+	messages, consErrs := cons.TailingLogs(appGuid, token)
+	done := ctx.Done()
+	// the consumer channels are drained until closed so that it can stop
+	for messages != nil || consErrs != nil {
+		select {
+		case <-done:
+			done = nil
+			cons.Close()
+		case m, ok := <-messages:
+			if !ok {
+				messages = nil
+			} else if done != nil {
+				select {
+				case logs <- newLogMessage(m):
+				case <-ctx.Done():
+				}
+			}
+		case err, ok := <-consErrs:
+			if !ok {
+				consErrs = nil
+			} else if err != nil && done != nil {
+				select {
+				case errs <- errors.Wrapf(err, "Error tailing logs of app %s", appGuid):
+				case <-ctx.Done():
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package cfclient
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+)
+
+func testLogEnvelope(message string, timestamp int64) []byte {
+	env := &events.Envelope{
+		Origin:    proto.String("rep"),
+		EventType: events.Envelope_LogMessage.Enum(),
+		LogMessage: &events.LogMessage{
+			Message:        []byte(message),
+			MessageType:    events.LogMessage_OUT.Enum(),
+			Timestamp:      proto.Int64(timestamp),
+			AppId:          proto.String("9902530c-c634-4864-a189-71d763cb12e2"),
+			SourceType:     proto.String("APP/PROC/WEB"),
+			SourceInstance: proto.String("0"),
+		},
+	}
+	data, err := proto.Marshal(env)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// dopplerStandIn serves recent logs and drops the stream connection after
+// every message so that clients have to reconnect.
+func dopplerStandIn(t *testing.T, messages ...string) *httptest.Server {
+	var connections int32
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps/9902530c-c634-4864-a189-71d763cb12e2/recentlogs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer foobar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		buf := bytes.NewBuffer(nil)
+		mw := multipart.NewWriter(buf)
+		// recent logs are not sorted
+		for i := len(messages) - 1; i >= 0; i-- {
+			part, _ := mw.CreatePart(nil)
+			part.Write(testLogEnvelope(messages[i], int64(i+1)*int64(time.Second)))
+		}
+		mw.Close()
+		w.Header().Set("Content-Type", "multipart/x-protobuf; boundary="+mw.Boundary())
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/apps/9902530c-c634-4864-a189-71d763cb12e2/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer foobar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Error upgrading stream: %s", err)
+			return
+		}
+		defer ws.Close()
+		i := int(atomic.AddInt32(&connections, 1)) - 1
+		if i >= len(messages) {
+			// wait for the client to go away
+			ws.ReadMessage()
+			return
+		}
+		ws.WriteMessage(websocket.BinaryMessage, testLogEnvelope(messages[i], int64(i+1)*int64(time.Second)))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRecentLogs(t *testing.T) {
+	Convey("Recent logs", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		doppler := dopplerStandIn(t, "first", "second")
+		defer doppler.Close()
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+		client.Endpoint.DopplerEndpoint = strings.Replace(doppler.URL, "http", "ws", 1)
+
+		logs, err := client.RecentLogs("9902530c-c634-4864-a189-71d763cb12e2")
+		So(err, ShouldBeNil)
+		So(len(logs), ShouldEqual, 2)
+		So(logs[0], ShouldResemble, LogMessage{
+			AppGuid:        "9902530c-c634-4864-a189-71d763cb12e2",
+			Message:        "first",
+			MessageType:    LogMessageOut,
+			Timestamp:      time.Unix(1, 0),
+			SourceType:     "APP/PROC/WEB",
+			SourceInstance: "0",
+		})
+		So(logs[1].Message, ShouldEqual, "second")
+	})
+
+	Convey("Recent logs without Doppler", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.RecentLogs("9902530c-c634-4864-a189-71d763cb12e2")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestTailLogs(t *testing.T) {
+	Convey("Tail logs", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		doppler := dopplerStandIn(t, "first", "second")
+		defer doppler.Close()
+
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+		client.Endpoint.DopplerEndpoint = strings.Replace(doppler.URL, "http", "ws", 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		logs, errs := client.TailLogs(ctx, "9902530c-c634-4864-a189-71d763cb12e2")
+
+		var received []string
+		var reconnections int
+		for len(received) < 2 {
+			select {
+			case m := <-logs:
+				received = append(received, m.Message)
+			case err := <-errs:
+				So(err, ShouldNotBeNil)
+				reconnections++
+			case <-time.After(5 * time.Second):
+				panic(fmt.Sprintf("Timed out tailing logs, received %v", received))
+			}
+		}
+		So(received, ShouldResemble, []string{"first", "second"})
+		So(reconnections, ShouldBeGreaterThan, 0)
+
+		cancel()
+		for range logs {
+		}
+		for range errs {
+		}
+	})
+}
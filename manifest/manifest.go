@@ -0,0 +1,456 @@
+// Package manifest parses, validates and writes Cloud Foundry application
+// manifests.
+//
+// Only the inheritance-free version 1 schema is supported: the deprecated
+// inherit, host, hosts, domain and domains attributes are not. Variables
+// written as ((name)) are substituted from vars files or from explicit values
+// before the manifest is decoded.
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest is a Cloud Foundry application manifest.
+type Manifest struct {
+	Version      int           `yaml:"version,omitempty"`
+	Applications []Application `yaml:"applications"`
+}
+
+// Application is an application of a manifest. Memory and disk quotas are
+// sizes with a unit, e.g. "512M" or "1G", see ParseMegabytes.
+type Application struct {
+	Name                    string            `yaml:"name"`
+	Path                    string            `yaml:"path,omitempty"`
+	Buildpack               string            `yaml:"buildpack,omitempty"`
+	Buildpacks              []string          `yaml:"buildpacks,omitempty"`
+	Command                 string            `yaml:"command,omitempty"`
+	DiskQuota               string            `yaml:"disk_quota,omitempty"`
+	Docker                  *Docker           `yaml:"docker,omitempty"`
+	Env                     map[string]string `yaml:"env,omitempty"`
+	HealthCheckType         string            `yaml:"health-check-type,omitempty"`
+	HealthCheckHttpEndpoint string            `yaml:"health-check-http-endpoint,omitempty"`
+	Timeout                 int               `yaml:"timeout,omitempty"`
+	Instances               *int              `yaml:"instances,omitempty"`
+	Memory                  string            `yaml:"memory,omitempty"`
+	NoRoute                 bool              `yaml:"no-route,omitempty"`
+	RandomRoute             bool              `yaml:"random-route,omitempty"`
+	Routes                  []Route           `yaml:"routes,omitempty"`
+	Services                []Service         `yaml:"services,omitempty"`
+	Stack                   string            `yaml:"stack,omitempty"`
+	Processes               []Process         `yaml:"processes,omitempty"`
+	Sidecars                []Sidecar         `yaml:"sidecars,omitempty"`
+}
+
+// Docker is the image of a docker application.
+type Docker struct {
+	Image    string `yaml:"image"`
+	Username string `yaml:"username,omitempty"`
+}
+
+// Route is a route of an application, e.g. "my-app.example.com/path".
+type Route struct {
+	Route    string `yaml:"route"`
+	Protocol string `yaml:"protocol,omitempty"`
+}
+
+// Service is a service instance bound to an application. It is written as
+// the plain name of the instance unless binding options are set.
+type Service struct {
+	Name        string                 `yaml:"name"`
+	BindingName string                 `yaml:"binding_name,omitempty"`
+	Parameters  map[string]interface{} `yaml:"parameters,omitempty"`
+}
+
+// Process is a process type of an application.
+type Process struct {
+	Type                         string `yaml:"type"`
+	Command                      string `yaml:"command,omitempty"`
+	DiskQuota                    string `yaml:"disk_quota,omitempty"`
+	HealthCheckType              string `yaml:"health-check-type,omitempty"`
+	HealthCheckHttpEndpoint      string `yaml:"health-check-http-endpoint,omitempty"`
+	HealthCheckInvocationTimeout int    `yaml:"health-check-invocation-timeout,omitempty"`
+	Instances                    *int   `yaml:"instances,omitempty"`
+	Memory                       string `yaml:"memory,omitempty"`
+	Timeout                      int    `yaml:"timeout,omitempty"`
+}
+
+// Sidecar is an additional process running next to the processes of the
+// given types.
+type Sidecar struct {
+	Name         string   `yaml:"name"`
+	ProcessTypes []string `yaml:"process_types"`
+	Command      string   `yaml:"command"`
+	Memory       string   `yaml:"memory,omitempty"`
+}
+
+func (s *Service) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*s = Service{Name: name}
+		return nil
+	}
+	type plain Service
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Parameters != nil {
+		s.Parameters = jsonCompatible(s.Parameters).(map[string]interface{})
+	}
+	return nil
+}
+
+func (s Service) MarshalYAML() (interface{}, error) {
+	if s.BindingName == "" && len(s.Parameters) == 0 {
+		return s.Name, nil
+	}
+	type plain Service
+	return plain(s), nil
+}
+
+// jsonCompatible converts the maps decoded by yaml, which have interface{}
+// keys, into maps with string keys so that the value can be encoded to JSON.
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonCompatible(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	}
+	return value
+}
+
+// Application returns the application of the manifest with the given name,
+// or nil if there is none.
+func (m *Manifest) Application(name string) *Application {
+	for i := range m.Applications {
+		if m.Applications[i].Name == name {
+			return &m.Applications[i]
+		}
+	}
+	return nil
+}
+
+// Marshal returns the YAML representation of the manifest.
+func (m *Manifest) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error marshalling manifest")
+	}
+	return data, nil
+}
+
+// Parse substitutes vars into the manifest data and decodes it. The
+// manifest is not validated, see Validate.
+func Parse(data []byte, vars map[string]interface{}) (*Manifest, error) {
+	data, err := Interpolate(data, vars)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "Error parsing manifest")
+	}
+	if _, ok := raw["inherit"]; ok {
+		return nil, errors.New("Manifest inheritance is not supported")
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrap(err, "Error parsing manifest")
+	}
+	return &m, nil
+}
+
+// ParseFile reads the manifest at path and substitutes its variables from
+// the given vars files, in order, and then from vars, which take precedence.
+// Relative application paths are resolved against the manifest directory.
+func ParseFile(path string, vars map[string]interface{}, varsFiles ...string) (*Manifest, error) {
+	allVars := map[string]interface{}{}
+	for _, varsFile := range varsFiles {
+		fileVars, err := ReadVarsFile(varsFile)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fileVars {
+			allVars[k] = v
+		}
+	}
+	for k, v := range vars {
+		allVars[k] = v
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error reading manifest %s", path)
+	}
+	m, err := Parse(data, allVars)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error parsing manifest %s", path)
+	}
+	dir := filepath.Dir(path)
+	for i, app := range m.Applications {
+		if app.Path != "" && !filepath.IsAbs(app.Path) {
+			m.Applications[i].Path = filepath.Join(dir, app.Path)
+		}
+	}
+	return m, nil
+}
+
+// ReadVarsFile reads a YAML file of variables.
+func ReadVarsFile(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error reading vars file %s", path)
+	}
+	vars := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &vars); err != nil {
+		return nil, errors.Wrapf(err, "Error parsing vars file %s", path)
+	}
+	return vars, nil
+}
+
+var variablePattern = regexp.MustCompile(`\(\(([-/.\w]+)\)\)`)
+
+// Interpolate substitutes the ((name)) variables of the YAML document data
+// with their values. A variable which makes up a whole value is replaced by
+// the value with its type, otherwise the value is formatted into the string.
+// An error naming every undefined variable is returned.
+func Interpolate(data []byte, vars map[string]interface{}) ([]byte, error) {
+	if !variablePattern.Match(data) {
+		return data, nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "Error parsing manifest")
+	}
+	missing := map[string]bool{}
+	doc = interpolate(doc, vars, missing)
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, errors.Errorf("Expected to find variables: %s", strings.Join(names, ", "))
+	}
+	return yaml.Marshal(doc)
+}
+
+func interpolate(node interface{}, vars map[string]interface{}, missing map[string]bool) interface{} {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range n {
+			n[key] = interpolate(value, vars, missing)
+		}
+	case []interface{}:
+		for i, value := range n {
+			n[i] = interpolate(value, vars, missing)
+		}
+	case string:
+		if match := variablePattern.FindStringSubmatch(n); match != nil && match[0] == n {
+			if value, ok := vars[match[1]]; ok {
+				return value
+			}
+			missing[match[1]] = true
+			return n
+		}
+		return variablePattern.ReplaceAllStringFunc(n, func(variable string) string {
+			name := variable[2 : len(variable)-2]
+			value, ok := vars[name]
+			if !ok {
+				missing[name] = true
+				return variable
+			}
+			return fmt.Sprint(value)
+		})
+	}
+	return node
+}
+
+var sizePattern = regexp.MustCompile(`^(\d+)\s*([KMGT])B?$`)
+
+// ParseMegabytes converts a size with a unit, e.g. "512M", "1G" or "1024KB",
+// to megabytes. Sizes in kilobytes are rounded up to the next megabyte.
+func ParseMegabytes(size string) (int, error) {
+	match := sizePattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(size)))
+	if match == nil {
+		return 0, errors.Errorf("Invalid size %q, expected a number with a unit (K, M, G or T)", size)
+	}
+	value, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid size %q", size)
+	}
+	switch match[2] {
+	case "K":
+		return (value + 1023) / 1024, nil
+	case "G":
+		return value * 1024, nil
+	case "T":
+		return value * 1024 * 1024, nil
+	}
+	return value, nil
+}
+
+// ValidationError lists the problems found in a manifest.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e ValidationError) Error() string {
+	return "manifest: " + strings.Join(e.Errors, "; ")
+}
+
+// Validate checks the manifest for missing or conflicting attributes and
+// returns a ValidationError listing all of them.
+func (m *Manifest) Validate() error {
+	v := &validator{}
+	if len(m.Applications) == 0 {
+		v.add("no applications")
+	}
+	names := map[string]bool{}
+	for i, app := range m.Applications {
+		if app.Name == "" {
+			v.add("application %d: name is required", i)
+		} else if names[app.Name] {
+			v.add("application %s: duplicate name", app.Name)
+		}
+		names[app.Name] = true
+		v.application(app)
+	}
+	if len(v.errors) > 0 {
+		return ValidationError{Errors: v.errors}
+	}
+	return nil
+}
+
+type validator struct {
+	errors []string
+}
+
+func (v *validator) add(format string, args ...interface{}) {
+	v.errors = append(v.errors, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) size(prefix, attribute, size string) {
+	if size == "" {
+		return
+	}
+	if _, err := ParseMegabytes(size); err != nil {
+		v.add("%s: invalid %s %q", prefix, attribute, size)
+	}
+}
+
+func (v *validator) healthCheck(prefix, checkType, endpoint string) {
+	switch checkType {
+	case "", "port", "process", "http", "none":
+	default:
+		v.add("%s: invalid health-check-type %q", prefix, checkType)
+	}
+	if endpoint != "" && checkType != "http" {
+		v.add("%s: health-check-http-endpoint requires the http health-check-type", prefix)
+	}
+}
+
+func (v *validator) application(app Application) {
+	prefix := "application " + app.Name
+	if app.Buildpack != "" && len(app.Buildpacks) > 0 {
+		v.add("%s: buildpack and buildpacks cannot both be set", prefix)
+	}
+	if app.Docker != nil {
+		if app.Docker.Image == "" {
+			v.add("%s: docker image is required", prefix)
+		}
+		if app.Buildpack != "" || len(app.Buildpacks) > 0 {
+			v.add("%s: docker and buildpacks cannot both be set", prefix)
+		}
+		if app.Path != "" {
+			v.add("%s: docker and path cannot both be set", prefix)
+		}
+	}
+	v.size(prefix, "memory", app.Memory)
+	v.size(prefix, "disk_quota", app.DiskQuota)
+	v.healthCheck(prefix, app.HealthCheckType, app.HealthCheckHttpEndpoint)
+	if app.Instances != nil && *app.Instances < 0 {
+		v.add("%s: instances cannot be negative", prefix)
+	}
+	if app.Timeout < 0 {
+		v.add("%s: timeout cannot be negative", prefix)
+	}
+	if app.NoRoute && (len(app.Routes) > 0 || app.RandomRoute) {
+		v.add("%s: no-route cannot be combined with routes or random-route", prefix)
+	}
+	if app.RandomRoute && len(app.Routes) > 0 {
+		v.add("%s: random-route and routes cannot both be set", prefix)
+	}
+	for _, route := range app.Routes {
+		if route.Route == "" {
+			v.add("%s: route is required", prefix)
+		}
+		switch route.Protocol {
+		case "", "http1", "http2", "tcp":
+		default:
+			v.add("%s: invalid protocol %q for route %s", prefix, route.Protocol, route.Route)
+		}
+	}
+	for _, service := range app.Services {
+		if service.Name == "" {
+			v.add("%s: service name is required", prefix)
+		}
+	}
+
+	types := map[string]bool{}
+	for _, process := range app.Processes {
+		processPrefix := prefix + " process " + process.Type
+		if process.Type == "" {
+			v.add("%s: process type is required", prefix)
+		} else if types[process.Type] {
+			v.add("%s: duplicate process type", processPrefix)
+		}
+		types[process.Type] = true
+		v.size(processPrefix, "memory", process.Memory)
+		v.size(processPrefix, "disk_quota", process.DiskQuota)
+		v.healthCheck(processPrefix, process.HealthCheckType, process.HealthCheckHttpEndpoint)
+		if process.Instances != nil && *process.Instances < 0 {
+			v.add("%s: instances cannot be negative", processPrefix)
+		}
+	}
+
+	sidecars := map[string]bool{}
+	for _, sidecar := range app.Sidecars {
+		sidecarPrefix := prefix + " sidecar " + sidecar.Name
+		if sidecar.Name == "" {
+			v.add("%s: sidecar name is required", prefix)
+		} else if sidecars[sidecar.Name] {
+			v.add("%s: duplicate sidecar name", sidecarPrefix)
+		}
+		sidecars[sidecar.Name] = true
+		if sidecar.Command == "" {
+			v.add("%s: command is required", sidecarPrefix)
+		}
+		if len(sidecar.ProcessTypes) == 0 {
+			v.add("%s: process_types is required", sidecarPrefix)
+		}
+		v.size(sidecarPrefix, "memory", sidecar.Memory)
+	}
+}
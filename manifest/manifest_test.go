@@ -0,0 +1,217 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testManifest = `---
+applications:
+- name: my-app
+  path: ./build
+  memory: ((memory))
+  disk_quota: 1G
+  instances: ((instances))
+  buildpacks:
+  - java_buildpack
+  command: bin/start --port $PORT
+  health-check-type: http
+  health-check-http-endpoint: /health
+  timeout: 120
+  env:
+    LOG_LEVEL: info
+    API_URL: https://((api_host))/v1
+    RETRIES: 3
+  routes:
+  - route: my-app.example.com
+  - route: my-app.example.com/api
+    protocol: http2
+  services:
+  - my-db
+  - name: my-queue
+    binding_name: queue
+    parameters:
+      durable: true
+      limits:
+        max: 10
+  stack: cflinuxfs3
+  processes:
+  - type: worker
+    command: bin/worker
+    instances: 2
+    memory: 256M
+  sidecars:
+  - name: proxy
+    process_types: [web, worker]
+    command: bin/proxy
+    memory: 64M
+`
+
+func TestParse(t *testing.T) {
+	Convey("Parse manifest", t, func() {
+		m, err := Parse([]byte(testManifest), map[string]interface{}{
+			"memory":    "512M",
+			"instances": 3,
+			"api_host":  "api.example.com",
+		})
+		So(err, ShouldBeNil)
+		So(m.Validate(), ShouldBeNil)
+		So(len(m.Applications), ShouldEqual, 1)
+
+		app := m.Application("my-app")
+		So(app, ShouldNotBeNil)
+		So(app.Path, ShouldEqual, "./build")
+		So(app.Memory, ShouldEqual, "512M")
+		So(*app.Instances, ShouldEqual, 3)
+		So(app.Buildpacks, ShouldResemble, []string{"java_buildpack"})
+		So(app.HealthCheckType, ShouldEqual, "http")
+		So(app.HealthCheckHttpEndpoint, ShouldEqual, "/health")
+		So(app.Timeout, ShouldEqual, 120)
+		So(app.Env, ShouldResemble, map[string]string{
+			"LOG_LEVEL": "info",
+			"API_URL":   "https://api.example.com/v1",
+			"RETRIES":   "3",
+		})
+		So(app.Routes, ShouldResemble, []Route{
+			{Route: "my-app.example.com"},
+			{Route: "my-app.example.com/api", Protocol: "http2"},
+		})
+		So(app.Services, ShouldResemble, []Service{
+			{Name: "my-db"},
+			{
+				Name:        "my-queue",
+				BindingName: "queue",
+				Parameters: map[string]interface{}{
+					"durable": true,
+					"limits":  map[string]interface{}{"max": 10},
+				},
+			},
+		})
+		So(app.Stack, ShouldEqual, "cflinuxfs3")
+		So(app.Processes[0].Type, ShouldEqual, "worker")
+		So(*app.Processes[0].Instances, ShouldEqual, 2)
+		So(app.Sidecars[0].ProcessTypes, ShouldResemble, []string{"web", "worker"})
+
+		So(m.Application("other-app"), ShouldBeNil)
+	})
+
+	Convey("Parse manifest with missing variables", t, func() {
+		_, err := Parse([]byte(testManifest), map[string]interface{}{"memory": "512M"})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Expected to find variables: api_host, instances")
+	})
+
+	Convey("Parse manifest with inheritance", t, func() {
+		_, err := Parse([]byte("inherit: base.yml\napplications:\n- name: my-app\n"), nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Marshal manifest", t, func() {
+		m, err := Parse([]byte(testManifest), map[string]interface{}{
+			"memory":    "512M",
+			"instances": 3,
+			"api_host":  "api.example.com",
+		})
+		So(err, ShouldBeNil)
+		data, err := m.Marshal()
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, "  services:\n  - my-db\n  - name: my-queue\n")
+
+		parsed, err := Parse(data, nil)
+		So(err, ShouldBeNil)
+		So(parsed, ShouldResemble, m)
+	})
+}
+
+func TestParseFile(t *testing.T) {
+	Convey("Parse manifest file with vars files", t, func() {
+		dir, err := ioutil.TempDir("", "manifest")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		write := func(name, content string) string {
+			path := filepath.Join(dir, name)
+			So(ioutil.WriteFile(path, []byte(content), 0644), ShouldBeNil)
+			return path
+		}
+		manifestPath := write("manifest.yml", testManifest)
+		defaults := write("defaults.yml", "memory: 256M\ninstances: 1\napi_host: localhost\n")
+		prod := write("prod.yml", "instances: 4\n")
+
+		m, err := ParseFile(manifestPath, map[string]interface{}{"api_host": "api.example.com"}, defaults, prod)
+		So(err, ShouldBeNil)
+		app := m.Applications[0]
+		So(app.Path, ShouldEqual, filepath.Join(dir, "build"))
+		So(app.Memory, ShouldEqual, "256M")
+		So(*app.Instances, ShouldEqual, 4)
+		So(app.Env["API_URL"], ShouldEqual, "https://api.example.com/v1")
+	})
+}
+
+func TestParseMegabytes(t *testing.T) {
+	Convey("Parse sizes", t, func() {
+		for size, mb := range map[string]int{
+			"512M":   512,
+			"512MB":  512,
+			"1G":     1024,
+			"2gb":    2048,
+			"1T":     1024 * 1024,
+			"2048KB": 2,
+			"512K":   1,
+			"1500K":  2,
+			"0K":     0,
+		} {
+			value, err := ParseMegabytes(size)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, mb)
+		}
+		for _, size := range []string{"", "512", "1.5G", "M", "12X"} {
+			_, err := ParseMegabytes(size)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Validate manifest", t, func() {
+		negative := -1
+		m := &Manifest{Applications: []Application{
+			{
+				Name:                    "my-app",
+				Buildpack:               "go_buildpack",
+				Buildpacks:              []string{"go_buildpack"},
+				Memory:                  "lots",
+				HealthCheckHttpEndpoint: "/health",
+				Instances:               &negative,
+				NoRoute:                 true,
+				Routes:                  []Route{{Route: "my-app.example.com"}},
+				Processes:               []Process{{Type: "web"}, {Type: "web"}},
+				Sidecars:                []Sidecar{{Name: "proxy"}},
+			},
+			{Name: "my-app", Docker: &Docker{}, Path: "."},
+			{},
+		}}
+		err := m.Validate()
+		So(err, ShouldNotBeNil)
+		So(err.(ValidationError).Errors, ShouldResemble, []string{
+			"application my-app: buildpack and buildpacks cannot both be set",
+			`application my-app: invalid memory "lots"`,
+			"application my-app: health-check-http-endpoint requires the http health-check-type",
+			"application my-app: instances cannot be negative",
+			"application my-app: no-route cannot be combined with routes or random-route",
+			"application my-app process web: duplicate process type",
+			"application my-app sidecar proxy: command is required",
+			"application my-app sidecar proxy: process_types is required",
+			"application my-app: duplicate name",
+			"application my-app: docker image is required",
+			"application my-app: docker and path cannot both be set",
+			"application 2: name is required",
+		})
+
+		So((&Manifest{}).Validate(), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,604 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfclient/manifest"
+	"github.com/pkg/errors"
+)
+
+// ApplyManifest applies the manifest to the space identified by spaceGuid
+// through the V3 apply_manifest action and waits for the resulting job. The
+// apps are created if needed and their settings, routes, services, processes
+// and sidecars updated, but no bits are uploaded nor apps restarted.
+func (c *Client) ApplyManifest(spaceGuid string, m *manifest.Manifest) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	body, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	requestURL := fmt.Sprintf("%s/v3/spaces/%s/actions/apply_manifest", c.Config.ApiAddress, spaceGuid)
+	req, err := http.NewRequest("POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Error creating apply manifest request")
+	}
+	req.Header.Set("Content-Type", "application/x-yaml")
+	resp, err := c.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Error applying manifest to space %s", spaceGuid)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return errors.Errorf("Error applying manifest to space %s, response code: %d", spaceGuid, resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return errors.Errorf("Error applying manifest to space %s, no job location returned", spaceGuid)
+	}
+	_, err = c.WaitForJob(location, WaitForJobOptions{})
+	return err
+}
+
+// PushManifest creates or updates the apps of the manifest in the space
+// identified by spaceGuid with V2 calls: settings are applied, routes created
+// and mapped, services bound and bits uploaded from the app path before the
+// app is restarted. Only the routes listed in the manifest are mapped, no
+// default route is generated. Random routes, processes other than web and
+// sidecars require the V3 API, see ApplyManifest.
+func (c *Client) PushManifest(spaceGuid string, m *manifest.Manifest) ([]App, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	apps := make([]App, 0, len(m.Applications))
+	for _, manifestApp := range m.Applications {
+		app, err := c.pushManifestApp(spaceGuid, manifestApp)
+		if err != nil {
+			return apps, errors.Wrapf(err, "Error pushing app %s", manifestApp.Name)
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+func (c *Client) pushManifestApp(spaceGuid string, manifestApp manifest.Application) (App, error) {
+	req, err := c.manifestAppRequest(manifestApp)
+	if err != nil {
+		return App{}, err
+	}
+
+	q := url.Values{}
+	q.Add("q", "name:"+manifestApp.Name)
+	q.Add("q", "space_guid:"+spaceGuid)
+	existing, err := c.ListAppsByQuery(q)
+	if err != nil {
+		return App{}, err
+	}
+	var app App
+	if len(existing) > 0 {
+		app, err = c.UpdateApp(existing[0].Guid, req)
+	} else {
+		app, err = c.CreateApp(createRequestFromUpdate(manifestApp.Name, spaceGuid, req))
+	}
+	if err != nil {
+		return App{}, err
+	}
+
+	if len(manifestApp.Routes) > 0 {
+		if err := c.mapManifestRoutes(app, manifestApp.Routes); err != nil {
+			return App{}, err
+		}
+	}
+	if len(manifestApp.Services) > 0 {
+		if err := c.bindManifestServices(app, manifestApp.Services); err != nil {
+			return App{}, err
+		}
+	}
+	if manifestApp.Docker == nil && manifestApp.Path != "" {
+		if err := c.uploadManifestPath(app.Guid, manifestApp.Path); err != nil {
+			return App{}, err
+		}
+	}
+	return c.RestartV2App(app.Guid)
+}
+
+// manifestAppRequest translates the settings of a manifest app into an
+// update request, only the attributes present in the manifest are set.
+func (c *Client) manifestAppRequest(manifestApp manifest.Application) (AppUpdateRequest, error) {
+	var processes []manifest.Process
+	for _, process := range manifestApp.Processes {
+		if process.Type != "web" {
+			return AppUpdateRequest{}, errors.Errorf("Process %s requires ApplyManifest", process.Type)
+		}
+		processes = append(processes, process)
+	}
+	if len(manifestApp.Sidecars) > 0 {
+		return AppUpdateRequest{}, errors.New("Sidecars require ApplyManifest")
+	}
+	if manifestApp.RandomRoute {
+		return AppUpdateRequest{}, errors.New("Random routes require ApplyManifest")
+	}
+	if len(manifestApp.Buildpacks) > 1 {
+		return AppUpdateRequest{}, errors.New("Multiple buildpacks require ApplyManifest")
+	}
+
+	req := AppUpdateRequest{
+		Command:                 manifestApp.Command,
+		Buildpack:               manifestApp.Buildpack,
+		HealthCheckType:         manifestApp.HealthCheckType,
+		HealthCheckHttpEndpoint: manifestApp.HealthCheckHttpEndpoint,
+		Instances:               manifestApp.Instances,
+	}
+	if len(manifestApp.Buildpacks) == 1 {
+		req.Buildpack = manifestApp.Buildpacks[0]
+	}
+	if manifestApp.Timeout > 0 {
+		timeout := manifestApp.Timeout
+		req.HealthCheckTimeout = &timeout
+	}
+	memory, diskQuota := manifestApp.Memory, manifestApp.DiskQuota
+	for _, web := range processes {
+		if web.Command != "" {
+			req.Command = web.Command
+		}
+		if web.HealthCheckType != "" {
+			req.HealthCheckType = web.HealthCheckType
+			req.HealthCheckHttpEndpoint = web.HealthCheckHttpEndpoint
+		}
+		if web.Instances != nil {
+			req.Instances = web.Instances
+		}
+		if web.Timeout > 0 {
+			timeout := web.Timeout
+			req.HealthCheckTimeout = &timeout
+		}
+		if web.Memory != "" {
+			memory = web.Memory
+		}
+		if web.DiskQuota != "" {
+			diskQuota = web.DiskQuota
+		}
+	}
+	if memory != "" {
+		mb, err := manifest.ParseMegabytes(memory)
+		if err != nil {
+			return AppUpdateRequest{}, err
+		}
+		req.Memory = &mb
+	}
+	if diskQuota != "" {
+		mb, err := manifest.ParseMegabytes(diskQuota)
+		if err != nil {
+			return AppUpdateRequest{}, err
+		}
+		req.DiskQuota = &mb
+	}
+	if manifestApp.Docker != nil {
+		if manifestApp.Docker.Username != "" {
+			return AppUpdateRequest{}, errors.New("Docker registry credentials are not supported")
+		}
+		req.DockerImage = manifestApp.Docker.Image
+		diego := true
+		req.Diego = &diego
+	}
+	if len(manifestApp.Env) > 0 {
+		req.Environment = make(map[string]interface{}, len(manifestApp.Env))
+		for k, v := range manifestApp.Env {
+			req.Environment[k] = v
+		}
+	}
+	if manifestApp.Stack != "" {
+		q := url.Values{}
+		q.Set("q", "name:"+manifestApp.Stack)
+		stacks, err := c.ListStacksByQuery(q)
+		if err != nil {
+			return AppUpdateRequest{}, err
+		}
+		if len(stacks) == 0 {
+			return AppUpdateRequest{}, errors.Errorf("Stack %s not found", manifestApp.Stack)
+		}
+		req.StackGuid = stacks[0].Guid
+	}
+	return req, nil
+}
+
+func createRequestFromUpdate(name, spaceGuid string, req AppUpdateRequest) AppCreateRequest {
+	create := AppCreateRequest{
+		Name:                    name,
+		SpaceGuid:               spaceGuid,
+		StackGuid:               req.StackGuid,
+		State:                   AppStopped,
+		Command:                 req.Command,
+		Buildpack:               req.Buildpack,
+		HealthCheckHttpEndpoint: req.HealthCheckHttpEndpoint,
+		HealthCheckType:         req.HealthCheckType,
+		DockerImage:             req.DockerImage,
+		Environment:             req.Environment,
+	}
+	if req.Memory != nil {
+		create.Memory = *req.Memory
+	}
+	if req.Instances != nil {
+		create.Instances = *req.Instances
+	}
+	if req.DiskQuota != nil {
+		create.DiskQuota = *req.DiskQuota
+	}
+	if req.HealthCheckTimeout != nil {
+		create.HealthCheckTimeout = *req.HealthCheckTimeout
+	}
+	if req.Diego != nil {
+		create.Diego = *req.Diego
+	}
+	return create
+}
+
+// mapManifestRoutes maps the routes to the app, creating the routes which
+// do not exist yet.
+func (c *Client) mapManifestRoutes(app App, routes []manifest.Route) error {
+	domains := map[string]string{}
+	privateDomains, err := c.ListDomains()
+	if err != nil {
+		return err
+	}
+	for _, domain := range privateDomains {
+		domains[domain.Name] = domain.Guid
+	}
+	sharedDomains, err := c.ListSharedDomains()
+	if err != nil {
+		return err
+	}
+	for _, domain := range sharedDomains {
+		domains[domain.Name] = domain.Guid
+	}
+
+	appRoutes, err := c.GetAppRoutes(app.Guid)
+	if err != nil {
+		return err
+	}
+	mapped := make(map[string]bool, len(appRoutes))
+	for _, route := range appRoutes {
+		mapped[route.Guid] = true
+	}
+
+	for _, route := range routes {
+		routeGuid, err := c.findOrCreateManifestRoute(app.SpaceGuid, route, domains)
+		if err != nil {
+			return err
+		}
+		if mapped[routeGuid] {
+			continue
+		}
+		if _, err := c.MapRoute(RouteMap{AppGUID: app.Guid, RouteGUID: routeGuid}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) findOrCreateManifestRoute(spaceGuid string, route manifest.Route, domains map[string]string) (string, error) {
+	if route.Protocol == "tcp" || strings.Contains(route.Route, ":") {
+		return "", errors.Errorf("TCP route %s is not supported", route.Route)
+	}
+	hostname, path := route.Route, ""
+	if i := strings.Index(hostname, "/"); i >= 0 {
+		hostname, path = hostname[:i], hostname[i:]
+	}
+	// the domain is the longest known suffix of the hostname
+	var host, domainName string
+	for name := range domains {
+		if len(name) <= len(domainName) {
+			continue
+		}
+		if hostname == name {
+			host, domainName = "", name
+		} else if strings.HasSuffix(hostname, "."+name) {
+			host, domainName = strings.TrimSuffix(hostname, "."+name), name
+		}
+	}
+	if domainName == "" {
+		return "", errors.Errorf("No domain found for route %s", route.Route)
+	}
+
+	q := url.Values{}
+	q.Add("q", "host:"+host)
+	q.Add("q", "domain_guid:"+domains[domainName])
+	if path != "" {
+		q.Add("q", "path:"+path)
+	}
+	existing, err := c.ListRoutesByQuery(q)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range existing {
+		if r.Path == path {
+			return r.Guid, nil
+		}
+	}
+	created, err := c.CreateHttpRoute(RouteRequest{
+		DomainGuid: domains[domainName],
+		SpaceGuid:  spaceGuid,
+		Host:       host,
+		Path:       path,
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Meta.GUID, nil
+}
+
+// bindManifestServices binds the service instances, managed or user
+// provided, which are not bound to the app yet.
+func (c *Client) bindManifestServices(app App, services []manifest.Service) error {
+	q := url.Values{}
+	q.Set("q", "app_guid:"+app.Guid)
+	bindings, err := c.ListServiceBindingsByQuery(q)
+	if err != nil {
+		return err
+	}
+	bound := make(map[string]bool, len(bindings))
+	for _, binding := range bindings {
+		bound[binding.ServiceInstanceGuid] = true
+	}
+
+	for _, service := range services {
+		instanceGuid, err := c.findManifestServiceInstance(app.SpaceGuid, service.Name)
+		if err != nil {
+			return err
+		}
+		if bound[instanceGuid] {
+			continue
+		}
+		_, err = c.CreateServiceBinding(ServiceBindingRequest{
+			AppGuid:             app.Guid,
+			ServiceInstanceGuid: instanceGuid,
+			Name:                service.BindingName,
+			Parameters:          service.Parameters,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) findManifestServiceInstance(spaceGuid, name string) (string, error) {
+	q := url.Values{}
+	q.Add("q", "name:"+name)
+	q.Add("q", "space_guid:"+spaceGuid)
+	instances, err := c.ListServiceInstancesByQuery(q)
+	if err != nil {
+		return "", err
+	}
+	if len(instances) > 0 {
+		return instances[0].Guid, nil
+	}
+	userProvided, err := c.ListUserProvidedServiceInstancesByQuery(q)
+	if err != nil {
+		return "", err
+	}
+	if len(userProvided) > 0 {
+		return userProvided[0].Guid, nil
+	}
+	return "", errors.Errorf("Service instance %s not found", name)
+}
+
+// uploadManifestPath uploads a directory, or an archive such as a zip or a
+// jar file, as the bits of the app.
+func (c *Client) uploadManifestPath(appGuid, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "Error reading app path %s", path)
+	}
+	if info.IsDir() {
+		return c.UploadAppBits(appGuid, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "Error reading app path %s", path)
+	}
+	defer f.Close()
+	return c.UploadAppZip(appGuid, f)
+}
+
+// GenerateManifest builds the manifest of the running V2 app identified by
+// appGuid from its settings, user provided environment, routes and service
+// bindings. When the V3 API is available, the processes, sidecars and
+// buildpacks of the manifest generated by the Cloud Controller are used as
+// well. Use Marshal on the result to get its YAML representation.
+func (c *Client) GenerateManifest(appGuid string) (*manifest.Manifest, error) {
+	app, err := c.GetAppByGuid(appGuid)
+	if err != nil {
+		return nil, err
+	}
+	instances := app.Instances
+	manifestApp := manifest.Application{
+		Name:                    app.Name,
+		Buildpack:               app.Buildpack,
+		Command:                 app.Command,
+		HealthCheckType:         app.HealthCheckType,
+		HealthCheckHttpEndpoint: app.HealthCheckHttpEndpoint,
+		Timeout:                 app.HealthCheckTimeout,
+		Instances:               &instances,
+	}
+	if app.Memory > 0 {
+		manifestApp.Memory = fmt.Sprintf("%dM", app.Memory)
+	}
+	if app.DiskQuota > 0 {
+		manifestApp.DiskQuota = fmt.Sprintf("%dM", app.DiskQuota)
+	}
+	if app.DockerImage != "" {
+		manifestApp.Docker = &manifest.Docker{Image: app.DockerImage}
+	}
+	if app.StackGuid != "" {
+		stack, err := c.GetStackByGuid(app.StackGuid)
+		if err != nil {
+			return nil, err
+		}
+		manifestApp.Stack = stack.Name
+	}
+
+	env, err := c.GetAppEnv(appGuid)
+	if err != nil {
+		return nil, err
+	}
+	if manifestApp.Env, err = manifestEnv(env.Environment); err != nil {
+		return nil, err
+	}
+
+	if manifestApp.Routes, err = c.manifestRoutes(appGuid); err != nil {
+		return nil, err
+	}
+	if len(manifestApp.Routes) == 0 {
+		manifestApp.NoRoute = true
+	}
+	if manifestApp.Services, err = c.manifestServices(appGuid); err != nil {
+		return nil, err
+	}
+
+	v3App, err := c.getV3AppManifest(appGuid)
+	if err != nil {
+		return nil, err
+	}
+	if v3App != nil {
+		manifestApp.Processes = v3App.Processes
+		manifestApp.Sidecars = v3App.Sidecars
+		if len(v3App.Buildpacks) > 0 {
+			manifestApp.Buildpack = ""
+			manifestApp.Buildpacks = v3App.Buildpacks
+		}
+	}
+	return &manifest.Manifest{Applications: []manifest.Application{manifestApp}}, nil
+}
+
+// manifestEnv converts environment values to strings, structured values are
+// written as JSON.
+func manifestEnv(environment map[string]interface{}) (map[string]string, error) {
+	if len(environment) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(environment))
+	for k, v := range environment {
+		switch value := v.(type) {
+		case string:
+			env[k] = value
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error encoding env %s", k)
+			}
+			env[k] = string(b)
+		default:
+			env[k] = fmt.Sprint(value)
+		}
+	}
+	return env, nil
+}
+
+func (c *Client) manifestRoutes(appGuid string) ([]manifest.Route, error) {
+	routes, err := c.GetAppRoutes(appGuid)
+	if err != nil || len(routes) == 0 {
+		return nil, err
+	}
+	domains := map[string]string{}
+	privateDomains, err := c.ListDomains()
+	if err != nil {
+		return nil, err
+	}
+	for _, domain := range privateDomains {
+		domains[domain.Guid] = domain.Name
+	}
+	sharedDomains, err := c.ListSharedDomains()
+	if err != nil {
+		return nil, err
+	}
+	for _, domain := range sharedDomains {
+		domains[domain.Guid] = domain.Name
+	}
+
+	manifestRoutes := make([]manifest.Route, 0, len(routes))
+	for _, route := range routes {
+		domain, ok := domains[route.DomainGuid]
+		if !ok {
+			return nil, errors.Errorf("Domain %s of route %s not found", route.DomainGuid, route.Guid)
+		}
+		name := domain
+		if route.Host != "" {
+			name = route.Host + "." + domain
+		}
+		if route.Port > 0 {
+			name = fmt.Sprintf("%s:%d", name, route.Port)
+		}
+		manifestRoutes = append(manifestRoutes, manifest.Route{Route: name + route.Path})
+	}
+	return manifestRoutes, nil
+}
+
+func (c *Client) manifestServices(appGuid string) ([]manifest.Service, error) {
+	q := url.Values{}
+	q.Set("q", "app_guid:"+appGuid)
+	bindings, err := c.ListServiceBindingsByQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	var services []manifest.Service
+	for _, binding := range bindings {
+		name, err := c.serviceInstanceName(binding.ServiceInstanceGuid)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, manifest.Service{Name: name})
+	}
+	return services, nil
+}
+
+// serviceInstanceName returns the name of a managed or user provided service
+// instance. User provided instances are not found among the managed ones.
+func (c *Client) serviceInstanceName(guid string) (string, error) {
+	instance, err := c.GetServiceInstanceByGuid(guid)
+	if err == nil {
+		return instance.Name, nil
+	}
+	if cfErr, ok := errors.Cause(err).(CloudFoundryError); !ok || cfErr.ErrorCode != "CF-ServiceInstanceNotFound" {
+		return "", err
+	}
+	userProvided, err := c.GetUserProvidedServiceInstanceByGuid(guid)
+	if err != nil {
+		return "", errors.Wrapf(err, "Error requesting service instance %s", guid)
+	}
+	return userProvided.Name, nil
+}
+
+// getV3AppManifest returns the application of the manifest generated by the
+// V3 API, or nil when the Cloud Controller does not support it, i.e. answers
+// the request as an unknown endpoint.
+func (c *Client) getV3AppManifest(appGuid string) (*manifest.Application, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/apps/"+appGuid+"/manifest"))
+	if err != nil {
+		if cfErr, ok := errors.Cause(err).(CloudFoundryError); ok && cfErr.Code == 10000 && cfErr.ErrorCode == "CF-NotFound" {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "Error requesting manifest of app %s", appGuid)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading manifest")
+	}
+	m, err := manifest.Parse(body, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Applications) == 0 {
+		return nil, nil
+	}
+	return &m.Applications[0], nil
+}
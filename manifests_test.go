@@ -0,0 +1,294 @@
+package cfclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfclient/manifest"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testSpaceGuid = "a72fa1e8-c694-47b3-85f2-55f61fd00d73"
+
+func testManifest() *manifest.Manifest {
+	instances := 2
+	return &manifest.Manifest{Applications: []manifest.Application{{
+		Name:       "my-app",
+		Memory:     "512M",
+		Instances:  &instances,
+		Command:    "./start.sh",
+		Buildpacks: []string{"go_buildpack"},
+		Env:        map[string]string{"FOO": "bar"},
+		Routes:     []manifest.Route{{Route: "my-app.vcap.me"}},
+		Services:   []manifest.Service{{Name: "my-db"}},
+	}}}
+}
+
+func handleApplyManifest(t *testing.T) {
+	mux.HandleFunc("/v3/spaces/"+testSpaceGuid+"/actions/apply_manifest", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/x-yaml" {
+			t.Errorf("Unexpected apply manifest request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if m, err := manifest.Parse(body, nil); err != nil || m.Application("my-app") == nil {
+			t.Errorf("Unexpected manifest %s", body)
+		}
+		w.Header().Set("Location", server.URL+"/v3/jobs/b19ae525-cbd3-4155-b156-dc0c2a431b4c")
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func TestApplyManifest(t *testing.T) {
+	Convey("Apply manifest", t, func() {
+		setup(MockRoute{"GET", "/v3/jobs/b19ae525-cbd3-4155-b156-dc0c2a431b4c", applyManifestJobCompletePayload, "", 200, "", nil}, t)
+		defer teardown()
+		handleApplyManifest(t)
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.ApplyManifest(testSpaceGuid, testManifest())
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Apply manifest failing", t, func() {
+		setup(MockRoute{"GET", "/v3/jobs/b19ae525-cbd3-4155-b156-dc0c2a431b4c", applyManifestJobFailedPayload, "", 200, "", nil}, t)
+		defer teardown()
+		handleApplyManifest(t)
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.ApplyManifest(testSpaceGuid, testManifest())
+		So(err, ShouldNotBeNil)
+		jobErr, ok := err.(JobFailedError)
+		So(ok, ShouldBeTrue)
+		So(jobErr.Operation, ShouldEqual, "app.apply_manifest")
+		cfErr, ok := errors.Cause(err).(CloudFoundryError)
+		So(ok, ShouldBeTrue)
+		So(cfErr.Code, ShouldEqual, 10008)
+		So(cfErr.ErrorCode, ShouldEqual, "CF-UnprocessableEntity")
+	})
+
+	Convey("Apply invalid manifest", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.ApplyManifest(testSpaceGuid, &manifest.Manifest{})
+		So(err, ShouldHaveSameTypeAs, manifest.ValidationError{})
+	})
+}
+
+func TestPushManifest(t *testing.T) {
+	Convey("Push manifest", t, func() {
+		dir, err := ioutil.TempDir("", "cfclient-push")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		err = ioutil.WriteFile(filepath.Join(dir, "start.sh"), []byte("#!/bin/sh\nexec ./my-app\n"), 0755)
+		So(err, ShouldBeNil)
+
+		appBody := `{"name":"my-app","space_guid":"a72fa1e8-c694-47b3-85f2-55f61fd00d73","memory":512,"instances":2,"state":"STOPPED","command":"./start.sh","buildpack":"go_buildpack","environment_json":{"FOO":"bar"}}`
+		routeBody := `{"domain_guid":"b2a35f0c-d5ad-4a59-bea7-461711d96b0d","space_guid":"a72fa1e8-c694-47b3-85f2-55f61fd00d73","host":"my-app"}`
+		routeMapBody := `{"app_guid":"b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c","route_guid":"78fe5006-1d1c-41ba-94de-eb7002241b82"}`
+		bindingBody := `{"app_guid":"b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c","service_instance_guid":"8423ca96-90ad-411f-b77a-0907844949fc"}`
+		mocks := []MockRoute{
+			{"GET", "/v2/apps", emptyResources, "", 200, "q=name:my-app&q=space_guid:a72fa1e8-c694-47b3-85f2-55f61fd00d73", nil},
+			{"POST", "/v2/apps", createAppPayload, "", 201, "", &appBody},
+			{"GET", "/v2/private_domains", listDomainsPayload, "", 200, "", nil},
+			{"GET", "/v2/shared_domains", listSharedDomainsPayload, "", 200, "", nil},
+			{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/routes", emptyResources, "", 200, "", nil},
+			{"GET", "/v2/routes", emptyResources, "", 200, "q=host:my-app&q=domain_guid:b2a35f0c-d5ad-4a59-bea7-461711d96b0d", nil},
+			{"POST", "/v2/routes", createRoute, "", 201, "", &routeBody},
+			{"POST", "/v2/route_mappings", "{}", "", 201, "", &routeMapBody},
+			{"GET", "/v2/service_bindings", emptyResources, "", 200, "q=app_guid:b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", nil},
+			{"GET", "/v2/service_instances", listServiceInstancePayload, "", 200, "q=name:my-db&q=space_guid:a72fa1e8-c694-47b3-85f2-55f61fd00d73", nil},
+			{"POST", "/v2/service_bindings", serviceBindingByGuidPayload, "", 201, "", &bindingBody},
+			{"PUT", "/v2/resource_match", "[]", "", 200, "", nil},
+			{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/bits", uploadBitsFinishedJobPayload, "", 201, "async=true", nil},
+			{"PUT", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", updateAppPayload, "", 201, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		m := testManifest()
+		m.Applications[0].Path = dir
+		apps, err := client.PushManifest(testSpaceGuid, m)
+		So(err, ShouldBeNil)
+		So(len(apps), ShouldEqual, 1)
+		So(apps[0].Guid, ShouldEqual, "b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+	})
+
+	Convey("Push manifest with worker process", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		m := testManifest()
+		m.Applications[0].Processes = []manifest.Process{{Type: "worker", Command: "./worker"}}
+		_, err = client.PushManifest(testSpaceGuid, m)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Push manifest with random route", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		m := testManifest()
+		m.Applications[0].Routes = nil
+		m.Applications[0].RandomRoute = true
+		So(m.Validate(), ShouldBeNil)
+		_, err = client.PushManifest(testSpaceGuid, m)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Random routes require ApplyManifest")
+	})
+}
+
+func TestGenerateManifest(t *testing.T) {
+	generateMocks := []MockRoute{
+		{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", createAppPayload, "", 200, "inline-relations-depth=2", nil},
+		{"GET", "/v2/stacks/2c531037-68a2-4e2c-a9e0-71f9d0abf0d4", stackPayload, "", 200, "", nil},
+		{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/env", appEnvPayload, "", 200, "", nil},
+		{"GET", "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/routes", generateManifestRoutesPayload, "", 200, "", nil},
+		{"GET", "/v2/private_domains", listDomainsPayload, "", 200, "", nil},
+		{"GET", "/v2/shared_domains", listSharedDomainsPayload, "", 200, "", nil},
+		{"GET", "/v2/service_bindings", listServiceBindingsPayload, "", 200, "q=app_guid:b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c", nil},
+		{"GET", "/v2/service_instances/bde206e0-1ee8-48ad-b794-44c857633d50", serviceInstanceNotFoundPayload, "", 404, "", nil},
+		{"GET", "/v2/user_provided_service_instances/bde206e0-1ee8-48ad-b794-44c857633d50", userProvidedServiceInstancePayload, "", 200, "", nil},
+	}
+
+	Convey("Generate manifest", t, func() {
+		mocks := append(generateMocks, MockRoute{"GET", "/v3/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/manifest", v3AppManifestPayload, "", 200, "", nil})
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		m, err := client.GenerateManifest("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldBeNil)
+		So(m.Validate(), ShouldBeNil)
+		app := m.Applications[0]
+		So(app.Name, ShouldEqual, "my-app")
+		So(app.Memory, ShouldEqual, "512M")
+		So(app.DiskQuota, ShouldEqual, "1024M")
+		So(*app.Instances, ShouldEqual, 2)
+		So(app.Command, ShouldEqual, "./start.sh")
+		So(app.Stack, ShouldEqual, "cflinuxfs2")
+		So(app.Env, ShouldResemble, map[string]string{"env_var": "env_val"})
+		So(app.Routes, ShouldResemble, []manifest.Route{
+			{Route: "my-app.vcap.me"},
+			{Route: "domain-49.example.com/api"},
+		})
+		So(app.Services, ShouldResemble, []manifest.Service{{Name: "name-1700"}})
+		So(app.Buildpack, ShouldEqual, "")
+		So(app.Buildpacks, ShouldResemble, []string{"go_buildpack", "nodejs_buildpack"})
+		So(len(app.Processes), ShouldEqual, 2)
+		So(app.Processes[1].Type, ShouldEqual, "worker")
+		So(app.Sidecars[0].Name, ShouldEqual, "proxy")
+
+		data, err := m.Marshal()
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, "- name: my-app\n")
+		So(string(data), ShouldContainSubstring, "  - route: my-app.vcap.me\n")
+	})
+
+	Convey("Generate manifest without V3 API", t, func() {
+		mocks := append(generateMocks, MockRoute{"GET", "/v3/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/manifest", `{"code":10000,"description":"Unknown request","error_code":"CF-NotFound"}`, "", 404, "", nil})
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		m, err := client.GenerateManifest("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldBeNil)
+		app := m.Applications[0]
+		So(app.Processes, ShouldBeEmpty)
+		So(app.Buildpacks, ShouldBeEmpty)
+		So(app.HealthCheckType, ShouldEqual, "http")
+		So(app.HealthCheckHttpEndpoint, ShouldEqual, "/health")
+	})
+
+	Convey("Generate manifest failing on a service instance", t, func() {
+		mocks := []MockRoute{}
+		for _, mock := range generateMocks {
+			if mock.Endpoint == "/v2/service_instances/bde206e0-1ee8-48ad-b794-44c857633d50" {
+				mock.Output = `{"code":10001,"description":"The service broker is unavailable","error_code":"CF-ServiceBrokerUnavailable"}`
+				mock.Status = 502
+			}
+			mocks = append(mocks, mock)
+		}
+		mocks = append(mocks, MockRoute{"GET", "/v3/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/manifest", v3AppManifestPayload, "", 200, "", nil})
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.GenerateManifest("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldNotBeNil)
+		cfErr, ok := errors.Cause(err).(CloudFoundryError)
+		So(ok, ShouldBeTrue)
+		So(cfErr.ErrorCode, ShouldEqual, "CF-ServiceBrokerUnavailable")
+	})
+
+	Convey("Generate manifest failing on the V3 API", t, func() {
+		mocks := append(generateMocks, MockRoute{"GET", "/v3/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/manifest", `{"code":10003,"description":"You are not authorized to perform the requested action","error_code":"CF-NotAuthorized"}`, "", 403, "", nil})
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.GenerateManifest("b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c")
+		So(err, ShouldNotBeNil)
+		cfErr, ok := errors.Cause(err).(CloudFoundryError)
+		So(ok, ShouldBeTrue)
+		So(cfErr.ErrorCode, ShouldEqual, "CF-NotAuthorized")
+	})
+}
@@ -1075,6 +1075,106 @@ const appPayload = `{
    }
 }`
 
+const createAppPayload = `{
+   "metadata": {
+      "guid": "b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c",
+      "url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c",
+      "created_at": "2017-09-12T09:41:26Z",
+      "updated_at": null
+   },
+   "entity": {
+      "name": "my-app",
+      "production": false,
+      "space_guid": "a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+      "stack_guid": "2c531037-68a2-4e2c-a9e0-71f9d0abf0d4",
+      "buildpack": null,
+      "detected_buildpack": null,
+      "detected_buildpack_guid": null,
+      "environment_json": {
+         "FOO": "bar"
+      },
+      "memory": 512,
+      "instances": 2,
+      "disk_quota": 1024,
+      "state": "STOPPED",
+      "version": "5c3d6d5c-9b1c-4b86-a4c3-cd6d5a5d7c71",
+      "command": "./start.sh",
+      "console": false,
+      "debug": null,
+      "staging_task_id": null,
+      "package_state": "PENDING",
+      "health_check_type": "http",
+      "health_check_timeout": null,
+      "health_check_http_endpoint": "/health",
+      "staging_failed_reason": null,
+      "staging_failed_description": null,
+      "diego": true,
+      "docker_image": null,
+      "package_updated_at": null,
+      "detected_start_command": "",
+      "enable_ssh": true,
+      "ports": [
+         8080
+      ],
+      "space_url": "/v2/spaces/a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+      "stack_url": "/v2/stacks/2c531037-68a2-4e2c-a9e0-71f9d0abf0d4",
+      "routes_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/routes",
+      "events_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/events",
+      "service_bindings_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/service_bindings",
+      "route_mappings_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/route_mappings"
+   }
+}`
+
+const updateAppPayload = `{
+   "metadata": {
+      "guid": "b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c",
+      "url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c",
+      "created_at": "2017-09-12T09:41:26Z",
+      "updated_at": "2017-09-12T09:45:02Z"
+   },
+   "entity": {
+      "name": "my-app",
+      "production": false,
+      "space_guid": "a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+      "stack_guid": "2c531037-68a2-4e2c-a9e0-71f9d0abf0d4",
+      "buildpack": null,
+      "detected_buildpack": "ruby",
+      "detected_buildpack_guid": "0d22f6a1-76c5-417f-ac6c-d9d21463ecbc",
+      "environment_json": {
+         "FOO": "bar"
+      },
+      "memory": 1024,
+      "instances": 0,
+      "disk_quota": 1024,
+      "state": "STARTED",
+      "version": "69b3e9a6-cf47-4df6-b4a7-bc1cc2bbc3d8",
+      "command": "./start.sh",
+      "console": false,
+      "debug": null,
+      "staging_task_id": "bd1b6ab6-3dbf-4b5f-9d4a-0ef1ce2c6cbb",
+      "package_state": "STAGED",
+      "health_check_type": "http",
+      "health_check_timeout": null,
+      "health_check_http_endpoint": "/health",
+      "staging_failed_reason": null,
+      "staging_failed_description": null,
+      "diego": true,
+      "docker_image": null,
+      "package_updated_at": "2017-09-12T09:42:11Z",
+      "detected_start_command": "bundle exec rackup",
+      "enable_ssh": true,
+      "ports": [
+         8080
+      ],
+      "space_url": "/v2/spaces/a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+      "stack_url": "/v2/stacks/2c531037-68a2-4e2c-a9e0-71f9d0abf0d4",
+      "routes_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/routes",
+      "events_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/events",
+      "service_bindings_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/service_bindings",
+      "route_mappings_url": "/v2/apps/b9ef8f86-0b7a-4a33-8c5e-9b1b4ff2da4c/route_mappings"
+   }
+}`
+
 const appEnvPayload = `{
   "staging_env_json": {
     "STAGING_ENV": "staging_value"
@@ -2058,8 +2158,7 @@ const createTaskPayload = `
       "href": "https://api.example.org/v3/droplets/740ebd2b-162b-469a-bd72-3edb96fabd9a"
     }
   }
-}
-`
+}`
 const listDomainsPayload = `{
   "total_results": 4,
   "total_pages": 1,
@@ -2653,3 +2752,1974 @@ const getServiceKeyPayload = `{
     }
   ]
 }`
+
+const resourceMatchPayload = `[
+  {
+    "sha1": "3d7735835e9194bf6b6dfdf967e2b77809f33657",
+    "size": 33
+  }
+]`
+
+const uploadBitsQueuedJobPayload = `{
+  "metadata": {
+    "guid": "3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b",
+    "created_at": "2017-09-12T10:02:33Z",
+    "url": "/v2/jobs/3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b"
+  },
+  "entity": {
+    "guid": "3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b",
+    "status": "queued"
+  }
+}`
+
+const uploadBitsFinishedJobPayload = `{
+  "metadata": {
+    "guid": "3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b",
+    "created_at": "2017-09-12T10:02:33Z",
+    "url": "/v2/jobs/3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b"
+  },
+  "entity": {
+    "guid": "3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b",
+    "status": "finished"
+  }
+}`
+
+const uploadBitsFailedJobPayload = `{
+  "metadata": {
+    "guid": "3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b",
+    "created_at": "2017-09-12T10:02:33Z",
+    "url": "/v2/jobs/3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b"
+  },
+  "entity": {
+    "guid": "3f3b4a5c-7d6e-4b2a-9f0e-1c2d3e4f5a6b",
+    "status": "failed",
+    "error": "Use error_details instead.",
+    "error_details": {
+      "error_code": "CF-AppBitsUploadInvalid",
+      "description": "The app upload is invalid: Error while processing files.",
+      "code": 160001
+    }
+  }
+}`
+
+const v3DropletPayload = `{
+  "guid": "585bc3c1-3743-497d-88b0-403ad6b56d16",
+  "state": "STAGED",
+  "error": null,
+  "lifecycle": {
+    "type": "buildpack",
+    "data": {}
+  },
+  "execution_metadata": "",
+  "process_types": {
+    "web": "rackup"
+  },
+  "checksum": {
+    "type": "sha256",
+    "value": "047fb131c9e3cd27fd864421c2f385981a0843be4eba9ee8fd5ac8130dce8fa2"
+  },
+  "buildpacks": [
+    {
+      "name": "ruby_buildpack",
+      "detect_output": "ruby 1.6.14",
+      "buildpack_name": "ruby",
+      "version": "1.1.1."
+    }
+  ],
+  "stack": "cflinuxfs2",
+  "image": null,
+  "created_at": "2016-03-28T23:39:34Z",
+  "updated_at": "2016-03-28T23:39:47Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16"
+    },
+    "package": {
+      "href": "https://api.example.org/v3/packages/8222f76a-9e09-4360-b3aa-1ed329945e92"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/7b34f1cf-7e73-428a-bb5a-8a17a8058396"
+    },
+    "assign_current_droplet": {
+      "href": "https://api.example.org/v3/apps/7b34f1cf-7e73-428a-bb5a-8a17a8058396/relationships/current_droplet",
+      "method": "PATCH"
+    }
+  }
+}`
+
+const appStagingFailedPayload = `{
+   "metadata": {
+      "guid": "9902530c-c634-4864-a189-71d763cb12e2",
+      "url": "/v2/apps/9902530c-c634-4864-a189-71d763cb12e2",
+      "created_at": "2014-11-07T23:11:39+00:00",
+      "updated_at": "2014-11-07T23:12:03+00:00"
+   },
+   "entity": {
+      "name": "test-env",
+      "space_guid": "a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+      "memory": 256,
+      "instances": 1,
+      "disk_quota": 1024,
+      "state": "STARTED",
+      "package_state": "FAILED",
+      "staging_failed_reason": "BuildpackCompileFailed",
+      "staging_failed_description": "App staging failed in the buildpack compile phase"
+   }
+}`
+
+const emptyEventsPayload = `{
+   "total_results": 0,
+   "total_pages": 1,
+   "prev_url": null,
+   "next_url": null,
+   "resources": []
+}`
+
+const appCrashEventsPayload = `{
+   "total_results": 1,
+   "total_pages": 1,
+   "prev_url": null,
+   "next_url": null,
+   "resources": [
+      {
+         "metadata": {
+            "guid": "b6e0a9a2-e4b6-4d3e-bd4a-e3d0e1de9f7e",
+            "url": "/v2/events/b6e0a9a2-e4b6-4d3e-bd4a-e3d0e1de9f7e",
+            "created_at": "2017-09-12T10:00:42Z",
+            "updated_at": "2017-09-12T10:00:42Z"
+         },
+         "entity": {
+            "type": "app.crash",
+            "actor": "9902530c-c634-4864-a189-71d763cb12e2",
+            "actor_type": "app",
+            "actor_name": "test-env",
+            "actee": "9902530c-c634-4864-a189-71d763cb12e2",
+            "actee_type": "app",
+            "actee_name": "test-env",
+            "timestamp": "2017-09-12T10:00:42Z",
+            "metadata": {
+               "instance": "6ac1fb2e-4e3a-4a8e-63b5-5b8e",
+               "index": 1,
+               "exit_description": "APP/PROC/WEB: Exited with status 1",
+               "reason": "CRASHED"
+            },
+            "space_guid": "a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+            "organization_guid": "bfdcdf09-a3b8-46f4-ab74-d494efefe5b4"
+         }
+      }
+   ]
+}`
+
+const appInstanceCrashedPayload = `{
+   "0": {
+      "state": "RUNNING",
+      "since": 1455210430.5104606
+   },
+   "1": {
+      "state": "CRASHED",
+      "since": 1455210430.3912115
+   }
+}`
+
+const appInstanceStartingPayload = `{
+   "0": {
+      "state": "STARTING",
+      "since": 1455210430.5104606
+   }
+}`
+
+const applyManifestJobCompletePayload = `{
+  "guid": "b19ae525-cbd3-4155-b156-dc0c2a431b4c",
+  "created_at": "2018-04-26T15:41:26Z",
+  "updated_at": "2018-04-26T15:41:28Z",
+  "operation": "app.apply_manifest",
+  "state": "COMPLETE",
+  "errors": [],
+  "warnings": [],
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/jobs/b19ae525-cbd3-4155-b156-dc0c2a431b4c"
+    },
+    "space": {
+      "href": "https://api.example.org/v3/spaces/a72fa1e8-c694-47b3-85f2-55f61fd00d73"
+    }
+  }
+}`
+
+const applyManifestJobFailedPayload = `{
+  "guid": "b19ae525-cbd3-4155-b156-dc0c2a431b4c",
+  "created_at": "2018-04-26T15:41:26Z",
+  "updated_at": "2018-04-26T15:41:28Z",
+  "operation": "app.apply_manifest",
+  "state": "FAILED",
+  "errors": [
+    {
+      "code": 10008,
+      "title": "CF-UnprocessableEntity",
+      "detail": "For application 'my-app': Routes cannot be mapped to destinations in different spaces"
+    }
+  ],
+  "warnings": [],
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/jobs/b19ae525-cbd3-4155-b156-dc0c2a431b4c"
+    }
+  }
+}`
+
+const stackPayload = `{
+   "metadata": {
+      "guid": "2c531037-68a2-4e2c-a9e0-71f9d0abf0d4",
+      "url": "/v2/stacks/2c531037-68a2-4e2c-a9e0-71f9d0abf0d4",
+      "created_at": "2017-01-18T16:39:11Z",
+      "updated_at": "2017-01-18T16:39:11Z"
+   },
+   "entity": {
+      "name": "cflinuxfs2",
+      "description": "Cloud Foundry Linux-based filesystem"
+   }
+}`
+
+const generateManifestRoutesPayload = `{
+  "total_results": 2,
+  "total_pages": 1,
+  "prev_url": null,
+  "next_url": null,
+  "resources": [
+    {
+      "metadata": {
+        "guid": "0e8d2b0c-5f5e-4a4e-9b3c-5d9f5c7c7b11",
+        "url": "/v2/routes/0e8d2b0c-5f5e-4a4e-9b3c-5d9f5c7c7b11",
+        "created_at": "2017-09-12T09:41:30Z",
+        "updated_at": null
+      },
+      "entity": {
+        "host": "my-app",
+        "path": "",
+        "domain_guid": "b2a35f0c-d5ad-4a59-bea7-461711d96b0d",
+        "space_guid": "a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+        "service_instance_guid": null,
+        "port": null
+      }
+    },
+    {
+      "metadata": {
+        "guid": "2f1c3a9e-7b8d-4c6e-a5f4-3e2d1c0b9a88",
+        "url": "/v2/routes/2f1c3a9e-7b8d-4c6e-a5f4-3e2d1c0b9a88",
+        "created_at": "2017-09-12T09:41:31Z",
+        "updated_at": null
+      },
+      "entity": {
+        "host": "",
+        "path": "/api",
+        "domain_guid": "91977695-8ad9-40db-858f-4df782603ec3",
+        "space_guid": "a72fa1e8-c694-47b3-85f2-55f61fd00d73",
+        "service_instance_guid": null,
+        "port": null
+      }
+    }
+  ]
+}`
+
+const serviceInstanceNotFoundPayload = `{
+  "code": 60004,
+  "description": "The service instance could not be found: bde206e0-1ee8-48ad-b794-44c857633d50",
+  "error_code": "CF-ServiceInstanceNotFound"
+}`
+
+const v3AppManifestPayload = `---
+applications:
+- name: my-app
+  buildpacks:
+  - go_buildpack
+  - nodejs_buildpack
+  processes:
+  - type: web
+    instances: 2
+    memory: 512M
+    disk_quota: 1024M
+    command: ./start.sh
+    health-check-type: http
+    health-check-http-endpoint: /health
+  - type: worker
+    instances: 1
+    memory: 256M
+    disk_quota: 1024M
+    command: ./worker
+  sidecars:
+  - name: proxy
+    process_types:
+    - web
+    command: ./proxy
+`
+
+const v3AppEnvVarsPayload = `{
+  "var": {
+    "RAILS_ENV": "production",
+    "DEBUG": "true"
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/environment_variables"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    }
+  }
+}`
+
+const v3AppEnvVarsUpdatedPayload = `{
+  "var": {
+    "NEW": "1"
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/environment_variables"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    }
+  }
+}`
+
+const v3AppStartedPayload = `{
+  "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446",
+  "name": "my_app",
+  "state": "STARTED",
+  "created_at": "2016-03-17T21:41:30Z",
+  "updated_at": "2016-03-18T11:32:30Z",
+  "lifecycle": {
+    "type": "buildpack",
+    "data": {
+      "buildpacks": ["java_buildpack"],
+      "stack": "cflinuxfs2"
+    }
+  },
+  "relationships": {
+    "space": {
+      "data": {
+        "guid": "2f35885d-0c9d-4423-83ad-fd05066f8576"
+      }
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    }
+  }
+}`
+
+const logCacheRootPayload = `{
+  "links": {
+    "self": {
+      "href": "https://api.example.org"
+    },
+    "cloud_controller_v2": {
+      "href": "https://api.example.org/v2",
+      "meta": {
+        "version": "2.131.0"
+      }
+    },
+    "cloud_controller_v3": {
+      "href": "https://api.example.org/v3",
+      "meta": {
+        "version": "3.66.0"
+      }
+    },
+    "log_cache": {
+      "href": "%s"
+    },
+    "logging": {
+      "href": "wss://doppler.example.org:443"
+    },
+    "uaa": {
+      "href": "https://uaa.example.org"
+    }
+  }
+}`
+
+const logCacheFirstPagePayload = `{
+  "envelopes": {
+    "batch": [
+      {
+        "timestamp": "1550000000000000000",
+        "source_id": "9902530c-c634-4864-a189-71d763cb12e2",
+        "instance_id": "0",
+        "tags": {
+          "source_type": "APP/PROC/WEB"
+        },
+        "log": {
+          "payload": "aGVsbG8gd29ybGQ=",
+          "type": "OUT"
+        }
+      },
+      {
+        "timestamp": "1550000001000000000",
+        "source_id": "9902530c-c634-4864-a189-71d763cb12e2",
+        "instance_id": "1",
+        "gauge": {
+          "metrics": {
+            "cpu": {
+              "unit": "percentage",
+              "value": 0.35
+            },
+            "memory": {
+              "unit": "bytes",
+              "value": 518123520
+            }
+          }
+        }
+      }
+    ]
+  }
+}`
+
+const logCacheSecondPagePayload = `{
+  "envelopes": {
+    "batch": [
+      {
+        "timestamp": "1550000002000000000",
+        "source_id": "9902530c-c634-4864-a189-71d763cb12e2",
+        "instance_id": "0",
+        "counter": {
+          "name": "requests",
+          "delta": "2",
+          "total": "42"
+        }
+      }
+    ]
+  }
+}`
+
+const promQLVectorPayload = `{
+  "status": "success",
+  "data": {
+    "resultType": "vector",
+    "result": [
+      {
+        "metric": {
+          "instance_id": "0",
+          "source_id": "9902530c-c634-4864-a189-71d763cb12e2"
+        },
+        "value": [1550000000.123, "0.35"]
+      },
+      {
+        "metric": {
+          "instance_id": "1",
+          "source_id": "9902530c-c634-4864-a189-71d763cb12e2"
+        },
+        "value": [1550000000.123, "0.5"]
+      }
+    ]
+  }
+}`
+
+const promQLMatrixPayload = `{
+  "status": "success",
+  "data": {
+    "resultType": "matrix",
+    "result": [
+      {
+        "metric": {
+          "source_id": "9902530c-c634-4864-a189-71d763cb12e2"
+        },
+        "values": [
+          [1550000000, "518123520"],
+          [1550000060, "530731008"]
+        ]
+      }
+    ]
+  }
+}`
+
+const promQLErrorPayload = `{
+  "status": "error",
+  "errorType": "bad_data",
+  "error": "parse error at char 4: unexpected end of input"
+}`
+
+const v3DockerAppStoppedPayload = `{
+  "guid": "02b4ec9b-94c7-4468-9c23-4e906191a0f8",
+  "name": "docker_app",
+  "state": "STOPPED",
+  "created_at": "2016-03-17T21:41:30Z",
+  "updated_at": "2016-03-18T11:32:30Z",
+  "lifecycle": {
+    "type": "docker",
+    "data": {}
+  },
+  "relationships": {
+    "space": {
+      "data": {
+        "guid": "2f35885d-0c9d-4423-83ad-fd05066f8576"
+      }
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/apps/02b4ec9b-94c7-4468-9c23-4e906191a0f8"
+    }
+  }
+}`
+
+const v3AppsFirstPagePayload = `{
+  "pagination": {
+    "total_results": 2,
+    "total_pages": 2,
+    "first": {
+      "href": "https://api.example.org/v3/apps?page=1&per_page=1&space_guids=2f35885d-0c9d-4423-83ad-fd05066f8576"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps?page=2&per_page=1&space_guids=2f35885d-0c9d-4423-83ad-fd05066f8576"
+    },
+    "next": {
+      "href": "https://api.example.org/v3/apps?page=2&per_page=1&space_guids=2f35885d-0c9d-4423-83ad-fd05066f8576"
+    },
+    "previous": null
+  },
+  "resources": [
+    ` + v3AppStartedPayload + `
+  ]
+}`
+
+const v3AppsSecondPagePayload = `{
+  "pagination": {
+    "total_results": 2,
+    "total_pages": 2,
+    "first": {
+      "href": "https://api.example.org/v3/apps?page=1&per_page=1&space_guids=2f35885d-0c9d-4423-83ad-fd05066f8576"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps?page=2&per_page=1&space_guids=2f35885d-0c9d-4423-83ad-fd05066f8576"
+    },
+    "next": null,
+    "previous": {
+      "href": "https://api.example.org/v3/apps?page=1&per_page=1&space_guids=2f35885d-0c9d-4423-83ad-fd05066f8576"
+    }
+  },
+  "resources": [
+    ` + v3DockerAppStoppedPayload + `
+  ]
+}`
+
+const v3PackageAwaitingUploadPayload = `{
+  "guid": "44f7c078-0934-470f-9883-4fcddc5b8f13",
+  "type": "bits",
+  "data": {
+    "checksum": {
+      "type": "sha256",
+      "value": null
+    },
+    "error": null
+  },
+  "state": "AWAITING_UPLOAD",
+  "created_at": "2015-11-13T17:02:56Z",
+  "updated_at": "2016-06-08T16:41:26Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13"
+    },
+    "upload": {
+      "href": "https://api.example.org/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13/upload",
+      "method": "POST"
+    },
+    "download": {
+      "href": "https://api.example.org/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13/download"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    }
+  }
+}`
+
+const v3PackageProcessingPayload = `{
+  "guid": "44f7c078-0934-470f-9883-4fcddc5b8f13",
+  "type": "bits",
+  "data": {
+    "checksum": {
+      "type": "sha256",
+      "value": null
+    },
+    "error": null
+  },
+  "state": "PROCESSING_UPLOAD",
+  "created_at": "2015-11-13T17:02:56Z",
+  "updated_at": "2016-06-08T16:41:27Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13"
+    }
+  }
+}`
+
+const v3PackageReadyPayload = `{
+  "guid": "44f7c078-0934-470f-9883-4fcddc5b8f13",
+  "type": "bits",
+  "data": {
+    "checksum": {
+      "type": "sha256",
+      "value": "ce127868b68204acf32d8fb14429592c91e42d503219e9dae74a2fafffdad059"
+    },
+    "error": null
+  },
+  "state": "READY",
+  "created_at": "2015-11-13T17:02:56Z",
+  "updated_at": "2016-06-08T16:41:30Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    }
+  }
+}`
+
+const v3PackageFailedPayload = `{
+  "guid": "44f7c078-0934-470f-9883-4fcddc5b8f13",
+  "type": "bits",
+  "data": {
+    "checksum": {
+      "type": "sha256",
+      "value": null
+    },
+    "error": "Invalid zip file"
+  },
+  "state": "FAILED",
+  "created_at": "2015-11-13T17:02:56Z",
+  "updated_at": "2016-06-08T16:41:30Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13"
+    }
+  }
+}`
+
+const v3DockerPackagePayload = `{
+  "guid": "732bc1e4-bf76-4d4c-9e6c-62f6bb4d5a8a",
+  "type": "docker",
+  "data": {
+    "image": "registry/image:latest",
+    "username": "username",
+    "password": "***"
+  },
+  "state": "READY",
+  "created_at": "2015-11-03T00:53:54Z",
+  "updated_at": "2016-06-08T16:41:26Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/packages/732bc1e4-bf76-4d4c-9e6c-62f6bb4d5a8a"
+    }
+  }
+}`
+
+const v3PackageCopyingPayload = `{
+  "guid": "fec72fc1-e453-4463-a86d-5df426f337a3",
+  "type": "bits",
+  "data": {
+    "checksum": {
+      "type": "sha256",
+      "value": null
+    },
+    "error": null
+  },
+  "state": "COPYING",
+  "created_at": "2016-06-08T16:41:35Z",
+  "updated_at": "2016-06-08T16:41:35Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/packages/fec72fc1-e453-4463-a86d-5df426f337a3"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/02b4ec9b-94c7-4468-9c23-4e906191a0f8"
+    }
+  }
+}`
+
+const v3AppPackagesPayload = `{
+  "pagination": {
+    "total_results": 2,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/packages?page=1&per_page=50&states=READY"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/packages?page=1&per_page=50&states=READY"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    ` + v3PackageReadyPayload + `,
+    ` + v3DockerPackagePayload + `
+  ]
+}`
+
+const v3ResourceMatchesPayload = `{
+  "resources": [
+    {
+      "checksum": {
+        "value": "3d7735835e9194bf6b6dfdf967e2b77809f33657"
+      },
+      "size_in_bytes": 33,
+      "path": "lib/cached.js",
+      "mode": "644"
+    }
+  ]
+}`
+
+const v3BuildStagingPayload = `{
+  "guid": "585bc3c1-3743-497d-88b0-403ad6b56d16",
+  "created_at": "2016-03-28T23:39:34Z",
+  "updated_at": "2016-06-08T16:41:26Z",
+  "created_by": {
+    "guid": "3cb4e243-bed4-49d5-8739-f8b45abdec1c",
+    "name": "bill",
+    "email": "bill@example.com"
+  },
+  "state": "STAGING",
+  "staging_memory_in_mb": 1024,
+  "staging_disk_in_mb": 1024,
+  "error": null,
+  "lifecycle": {
+    "type": "buildpack",
+    "data": {
+      "buildpacks": [
+        "ruby_buildpack"
+      ],
+      "stack": "cflinuxfs2"
+    }
+  },
+  "package": {
+    "guid": "44f7c078-0934-470f-9883-4fcddc5b8f13"
+  },
+  "droplet": null,
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    }
+  }
+}`
+
+const v3BuildStagedPayload = `{
+  "guid": "585bc3c1-3743-497d-88b0-403ad6b56d16",
+  "created_at": "2016-03-28T23:39:34Z",
+  "updated_at": "2016-06-08T16:42:26Z",
+  "state": "STAGED",
+  "staging_memory_in_mb": 1024,
+  "staging_disk_in_mb": 1024,
+  "error": null,
+  "lifecycle": {
+    "type": "buildpack",
+    "data": {
+      "buildpacks": [
+        "ruby_buildpack"
+      ],
+      "stack": "cflinuxfs2"
+    }
+  },
+  "package": {
+    "guid": "44f7c078-0934-470f-9883-4fcddc5b8f13"
+  },
+  "droplet": {
+    "guid": "be8a6d4f-a2c0-4b8e-a5d1-5c8a8d0b1e39"
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16"
+    },
+    "droplet": {
+      "href": "https://api.example.org/v3/droplets/be8a6d4f-a2c0-4b8e-a5d1-5c8a8d0b1e39"
+    }
+  }
+}`
+
+const v3BuildFailedPayload = `{
+  "guid": "585bc3c1-3743-497d-88b0-403ad6b56d16",
+  "created_at": "2016-03-28T23:39:34Z",
+  "updated_at": "2016-06-08T16:42:26Z",
+  "state": "FAILED",
+  "staging_memory_in_mb": 1024,
+  "staging_disk_in_mb": 4096,
+  "error": "StagingError - Staging error: staging failed",
+  "lifecycle": {
+    "type": "buildpack",
+    "data": {
+      "buildpacks": [
+        "ruby_buildpack"
+      ],
+      "stack": "cflinuxfs2"
+    }
+  },
+  "package": {
+    "guid": "44f7c078-0934-470f-9883-4fcddc5b8f13"
+  },
+  "droplet": null,
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16"
+    }
+  }
+}`
+
+const v3DropletAwaitingUploadPayload = `{
+  "guid": "3e3e8d8f-6c3e-4e3a-a2b6-1ef4c0d1f0a1",
+  "state": "AWAITING_UPLOAD",
+  "error": null,
+  "lifecycle": {
+    "type": "buildpack",
+    "data": {}
+  },
+  "execution_metadata": "",
+  "process_types": {
+    "web": "rackup"
+  },
+  "checksum": {
+    "type": "sha256",
+    "value": null
+  },
+  "buildpacks": [],
+  "stack": "cflinuxfs2",
+  "image": null,
+  "created_at": "2016-06-08T16:41:26Z",
+  "updated_at": "2016-06-08T16:41:26Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/droplets/3e3e8d8f-6c3e-4e3a-a2b6-1ef4c0d1f0a1"
+    }
+  }
+}`
+
+const v3DropletCopyingPayload = `{
+  "guid": "fdf3851c-def8-4de1-87f1-6d4543189e22",
+  "state": "COPYING",
+  "error": null,
+  "lifecycle": {
+    "type": "buildpack",
+    "data": {}
+  },
+  "execution_metadata": "",
+  "process_types": {
+    "web": "rackup"
+  },
+  "checksum": {
+    "type": "sha256",
+    "value": null
+  },
+  "buildpacks": [],
+  "stack": "cflinuxfs2",
+  "image": null,
+  "created_at": "2016-06-08T16:41:26Z",
+  "updated_at": "2016-06-08T16:41:26Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/droplets/fdf3851c-def8-4de1-87f1-6d4543189e22"
+    }
+  }
+}`
+
+const v3DropletUploadJobCompletePayload = `{
+  "guid": "d5d6d1c2-5b0e-4c1e-9f0a-3c1a0a6c7d5e",
+  "created_at": "2016-06-08T16:41:27Z",
+  "updated_at": "2016-06-08T16:41:29Z",
+  "operation": "droplet.upload",
+  "state": "COMPLETE",
+  "errors": [],
+  "warnings": [],
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/jobs/d5d6d1c2-5b0e-4c1e-9f0a-3c1a0a6c7d5e"
+    }
+  }
+}`
+
+const v3AppCurrentDropletListPayload = `{
+  "pagination": {
+    "total_results": 1,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets?current=true&page=1&per_page=50"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets?current=true&page=1&per_page=50"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    ` + v3DropletPayload + `
+  ]
+}`
+
+const v3AppDropletsPayload = `{
+  "pagination": {
+    "total_results": 5,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets?order_by=-created_at&page=1&per_page=50"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets?order_by=-created_at&page=1&per_page=50"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    ` + v3DropletCopyingPayload + `,
+    ` + v3DropletPayload + `,
+    {
+      "guid": "1f0fa1f6-8b8a-4e6d-9b54-2b0ad9f0e5c1",
+      "state": "STAGED",
+      "error": null,
+      "lifecycle": {
+        "type": "buildpack",
+        "data": {}
+      },
+      "execution_metadata": "",
+      "process_types": {
+        "web": "rackup"
+      },
+      "checksum": {
+        "type": "sha256",
+        "value": null
+      },
+      "buildpacks": [],
+      "stack": "cflinuxfs2",
+      "image": null,
+      "created_at": "2016-03-20T10:00:00Z",
+      "updated_at": "2016-03-20T10:00:00Z",
+      "links": {
+        "self": {
+          "href": "https://api.example.org/v3/droplets/1f0fa1f6-8b8a-4e6d-9b54-2b0ad9f0e5c1"
+        }
+      }
+    },
+    {
+      "guid": "2a8e2c43-0f1e-4c48-a2a4-5a7f8cd8e3b2",
+      "state": "FAILED",
+      "error": null,
+      "lifecycle": {
+        "type": "buildpack",
+        "data": {}
+      },
+      "execution_metadata": "",
+      "process_types": {
+        "web": "rackup"
+      },
+      "checksum": {
+        "type": "sha256",
+        "value": null
+      },
+      "buildpacks": [],
+      "stack": "cflinuxfs2",
+      "image": null,
+      "created_at": "2016-03-10T10:00:00Z",
+      "updated_at": "2016-03-10T10:00:00Z",
+      "links": {
+        "self": {
+          "href": "https://api.example.org/v3/droplets/2a8e2c43-0f1e-4c48-a2a4-5a7f8cd8e3b2"
+        }
+      }
+    },
+    {
+      "guid": "9b6e7e5d-6d1a-4b1c-8e0f-3f2c1b0a9d8e",
+      "state": "STAGED",
+      "error": null,
+      "lifecycle": {
+        "type": "buildpack",
+        "data": {}
+      },
+      "execution_metadata": "",
+      "process_types": {
+        "web": "rackup"
+      },
+      "checksum": {
+        "type": "sha256",
+        "value": null
+      },
+      "buildpacks": [],
+      "stack": "cflinuxfs2",
+      "image": null,
+      "created_at": "2016-03-01T10:00:00Z",
+      "updated_at": "2016-03-01T10:00:00Z",
+      "links": {
+        "self": {
+          "href": "https://api.example.org/v3/droplets/9b6e7e5d-6d1a-4b1c-8e0f-3f2c1b0a9d8e"
+        }
+      }
+    }
+  ]
+}`
+
+const v3WebProcessPayload = `{
+  "guid": "6a901b7c-9417-4dc1-8189-d3234aa0ab82",
+  "type": "web",
+  "command": "rackup",
+  "instances": 5,
+  "memory_in_mb": 256,
+  "disk_in_mb": 1024,
+  "health_check": {
+    "type": "port",
+    "data": {
+      "timeout": null,
+      "invocation_timeout": null
+    }
+  },
+  "created_at": "2016-03-23T18:48:22Z",
+  "updated_at": "2016-03-23T18:48:42Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82"
+    },
+    "scale": {
+      "href": "https://api.example.org/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82/actions/scale",
+      "method": "POST"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    },
+    "stats": {
+      "href": "https://api.example.org/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82/stats"
+    }
+  }
+}`
+
+const v3WorkerProcessPayload = `{
+  "guid": "3fccacd9-4b02-4b96-8d02-8e865865e9eb",
+  "type": "worker",
+  "command": "bundle exec rake worker",
+  "instances": 1,
+  "memory_in_mb": 256,
+  "disk_in_mb": 1024,
+  "health_check": {
+    "type": "process",
+    "data": {
+      "timeout": null
+    }
+  },
+  "created_at": "2016-03-23T18:48:22Z",
+  "updated_at": "2016-03-23T18:48:42Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/processes/3fccacd9-4b02-4b96-8d02-8e865865e9eb"
+    }
+  }
+}`
+
+const v3WebProcessScaledPayload = `{
+  "guid": "6a901b7c-9417-4dc1-8189-d3234aa0ab82",
+  "type": "web",
+  "command": "rackup",
+  "instances": 3,
+  "memory_in_mb": 512,
+  "disk_in_mb": 1024,
+  "health_check": {
+    "type": "port",
+    "data": {
+      "timeout": null,
+      "invocation_timeout": null
+    }
+  },
+  "created_at": "2016-03-23T18:48:22Z",
+  "updated_at": "2016-03-23T18:50:02Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82"
+    }
+  }
+}`
+
+const v3WebProcessHTTPHealthCheckPayload = `{
+  "guid": "6a901b7c-9417-4dc1-8189-d3234aa0ab82",
+  "type": "web",
+  "command": "rackup",
+  "instances": 5,
+  "memory_in_mb": 256,
+  "disk_in_mb": 1024,
+  "health_check": {
+    "type": "http",
+    "data": {
+      "timeout": 120,
+      "invocation_timeout": 5,
+      "endpoint": "/health"
+    }
+  },
+  "created_at": "2016-03-23T18:48:22Z",
+  "updated_at": "2016-03-23T18:51:12Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82"
+    }
+  }
+}`
+
+const v3AppProcessesPayload = `{
+  "pagination": {
+    "total_results": 2,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes?page=1&per_page=50"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes?page=1&per_page=50"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    ` + v3WebProcessPayload + `,
+    ` + v3WorkerProcessPayload + `
+  ]
+}`
+
+const v3ProcessStatsPayload = `{
+  "resources": [
+    {
+      "type": "web",
+      "index": 0,
+      "state": "RUNNING",
+      "usage": {
+        "time": "2016-03-23T23:17:30.476314154Z",
+        "cpu": 0.00038711029163348665,
+        "cpu_entitlement": 0.0125,
+        "mem": 19177472,
+        "disk": 69705728
+      },
+      "host": "10.244.16.10",
+      "instance_ports": [
+        {
+          "external": 64546,
+          "internal": 8080
+        }
+      ],
+      "uptime": 9042,
+      "mem_quota": 268435456,
+      "disk_quota": 1073741824,
+      "fds_quota": 16384,
+      "isolation_segment": "example_iso_segment",
+      "details": null
+    },
+    {
+      "type": "web",
+      "index": 1,
+      "state": "DOWN",
+      "usage": {},
+      "host": null,
+      "uptime": 0,
+      "mem_quota": null,
+      "disk_quota": null,
+      "fds_quota": null,
+      "isolation_segment": null,
+      "details": "insufficient resources"
+    }
+  ]
+}`
+
+const v3DeploymentDeployingPayload = `{
+  "guid": "59c3d133-2b83-46f3-960e-7765a129aea4",
+  "status": {
+    "value": "ACTIVE",
+    "reason": "DEPLOYING",
+    "details": {
+      "last_successful_healthcheck": "2018-04-25T22:42:10Z",
+      "last_status_change": "2018-04-25T22:42:10Z"
+    }
+  },
+  "strategy": "rolling",
+  "options": {
+    "max_in_flight": 1
+  },
+  "droplet": {
+    "guid": "44ccfa61-dbcf-4a0d-82fe-f668e9d2a962"
+  },
+  "previous_droplet": {
+    "guid": "cc6bc315-bd06-49ce-92c2-bc3ad45268c2"
+  },
+  "new_processes": [
+    {
+      "guid": "fd5d3e60-f88c-4c37-b1ae-667cfc65a856",
+      "type": "web"
+    }
+  ],
+  "revision": {
+    "guid": "56126cba-656a-4eba-a81e-7e9951b2df57",
+    "version": 1
+  },
+  "created_at": "2018-04-25T22:42:10Z",
+  "updated_at": "2018-04-25T22:42:10Z",
+  "relationships": {
+    "app": {
+      "data": {
+        "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+      }
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    },
+    "cancel": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4/actions/cancel",
+      "method": "POST"
+    }
+  }
+}`
+
+const v3DeploymentDeployedPayload = `{
+  "guid": "59c3d133-2b83-46f3-960e-7765a129aea4",
+  "status": {
+    "value": "FINALIZED",
+    "reason": "DEPLOYED",
+    "details": {
+      "last_successful_healthcheck": "2018-04-25T22:42:10Z",
+      "last_status_change": "2018-04-25T22:42:10Z"
+    }
+  },
+  "strategy": "rolling",
+  "options": {
+    "max_in_flight": 1
+  },
+  "droplet": {
+    "guid": "44ccfa61-dbcf-4a0d-82fe-f668e9d2a962"
+  },
+  "previous_droplet": {
+    "guid": "cc6bc315-bd06-49ce-92c2-bc3ad45268c2"
+  },
+  "new_processes": [
+    {
+      "guid": "fd5d3e60-f88c-4c37-b1ae-667cfc65a856",
+      "type": "web"
+    }
+  ],
+  "revision": {
+    "guid": "56126cba-656a-4eba-a81e-7e9951b2df57",
+    "version": 1
+  },
+  "created_at": "2018-04-25T22:42:10Z",
+  "updated_at": "2018-04-25T22:42:10Z",
+  "relationships": {
+    "app": {
+      "data": {
+        "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+      }
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    },
+    "cancel": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4/actions/cancel",
+      "method": "POST"
+    }
+  }
+}`
+
+const v3CanaryDeploymentPausedPayload = `{
+  "guid": "59c3d133-2b83-46f3-960e-7765a129aea4",
+  "status": {
+    "value": "ACTIVE",
+    "reason": "PAUSED",
+    "details": {
+      "last_successful_healthcheck": "2018-04-25T22:42:10Z",
+      "last_status_change": "2018-04-25T22:42:10Z"
+    }
+  },
+  "strategy": "canary",
+  "options": {
+    "max_in_flight": 1,
+    "canary": {
+      "steps": [
+        {
+          "instance_weight": 20
+        }
+      ]
+    }
+  },
+  "droplet": {
+    "guid": "44ccfa61-dbcf-4a0d-82fe-f668e9d2a962"
+  },
+  "previous_droplet": {
+    "guid": "cc6bc315-bd06-49ce-92c2-bc3ad45268c2"
+  },
+  "new_processes": [
+    {
+      "guid": "fd5d3e60-f88c-4c37-b1ae-667cfc65a856",
+      "type": "web"
+    }
+  ],
+  "revision": {
+    "guid": "56126cba-656a-4eba-a81e-7e9951b2df57",
+    "version": 1
+  },
+  "created_at": "2018-04-25T22:42:10Z",
+  "updated_at": "2018-04-25T22:42:10Z",
+  "relationships": {
+    "app": {
+      "data": {
+        "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+      }
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    },
+    "cancel": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4/actions/cancel",
+      "method": "POST"
+    }
+  }
+}`
+
+const v3CanaryDeploymentCanceledPayload = `{
+  "guid": "59c3d133-2b83-46f3-960e-7765a129aea4",
+  "status": {
+    "value": "FINALIZED",
+    "reason": "CANCELED",
+    "details": {
+      "last_successful_healthcheck": "2018-04-25T22:42:10Z",
+      "last_status_change": "2018-04-25T22:42:10Z"
+    }
+  },
+  "strategy": "canary",
+  "options": {
+    "max_in_flight": 1,
+    "canary": {
+      "steps": [
+        {
+          "instance_weight": 20
+        }
+      ]
+    }
+  },
+  "droplet": {
+    "guid": "44ccfa61-dbcf-4a0d-82fe-f668e9d2a962"
+  },
+  "previous_droplet": {
+    "guid": "cc6bc315-bd06-49ce-92c2-bc3ad45268c2"
+  },
+  "new_processes": [
+    {
+      "guid": "fd5d3e60-f88c-4c37-b1ae-667cfc65a856",
+      "type": "web"
+    }
+  ],
+  "revision": {
+    "guid": "56126cba-656a-4eba-a81e-7e9951b2df57",
+    "version": 1
+  },
+  "created_at": "2018-04-25T22:42:10Z",
+  "updated_at": "2018-04-25T22:42:10Z",
+  "relationships": {
+    "app": {
+      "data": {
+        "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+      }
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    },
+    "cancel": {
+      "href": "https://api.example.org/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4/actions/cancel",
+      "method": "POST"
+    }
+  }
+}`
+
+const v3DeploymentsPayload = `{
+  "pagination": {
+    "total_results": 1,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/deployments?app_guids=1cb006ee-fb05-47e1-b541-c34179ddc446&page=1&per_page=50&status_values=ACTIVE"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/deployments?app_guids=1cb006ee-fb05-47e1-b541-c34179ddc446&page=1&per_page=50&status_values=ACTIVE"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    ` + v3DeploymentDeployingPayload + `
+  ]
+}`
+
+const v3RevisionPayload = `{
+  "guid": "56126cba-656a-4eba-a81e-7e9951b2df57",
+  "version": 1,
+  "droplet": {
+    "guid": "585bc3c1-3743-497d-88b0-403ad6b56d16"
+  },
+  "processes": {
+    "web": {
+      "command": "bundle exec rackup"
+    }
+  },
+  "sidecars": [],
+  "description": "Initial revision.",
+  "deployable": true,
+  "relationships": {
+    "app": {
+      "data": {
+        "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+      }
+    }
+  },
+  "created_at": "2017-02-01T01:33:58Z",
+  "updated_at": "2017-02-01T01:33:58Z",
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/revisions/56126cba-656a-4eba-a81e-7e9951b2df57"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    },
+    "environment_variables": {
+      "href": "https://api.example.org/v3/revisions/56126cba-656a-4eba-a81e-7e9951b2df57/environment_variables"
+    }
+  }
+}`
+
+const v3AppRevisionsFirstPagePayload = `{
+  "pagination": {
+    "total_results": 2,
+    "total_pages": 2,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions?page=1&per_page=1"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions?page=2&per_page=1"
+    },
+    "next": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions?page=2&per_page=1"
+    },
+    "previous": null
+  },
+  "resources": [
+    ` + v3RevisionPayload + `
+  ]
+}`
+
+const v3AppRevisionsSecondPagePayload = `{
+  "pagination": {
+    "total_results": 2,
+    "total_pages": 2,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions?page=1&per_page=1"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions?page=2&per_page=1"
+    },
+    "next": null,
+    "previous": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions?page=1&per_page=1"
+    }
+  },
+  "resources": [
+    {
+      "guid": "a3c5d8e4-2f7b-4b61-9d0c-6e1f2a3b4c5d",
+      "version": 2,
+      "droplet": {
+        "guid": "44ccfa61-dbcf-4a0d-82fe-f668e9d2a962"
+      },
+      "processes": {
+        "web": {
+          "command": "bundle exec rackup"
+        }
+      },
+      "sidecars": [],
+      "description": "New droplet deployed.",
+      "deployable": true,
+      "relationships": {
+        "app": {
+          "data": {
+            "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+          }
+        }
+      },
+      "created_at": "2017-02-01T01:33:58Z",
+      "updated_at": "2017-02-01T01:33:58Z",
+      "links": {
+        "self": {
+          "href": "https://api.example.org/v3/revisions/a3c5d8e4-2f7b-4b61-9d0c-6e1f2a3b4c5d"
+        },
+        "app": {
+          "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+        },
+        "environment_variables": {
+          "href": "https://api.example.org/v3/revisions/a3c5d8e4-2f7b-4b61-9d0c-6e1f2a3b4c5d/environment_variables"
+        }
+      }
+    }
+  ]
+}`
+
+const v3DeployedRevisionsPayload = `{
+  "pagination": {
+    "total_results": 1,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions/deployed?page=1&per_page=50"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions/deployed?page=1&per_page=50"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    ` + v3RevisionPayload + `
+  ]
+}`
+
+const v3RevisionEnvVarsPayload = `{
+  "var": {
+    "RAILS_ENV": "production"
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/revisions/56126cba-656a-4eba-a81e-7e9951b2df57/environment_variables"
+    },
+    "revision": {
+      "href": "https://api.example.org/v3/revisions/56126cba-656a-4eba-a81e-7e9951b2df57"
+    },
+    "app": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+    }
+  }
+}`
+
+const v3SidecarPayload = `{
+  "guid": "885a8cb3-c07b-4856-b448-eeb10bf36236",
+  "name": "apm-agent",
+  "command": "./apm-agent",
+  "process_types": [
+    "web",
+    "worker"
+  ],
+  "memory_in_mb": 128,
+  "origin": "user",
+  "relationships": {
+    "app": {
+      "data": {
+        "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+      }
+    }
+  },
+  "created_at": "2017-02-01T01:33:58Z",
+  "updated_at": "2017-02-01T01:33:58Z"
+}`
+
+const v3SidecarUpdatedPayload = `{
+  "guid": "885a8cb3-c07b-4856-b448-eeb10bf36236",
+  "name": "apm-agent",
+  "command": "./apm-agent --verbose",
+  "process_types": [
+    "web"
+  ],
+  "memory_in_mb": 64,
+  "origin": "user",
+  "relationships": {
+    "app": {
+      "data": {
+        "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+      }
+    }
+  },
+  "created_at": "2017-02-01T01:33:58Z",
+  "updated_at": "2017-02-01T01:33:58Z"
+}`
+
+const v3SidecarsPayload = `{
+  "pagination": {
+    "total_results": 2,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/sidecars?page=1&per_page=50"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/sidecars?page=1&per_page=50"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    ` + v3SidecarPayload + `,
+    {
+      "guid": "f5a3f4c1-2d1e-4b3a-9c8d-7e6f5a4b3c2d",
+      "name": "config-server",
+      "command": "./config-server",
+      "process_types": [
+        "web"
+      ],
+      "memory_in_mb": null,
+      "origin": "buildpack",
+      "relationships": {
+        "app": {
+          "data": {
+            "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446"
+          }
+        }
+      },
+      "created_at": "2017-02-01T01:33:58Z",
+      "updated_at": "2017-02-01T01:33:58Z"
+    }
+  ]
+}`
+
+const v3SpaceLabeledPayload = `{
+  "guid": "2f35885d-0c9d-4423-83ad-fd05066f8576",
+  "created_at": "2017-02-01T01:33:58Z",
+  "updated_at": "2017-02-01T01:33:58Z",
+  "name": "my-space",
+  "relationships": {
+    "organization": {
+      "data": {
+        "guid": "e00705b9-7b42-4561-ae97-2520399d2133"
+      }
+    }
+  },
+  "metadata": {
+    "labels": {
+      "env": "prod",
+      "team": "payments"
+    },
+    "annotations": {
+      "contact": "payments@example.com"
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/spaces/2f35885d-0c9d-4423-83ad-fd05066f8576"
+    }
+  }
+}`
+
+const v3SpaceRelabeledPayload = `{
+  "guid": "2f35885d-0c9d-4423-83ad-fd05066f8576",
+  "created_at": "2017-02-01T01:33:58Z",
+  "updated_at": "2017-02-02T10:12:01Z",
+  "name": "my-space",
+  "metadata": {
+    "labels": {
+      "env": "stage"
+    },
+    "annotations": {
+      "contact": "payments@example.com",
+      "runbook": "https://wiki.example.com/payments"
+    }
+  },
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/spaces/2f35885d-0c9d-4423-83ad-fd05066f8576"
+    }
+  }
+}`
+
+const v3LabeledAppsPayload = `{
+  "pagination": {
+    "total_results": 1,
+    "total_pages": 1,
+    "first": {
+      "href": "https://api.example.org/v3/apps?label_selector=env+in+%28prod%2Cstage%29%2C%21deprecated&page=1&per_page=50"
+    },
+    "last": {
+      "href": "https://api.example.org/v3/apps?label_selector=env+in+%28prod%2Cstage%29%2C%21deprecated&page=1&per_page=50"
+    },
+    "next": null,
+    "previous": null
+  },
+  "resources": [
+    {
+      "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446",
+      "name": "my_app",
+      "state": "STARTED",
+      "created_at": "2016-03-17T21:41:30Z",
+      "updated_at": "2016-06-08T16:41:26Z",
+      "lifecycle": {
+        "type": "buildpack",
+        "data": {
+          "buildpacks": [
+            "java_buildpack"
+          ],
+          "stack": "cflinuxfs2"
+        }
+      },
+      "metadata": {
+        "labels": {
+          "env": "prod"
+        },
+        "annotations": {}
+      },
+      "links": {
+        "self": {
+          "href": "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446"
+        }
+      }
+    }
+  ]
+}`
+
+const v3AppDeleteJobProcessingPayload = `{
+  "guid": "c33a5caf-77e0-4d6e-b587-5555d339bc9a",
+  "created_at": "2018-09-03T11:50:02Z",
+  "updated_at": "2018-09-03T11:50:02Z",
+  "operation": "app.delete",
+  "state": "PROCESSING",
+  "errors": [],
+  "warnings": [],
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/jobs/c33a5caf-77e0-4d6e-b587-5555d339bc9a"
+    }
+  }
+}`
+
+const v3AppDeleteJobCompletePayload = `{
+  "guid": "c33a5caf-77e0-4d6e-b587-5555d339bc9a",
+  "created_at": "2018-09-03T11:50:02Z",
+  "updated_at": "2018-09-03T11:50:04Z",
+  "operation": "app.delete",
+  "state": "COMPLETE",
+  "errors": [],
+  "warnings": [
+    {
+      "detail": "The app had bound service instances which were unbound"
+    }
+  ],
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/jobs/c33a5caf-77e0-4d6e-b587-5555d339bc9a"
+    }
+  }
+}`
+
+const v3AppDeleteJobFailedPayload = `{
+  "guid": "c33a5caf-77e0-4d6e-b587-5555d339bc9a",
+  "created_at": "2018-09-03T11:50:02Z",
+  "updated_at": "2018-09-03T11:50:04Z",
+  "operation": "app.delete",
+  "state": "FAILED",
+  "errors": [
+    {
+      "code": 10001,
+      "title": "CF-AsyncServiceBindingOperationInProgress",
+      "detail": "An operation for the service binding between app my_app and service instance my-db is in progress."
+    },
+    {
+      "code": 10001,
+      "title": "CF-AsyncServiceBindingOperationInProgress",
+      "detail": "An operation for the service binding between app my_app and service instance my-cache is in progress."
+    }
+  ],
+  "warnings": [
+    {
+      "detail": "The service broker is slow to respond"
+    }
+  ],
+  "links": {
+    "self": {
+      "href": "https://api.example.org/v3/jobs/c33a5caf-77e0-4d6e-b587-5555d339bc9a"
+    }
+  }
+}`
+
+const serviceInstanceCreateInProgressPayload = `{
+   "metadata": {
+      "guid": "8423ca96-90ad-411f-b77a-0907844949fc",
+      "url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "created_at": "2016-10-21T18:22:56Z",
+      "updated_at": "2016-10-21T18:22:56Z"
+   },
+   "entity": {
+      "name": "fortunes-db",
+      "credentials": {},
+      "service_guid": "440ce9d9-b108-4bbe-80b4-08338f3cc25b",
+      "service_plan_guid": "f48419f7-4717-4706-86e4-a24973848a77",
+      "space_guid": "21e5fdc7-5131-4743-8447-6373cf336a77",
+      "gateway_data": null,
+      "dashboard_url": "https://p-mysql.system.example.com/manage/instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "type": "managed_service_instance",
+      "last_operation": {
+         "type": "create",
+         "state": "in progress",
+         "description": "Provisioning the database",
+         "updated_at": "2016-10-21T18:22:56Z",
+         "created_at": "2016-10-21T18:22:56Z"
+      },
+      "tags": [
+         "mysql"
+      ],
+      "space_url": "/v2/spaces/21e5fdc7-5131-4743-8447-6373cf336a77",
+      "service_plan_url": "/v2/service_plans/f48419f7-4717-4706-86e4-a24973848a77",
+      "service_bindings_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_bindings",
+      "service_keys_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_keys",
+      "routes_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/routes",
+      "service_url": "/v2/services/440ce9d9-b108-4bbe-80b4-08338f3cc25b"
+   }
+}`
+
+const serviceInstanceCreatedPayload = `{
+   "metadata": {
+      "guid": "8423ca96-90ad-411f-b77a-0907844949fc",
+      "url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "created_at": "2016-10-21T18:22:56Z",
+      "updated_at": "2016-10-21T18:22:56Z"
+   },
+   "entity": {
+      "name": "fortunes-db",
+      "credentials": {},
+      "service_guid": "440ce9d9-b108-4bbe-80b4-08338f3cc25b",
+      "service_plan_guid": "f48419f7-4717-4706-86e4-a24973848a77",
+      "space_guid": "21e5fdc7-5131-4743-8447-6373cf336a77",
+      "gateway_data": null,
+      "dashboard_url": "https://p-mysql.system.example.com/manage/instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "type": "managed_service_instance",
+      "last_operation": {
+         "type": "create",
+         "state": "succeeded",
+         "description": "",
+         "updated_at": "2016-10-21T18:22:56Z",
+         "created_at": "2016-10-21T18:22:56Z"
+      },
+      "tags": [
+         "mysql"
+      ],
+      "space_url": "/v2/spaces/21e5fdc7-5131-4743-8447-6373cf336a77",
+      "service_plan_url": "/v2/service_plans/f48419f7-4717-4706-86e4-a24973848a77",
+      "service_bindings_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_bindings",
+      "service_keys_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_keys",
+      "routes_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/routes",
+      "service_url": "/v2/services/440ce9d9-b108-4bbe-80b4-08338f3cc25b"
+   }
+}`
+
+const serviceInstanceUpdateInProgressPayload = `{
+   "metadata": {
+      "guid": "8423ca96-90ad-411f-b77a-0907844949fc",
+      "url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "created_at": "2016-10-21T18:22:56Z",
+      "updated_at": "2016-10-21T18:22:56Z"
+   },
+   "entity": {
+      "name": "quotes-db",
+      "credentials": {},
+      "service_guid": "440ce9d9-b108-4bbe-80b4-08338f3cc25b",
+      "service_plan_guid": "0a3c8a4b-2f35-4c5e-9a0d-4d8c2e3d5b71",
+      "space_guid": "21e5fdc7-5131-4743-8447-6373cf336a77",
+      "gateway_data": null,
+      "dashboard_url": "https://p-mysql.system.example.com/manage/instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "type": "managed_service_instance",
+      "last_operation": {
+         "type": "update",
+         "state": "in progress",
+         "description": "",
+         "updated_at": "2016-10-21T18:22:56Z",
+         "created_at": "2016-10-21T18:22:56Z"
+      },
+      "tags": [
+         "mysql"
+      ],
+      "space_url": "/v2/spaces/21e5fdc7-5131-4743-8447-6373cf336a77",
+      "service_plan_url": "/v2/service_plans/0a3c8a4b-2f35-4c5e-9a0d-4d8c2e3d5b71",
+      "service_bindings_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_bindings",
+      "service_keys_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_keys",
+      "routes_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/routes",
+      "service_url": "/v2/services/440ce9d9-b108-4bbe-80b4-08338f3cc25b"
+   }
+}`
+
+const serviceInstanceUpdateFailedPayload = `{
+   "metadata": {
+      "guid": "8423ca96-90ad-411f-b77a-0907844949fc",
+      "url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "created_at": "2016-10-21T18:22:56Z",
+      "updated_at": "2016-10-21T18:22:56Z"
+   },
+   "entity": {
+      "name": "quotes-db",
+      "credentials": {},
+      "service_guid": "440ce9d9-b108-4bbe-80b4-08338f3cc25b",
+      "service_plan_guid": "0a3c8a4b-2f35-4c5e-9a0d-4d8c2e3d5b71",
+      "space_guid": "21e5fdc7-5131-4743-8447-6373cf336a77",
+      "gateway_data": null,
+      "dashboard_url": "https://p-mysql.system.example.com/manage/instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "type": "managed_service_instance",
+      "last_operation": {
+         "type": "update",
+         "state": "failed",
+         "description": "The plan cannot be downgraded",
+         "updated_at": "2016-10-21T18:22:56Z",
+         "created_at": "2016-10-21T18:22:56Z"
+      },
+      "tags": [
+         "mysql"
+      ],
+      "space_url": "/v2/spaces/21e5fdc7-5131-4743-8447-6373cf336a77",
+      "service_plan_url": "/v2/service_plans/0a3c8a4b-2f35-4c5e-9a0d-4d8c2e3d5b71",
+      "service_bindings_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_bindings",
+      "service_keys_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_keys",
+      "routes_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/routes",
+      "service_url": "/v2/services/440ce9d9-b108-4bbe-80b4-08338f3cc25b"
+   }
+}`
+
+const serviceInstanceDeleteInProgressPayload = `{
+   "metadata": {
+      "guid": "8423ca96-90ad-411f-b77a-0907844949fc",
+      "url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "created_at": "2016-10-21T18:22:56Z",
+      "updated_at": "2016-10-21T18:22:56Z"
+   },
+   "entity": {
+      "name": "fortunes-db",
+      "credentials": {},
+      "service_guid": "440ce9d9-b108-4bbe-80b4-08338f3cc25b",
+      "service_plan_guid": "f48419f7-4717-4706-86e4-a24973848a77",
+      "space_guid": "21e5fdc7-5131-4743-8447-6373cf336a77",
+      "gateway_data": null,
+      "dashboard_url": "https://p-mysql.system.example.com/manage/instances/8423ca96-90ad-411f-b77a-0907844949fc",
+      "type": "managed_service_instance",
+      "last_operation": {
+         "type": "delete",
+         "state": "in progress",
+         "description": "",
+         "updated_at": "2016-10-21T18:22:56Z",
+         "created_at": "2016-10-21T18:22:56Z"
+      },
+      "tags": [
+         "mysql"
+      ],
+      "space_url": "/v2/spaces/21e5fdc7-5131-4743-8447-6373cf336a77",
+      "service_plan_url": "/v2/service_plans/f48419f7-4717-4706-86e4-a24973848a77",
+      "service_bindings_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_bindings",
+      "service_keys_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/service_keys",
+      "routes_url": "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/routes",
+      "service_url": "/v2/services/440ce9d9-b108-4bbe-80b4-08338f3cc25b"
+   }
+}`
+
+const serviceInstanceParametersPayload = `{
+  "max_connections": 100,
+  "backups": {
+    "enabled": true
+  }
+}`
@@ -3,7 +3,9 @@ package cfclient
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 
 	"github.com/pkg/errors"
@@ -27,6 +29,7 @@ type RouteRequest struct {
 	DomainGuid string `json:"domain_guid"`
 	SpaceGuid  string `json:"space_guid"`
 	Host       string `json:"host,omitempty"`
+	Path       string `json:"path,omitempty"`
 }
 
 type RouteMap struct {
@@ -96,6 +99,21 @@ func (c *Client) MapRoute(routeMap RouteMap) (mr MappedRoute, err error) {
 	return mr, nil
 
 }
+
+// UnmapRoute removes the mapping between the app and the route of routeMap.
+func (c *Client) UnmapRoute(routeMap RouteMap) error {
+	requestURL := fmt.Sprintf("/v2/routes/%s/apps/%s", routeMap.RouteGUID, routeMap.AppGUID)
+	resp, err := c.DoRequest(c.NewRequest("DELETE", requestURL))
+	if err != nil {
+		return errors.Wrapf(err, "Error unmapping route %s from app %s", routeMap.RouteGUID, routeMap.AppGUID)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return errors.Errorf("Error unmapping route %s from app %s, response code: %d", routeMap.RouteGUID, routeMap.AppGUID, resp.StatusCode)
+	}
+	return nil
+}
+
 func (c *Client) ListRoutesByQuery(query url.Values) ([]Route, error) {
 	return c.fetchRoutes("/v2/routes?" + query.Encode())
 }
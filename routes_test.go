@@ -60,3 +60,22 @@ func TestCreateTcpRoute(t *testing.T) {
 
 	})
 }
+
+func TestUnmapRoute(t *testing.T) {
+	Convey("Unmap route from app", t, func() {
+		setup(MockRoute{"DELETE", "/v2/routes/311d34d1-c045-4853-845f-05132377ad7d/apps/9902530c-c634-4864-a189-71d763cb12e2", "", "", 204, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.UnmapRoute(RouteMap{
+			AppGUID:   "9902530c-c634-4864-a189-71d763cb12e2",
+			RouteGUID: "311d34d1-c045-4853-845f-05132377ad7d",
+		})
+		So(err, ShouldBeNil)
+	})
+}
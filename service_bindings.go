@@ -1,8 +1,10 @@
 package cfclient
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 
 	"github.com/pkg/errors"
@@ -102,3 +104,41 @@ func (c *Client) GetServiceBindingByGuid(guid string) (ServiceBinding, error) {
 func (c *Client) ServiceBindingByGuid(guid string) (ServiceBinding, error) {
 	return c.GetServiceBindingByGuid(guid)
 }
+
+// ServiceBindingRequest is the request body of CreateServiceBinding.
+// Parameters are passed to the service broker.
+type ServiceBindingRequest struct {
+	AppGuid             string                 `json:"app_guid"`
+	ServiceInstanceGuid string                 `json:"service_instance_guid"`
+	Name                string                 `json:"name,omitempty"`
+	Parameters          map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// CreateServiceBinding binds a service instance to an app.
+func (c *Client) CreateServiceBinding(req ServiceBindingRequest) (ServiceBinding, error) {
+	var serviceBindingResource ServiceBindingResource
+	buf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buf).Encode(req)
+	if err != nil {
+		return ServiceBinding{}, errors.Wrap(err, "Error encoding service binding request")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v2/service_bindings", buf))
+	if err != nil {
+		return ServiceBinding{}, errors.Wrap(err, "Error creating service binding")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return ServiceBinding{}, errors.Errorf("Error creating service binding, response code: %d", resp.StatusCode)
+	}
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return ServiceBinding{}, errors.Wrap(err, "Error reading service binding response body")
+	}
+	err = json.Unmarshal(resBody, &serviceBindingResource)
+	if err != nil {
+		return ServiceBinding{}, errors.Wrap(err, "Error unmarshalling service binding")
+	}
+	serviceBindingResource.Entity.Guid = serviceBindingResource.Meta.Guid
+	serviceBindingResource.Entity.c = c
+	return serviceBindingResource.Entity, nil
+}
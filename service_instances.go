@@ -1,13 +1,34 @@
 package cfclient
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+const (
+	// ServiceInstanceInProgress is the state of the last operation of a
+	// service instance while the service broker is processing it
+	ServiceInstanceInProgress = "in progress"
+	// ServiceInstanceSucceeded is the state of the last operation of a
+	// service instance which succeeded
+	ServiceInstanceSucceeded = "succeeded"
+	// ServiceInstanceFailed is the state of the last operation of a service
+	// instance which failed
+	ServiceInstanceFailed = "failed"
+)
+
+const (
+	defaultServiceInstanceTimeout  = 30 * time.Minute
+	defaultServiceInstanceInterval = 5 * time.Second
+)
+
 type ServiceInstancesResponse struct {
 	Count     int                       `json:"total_results"`
 	Pages     int                       `json:"total_pages"`
@@ -110,3 +131,205 @@ func (c *Client) GetServiceInstanceByGuid(guid string) (ServiceInstance, error)
 func (c *Client) ServiceInstanceByGuid(guid string) (ServiceInstance, error) {
 	return c.GetServiceInstanceByGuid(guid)
 }
+
+// ServiceInstanceRequest is the request body of CreateServiceInstance.
+// Parameters are passed to the service broker.
+type ServiceInstanceRequest struct {
+	Name            string                 `json:"name"`
+	SpaceGuid       string                 `json:"space_guid"`
+	ServicePlanGuid string                 `json:"service_plan_guid"`
+	Parameters      map[string]interface{} `json:"parameters,omitempty"`
+	Tags            []string               `json:"tags,omitempty"`
+	// AcceptsIncomplete lets the service broker provision the instance
+	// asynchronously, see WaitForServiceInstance.
+	AcceptsIncomplete bool `json:"-"`
+}
+
+// ServiceInstanceUpdateRequest is the request body of UpdateServiceInstance.
+// Empty fields are left unchanged.
+type ServiceInstanceUpdateRequest struct {
+	Name            string                 `json:"name,omitempty"`
+	ServicePlanGuid string                 `json:"service_plan_guid,omitempty"`
+	Parameters      map[string]interface{} `json:"parameters,omitempty"`
+	Tags            []string               `json:"tags,omitempty"`
+	// AcceptsIncomplete lets the service broker update the instance
+	// asynchronously, see WaitForServiceInstance.
+	AcceptsIncomplete bool `json:"-"`
+}
+
+// WaitForServiceInstanceOptions configures WaitForServiceInstance. Zero
+// values are replaced by their defaults.
+type WaitForServiceInstanceOptions struct {
+	// Timeout defaults to 30 minutes.
+	Timeout time.Duration
+	// Interval is the time between two polls of the instance, defaults to 5
+	// seconds.
+	Interval time.Duration
+}
+
+// ServiceInstanceOperationError is returned by WaitForServiceInstance when
+// the last operation of the service instance failed.
+type ServiceInstanceOperationError struct {
+	Guid string
+	// Operation is the type of the operation, i.e. create, update or delete.
+	Operation string
+	// Description is the failure reported by the service broker.
+	Description string
+}
+
+func (e ServiceInstanceOperationError) Error() string {
+	return fmt.Sprintf("cfclient: %s of service instance %s failed: %s", e.Operation, e.Guid, e.Description)
+}
+
+// CreateServiceInstance provisions a managed service instance. When the
+// service broker provisions it asynchronously, the instance is returned with
+// its last operation in progress.
+func (c *Client) CreateServiceInstance(req ServiceInstanceRequest) (ServiceInstance, error) {
+	buf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buf).Encode(req)
+	if err != nil {
+		return ServiceInstance{}, errors.Wrap(err, "Error encoding service instance request")
+	}
+	requestUrl := fmt.Sprintf("/v2/service_instances?accepts_incomplete=%t", req.AcceptsIncomplete)
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", requestUrl, buf))
+	if err != nil {
+		return ServiceInstance{}, errors.Wrapf(err, "Error creating service instance %s", req.Name)
+	}
+	instance, err := c.handleServiceInstanceResp(resp)
+	if err != nil {
+		return ServiceInstance{}, errors.Wrapf(err, "Error creating service instance %s", req.Name)
+	}
+	return instance, nil
+}
+
+// UpdateServiceInstance renames the service instance identified by guid,
+// changes its plan or passes new parameters to the service broker. When the
+// service broker updates it asynchronously, the instance is returned with its
+// last operation in progress.
+func (c *Client) UpdateServiceInstance(guid string, req ServiceInstanceUpdateRequest) (ServiceInstance, error) {
+	buf := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buf).Encode(req)
+	if err != nil {
+		return ServiceInstance{}, errors.Wrap(err, "Error encoding service instance request")
+	}
+	requestUrl := fmt.Sprintf("/v2/service_instances/%s?accepts_incomplete=%t", guid, req.AcceptsIncomplete)
+	resp, err := c.DoRequest(c.NewRequestWithBody("PUT", requestUrl, buf))
+	if err != nil {
+		return ServiceInstance{}, errors.Wrapf(err, "Error updating service instance %s", guid)
+	}
+	instance, err := c.handleServiceInstanceResp(resp)
+	if err != nil {
+		return ServiceInstance{}, errors.Wrapf(err, "Error updating service instance %s", guid)
+	}
+	return instance, nil
+}
+
+// DeleteServiceInstance deprovisions the service instance identified by
+// guid. recursive also deletes its bindings, keys and routes. When
+// acceptsIncomplete is set and the service broker deprovisions it
+// asynchronously, the instance is only gone once
+// WaitForServiceInstanceDeletion returns.
+func (c *Client) DeleteServiceInstance(guid string, recursive, acceptsIncomplete bool) error {
+	requestUrl := fmt.Sprintf("/v2/service_instances/%s?recursive=%t&accepts_incomplete=%t", guid, recursive, acceptsIncomplete)
+	resp, err := c.DoRequest(c.NewRequest("DELETE", requestUrl))
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting service instance %s", guid)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
+		return errors.Errorf("Error deleting service instance %s, response code: %d", guid, resp.StatusCode)
+	}
+	return nil
+}
+
+// GetServiceInstanceParameters returns the parameters the service broker
+// holds for the service instance identified by guid. Not all service brokers
+// support fetching them.
+func (c *Client) GetServiceInstanceParameters(guid string) (map[string]interface{}, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v2/service_instances/"+guid+"/parameters"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting parameters of service instance %s", guid)
+	}
+	defer resp.Body.Close()
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading service instance parameters response body")
+	}
+	var parameters map[string]interface{}
+	err = json.Unmarshal(resBody, &parameters)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error unmarshalling service instance parameters")
+	}
+	return parameters, nil
+}
+
+// WaitForServiceInstance polls the service instance identified by guid until
+// its last operation succeeded or failed. A failed operation is returned
+// along with a ServiceInstanceOperationError.
+func (c *Client) WaitForServiceInstance(guid string, opts WaitForServiceInstanceOptions) (ServiceInstance, error) {
+	return c.waitForServiceInstance(guid, opts, false)
+}
+
+// WaitForServiceInstanceDeletion polls the service instance identified by
+// guid until it is gone, or its deletion failed.
+func (c *Client) WaitForServiceInstanceDeletion(guid string, opts WaitForServiceInstanceOptions) error {
+	_, err := c.waitForServiceInstance(guid, opts, true)
+	return err
+}
+
+func (c *Client) waitForServiceInstance(guid string, opts WaitForServiceInstanceOptions, deleting bool) (ServiceInstance, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultServiceInstanceTimeout
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultServiceInstanceInterval
+	}
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		instance, err := c.GetServiceInstanceByGuid(guid)
+		if err != nil {
+			if cfErr, ok := errors.Cause(err).(CloudFoundryError); deleting && ok && cfErr.ErrorCode == "CF-ServiceInstanceNotFound" {
+				return ServiceInstance{}, nil
+			}
+			return ServiceInstance{}, err
+		}
+		switch instance.LastOperation.State {
+		case ServiceInstanceSucceeded:
+			if !deleting {
+				return instance, nil
+			}
+		case ServiceInstanceFailed:
+			return instance, ServiceInstanceOperationError{
+				Guid:        guid,
+				Operation:   instance.LastOperation.Type,
+				Description: instance.LastOperation.Description,
+			}
+		}
+		if time.Now().After(deadline) {
+			return instance, errors.Errorf("Timed out after %s waiting for service instance %s, last operation: %s %s",
+				opts.Timeout, guid, instance.LastOperation.Type, instance.LastOperation.State)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
+
+// handleServiceInstanceResp decodes the instance returned by a synchronous,
+// 201, or asynchronous, 202, operation.
+func (c *Client) handleServiceInstanceResp(resp *http.Response) (ServiceInstance, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		return ServiceInstance{}, errors.Errorf("Unexpected response code: %d", resp.StatusCode)
+	}
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return ServiceInstance{}, errors.Wrap(err, "Error reading service instance response body")
+	}
+	var sir ServiceInstanceResource
+	err = json.Unmarshal(resBody, &sir)
+	if err != nil {
+		return ServiceInstance{}, errors.Wrap(err, "Error unmarshalling service instance")
+	}
+	sir.Entity.Guid = sir.Meta.Guid
+	sir.Entity.c = c
+	return sir.Entity, nil
+}
@@ -1,7 +1,9 @@
 package cfclient
 
 import (
+	"net/http"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -69,3 +71,187 @@ func TestServiceInstanceByGuid(t *testing.T) {
 		So(service, ShouldResemble, expected)
 	})
 }
+
+func TestCreateServiceInstance(t *testing.T) {
+	Convey("Create service instance", t, func() {
+		body := `{"name":"fortunes-db","space_guid":"21e5fdc7-5131-4743-8447-6373cf336a77","service_plan_guid":"f48419f7-4717-4706-86e4-a24973848a77","parameters":{"max_connections":100},"tags":["mysql"]}`
+		setup(MockRoute{"POST", "/v2/service_instances", serviceInstanceCreatedPayload, "", http.StatusCreated, "accepts_incomplete=false", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		instance, err := client.CreateServiceInstance(ServiceInstanceRequest{
+			Name:            "fortunes-db",
+			SpaceGuid:       "21e5fdc7-5131-4743-8447-6373cf336a77",
+			ServicePlanGuid: "f48419f7-4717-4706-86e4-a24973848a77",
+			Parameters:      map[string]interface{}{"max_connections": 100},
+			Tags:            []string{"mysql"},
+		})
+		So(err, ShouldBeNil)
+		So(instance.Guid, ShouldEqual, "8423ca96-90ad-411f-b77a-0907844949fc")
+		So(instance.LastOperation.State, ShouldEqual, ServiceInstanceSucceeded)
+		So(instance.Tags, ShouldResemble, []string{"mysql"})
+	})
+
+	Convey("Create service instance asynchronously", t, func() {
+		setupMultiple([]MockRoute{
+			{"POST", "/v2/service_instances", serviceInstanceCreateInProgressPayload, "", http.StatusAccepted, "accepts_incomplete=true", nil},
+		}, t)
+		defer teardown()
+		polls := 0
+		mux.HandleFunc("/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc", func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			if polls < 2 {
+				w.Write([]byte(serviceInstanceCreateInProgressPayload))
+				return
+			}
+			w.Write([]byte(serviceInstanceCreatedPayload))
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		instance, err := client.CreateServiceInstance(ServiceInstanceRequest{
+			Name:              "fortunes-db",
+			SpaceGuid:         "21e5fdc7-5131-4743-8447-6373cf336a77",
+			ServicePlanGuid:   "f48419f7-4717-4706-86e4-a24973848a77",
+			AcceptsIncomplete: true,
+		})
+		So(err, ShouldBeNil)
+		So(instance.LastOperation.State, ShouldEqual, ServiceInstanceInProgress)
+		So(instance.LastOperation.Description, ShouldEqual, "Provisioning the database")
+
+		instance, err = client.WaitForServiceInstance(instance.Guid, WaitForServiceInstanceOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(polls, ShouldEqual, 2)
+		So(instance.LastOperation.State, ShouldEqual, ServiceInstanceSucceeded)
+	})
+}
+
+func TestUpdateServiceInstance(t *testing.T) {
+	Convey("Update service instance", t, func() {
+		body := `{"name":"quotes-db","service_plan_guid":"0a3c8a4b-2f35-4c5e-9a0d-4d8c2e3d5b71"}`
+		setupMultiple([]MockRoute{
+			{"PUT", "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc", serviceInstanceUpdateInProgressPayload, "", http.StatusAccepted, "accepts_incomplete=true", &body},
+			{"GET", "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc", serviceInstanceUpdateFailedPayload, "", http.StatusOK, "", nil},
+		}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		instance, err := client.UpdateServiceInstance("8423ca96-90ad-411f-b77a-0907844949fc", ServiceInstanceUpdateRequest{
+			Name:              "quotes-db",
+			ServicePlanGuid:   "0a3c8a4b-2f35-4c5e-9a0d-4d8c2e3d5b71",
+			AcceptsIncomplete: true,
+		})
+		So(err, ShouldBeNil)
+		So(instance.Name, ShouldEqual, "quotes-db")
+		So(instance.LastOperation.Type, ShouldEqual, "update")
+
+		instance, err = client.WaitForServiceInstance(instance.Guid, WaitForServiceInstanceOptions{Interval: time.Millisecond})
+		So(err, ShouldNotBeNil)
+		opErr, ok := err.(ServiceInstanceOperationError)
+		So(ok, ShouldBeTrue)
+		So(opErr.Operation, ShouldEqual, "update")
+		So(opErr.Description, ShouldEqual, "The plan cannot be downgraded")
+		So(instance.LastOperation.State, ShouldEqual, ServiceInstanceFailed)
+	})
+
+	Convey("Time out waiting for service instance", t, func() {
+		setup(MockRoute{"GET", "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc", serviceInstanceUpdateInProgressPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.WaitForServiceInstance("8423ca96-90ad-411f-b77a-0907844949fc", WaitForServiceInstanceOptions{Timeout: 5 * time.Millisecond, Interval: time.Millisecond})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Timed out")
+	})
+}
+
+func TestDeleteServiceInstance(t *testing.T) {
+	Convey("Delete service instance", t, func() {
+		setup(MockRoute{"DELETE", "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc", "", "", http.StatusNoContent, "recursive=true&accepts_incomplete=false", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DeleteServiceInstance("8423ca96-90ad-411f-b77a-0907844949fc", true, false)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Delete service instance asynchronously", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		polls := 0
+		mux.HandleFunc("/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "DELETE" {
+				if r.URL.RawQuery != "recursive=false&accepts_incomplete=true" {
+					t.Errorf("Unexpected delete query %s", r.URL.RawQuery)
+				}
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte(serviceInstanceDeleteInProgressPayload))
+				return
+			}
+			polls++
+			if polls < 2 {
+				w.Write([]byte(serviceInstanceDeleteInProgressPayload))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(serviceInstanceNotFoundPayload))
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DeleteServiceInstance("8423ca96-90ad-411f-b77a-0907844949fc", false, true)
+		So(err, ShouldBeNil)
+		err = client.WaitForServiceInstanceDeletion("8423ca96-90ad-411f-b77a-0907844949fc", WaitForServiceInstanceOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(polls, ShouldEqual, 2)
+
+		_, err = client.WaitForServiceInstance("8423ca96-90ad-411f-b77a-0907844949fc", WaitForServiceInstanceOptions{Interval: time.Millisecond})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestGetServiceInstanceParameters(t *testing.T) {
+	Convey("Get service instance parameters", t, func() {
+		setup(MockRoute{"GET", "/v2/service_instances/8423ca96-90ad-411f-b77a-0907844949fc/parameters", serviceInstanceParametersPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		parameters, err := client.GetServiceInstanceParameters("8423ca96-90ad-411f-b77a-0907844949fc")
+		So(err, ShouldBeNil)
+		So(parameters["max_connections"], ShouldEqual, 100)
+		So(parameters["backups"], ShouldResemble, map[string]interface{}{"enabled": true})
+	})
+}
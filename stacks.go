@@ -70,3 +70,24 @@ func (c *Client) getStacksResponse(requestUrl string) (StacksResponse, error) {
 	}
 	return stacksResp, nil
 }
+
+func (c *Client) GetStackByGuid(guid string) (Stack, error) {
+	var stackResource StacksResource
+	r := c.NewRequest("GET", "/v2/stacks/"+guid)
+	resp, err := c.DoRequest(r)
+	if err != nil {
+		return Stack{}, errors.Wrap(err, "Error requesting stack")
+	}
+	defer resp.Body.Close()
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Stack{}, errors.Wrap(err, "Error reading stack body")
+	}
+	err = json.Unmarshal(resBody, &stackResource)
+	if err != nil {
+		return Stack{}, errors.Wrap(err, "Error unmarshalling stack")
+	}
+	stackResource.Entity.Guid = stackResource.Meta.Guid
+	stackResource.Entity.c = c
+	return stackResource.Entity, nil
+}
@@ -0,0 +1,97 @@
+package cfclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// V3Link is a link to a V3 resource or action.
+type V3Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// V3Pagination describes a page of a V3 list.
+type V3Pagination struct {
+	TotalResults int     `json:"total_results"`
+	TotalPages   int     `json:"total_pages"`
+	First        V3Link  `json:"first"`
+	Last         V3Link  `json:"last"`
+	Next         *V3Link `json:"next"`
+	Previous     *V3Link `json:"previous"`
+}
+
+// V3Relationship references another V3 resource by guid.
+type V3Relationship struct {
+	GUID string `json:"guid"`
+}
+
+// V3ToOneRelationship is a relationship to at most one V3 resource, Data is
+// nil when there is none.
+type V3ToOneRelationship struct {
+	Data *V3Relationship `json:"data"`
+}
+
+// V3Checksum is the checksum of a V3 package or droplet. The value is hex
+// encoded and empty until the blob has been processed.
+type V3Checksum struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// v3Page is a page of a V3 list whose resources are decoded by the caller.
+type v3Page struct {
+	Pagination V3Pagination    `json:"pagination"`
+	Resources  json.RawMessage `json:"resources"`
+}
+
+// listV3Resources requests requestURL and the following pages, calling
+// handle with the resources of each of them.
+func (c *Client) listV3Resources(requestURL string, handle func(resources json.RawMessage) error) error {
+	for requestURL != "" {
+		resp, err := c.DoRequest(c.NewRequest("GET", requestURL))
+		if err != nil {
+			return err
+		}
+		var page v3Page
+		if err := decodeV3Response(resp, http.StatusOK, &page); err != nil {
+			return err
+		}
+		if err := handle(page.Resources); err != nil {
+			return errors.Wrap(err, "Error unmarshalling resources")
+		}
+		requestURL = ""
+		if page.Pagination.Next != nil && page.Pagination.Next.Href != "" {
+			next, err := url.Parse(page.Pagination.Next.Href)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid next page %s", page.Pagination.Next.Href)
+			}
+			requestURL = next.RequestURI()
+		}
+	}
+	return nil
+}
+
+// decodeV3Response checks the status code of a V3 response and decodes its
+// body into out, unless out is nil.
+func decodeV3Response(resp *http.Response, expectedStatus int, out interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != expectedStatus {
+		return errors.Errorf("Unexpected response code: %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "Error reading response body")
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return errors.Wrap(err, "Error unmarshalling response")
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// V3LifecycleBuildpack is the lifecycle of apps staged by buildpacks
+	V3LifecycleBuildpack = "buildpack"
+	// V3LifecycleDocker is the lifecycle of apps running a docker image
+	V3LifecycleDocker = "docker"
+)
+
+// V3App is a V3 app of either lifecycle.
+type V3App struct {
+	GUID          string                         `json:"guid"`
+	Name          string                         `json:"name"`
+	State         string                         `json:"state"`
+	CreatedAt     time.Time                      `json:"created_at"`
+	UpdatedAt     time.Time                      `json:"updated_at"`
+	Lifecycle     V3Lifecycle                    `json:"lifecycle"`
+	Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	Metadata      V3Metadata                     `json:"metadata"`
+	Links         map[string]V3Link              `json:"links"`
+}
+
+// V3Lifecycle tells how an app is staged. Buildpacks and Stack only apply to
+// the buildpack lifecycle.
+type V3Lifecycle struct {
+	Type string          `json:"type"`
+	Data V3LifecycleData `json:"data"`
+}
+
+// V3LifecycleData holds the buildpack lifecycle settings. Empty Buildpacks
+// lets the platform detect the buildpack.
+type V3LifecycleData struct {
+	Buildpacks []string `json:"buildpacks,omitempty"`
+	Stack      string   `json:"stack,omitempty"`
+}
+
+// CreateV3AppRequest describes a V3 app to create. Lifecycle defaults to the
+// buildpack lifecycle with detected buildpacks and the default stack.
+type CreateV3AppRequest struct {
+	Name                 string
+	SpaceGUID            string
+	EnvironmentVariables map[string]string
+	Lifecycle            *V3Lifecycle
+}
+
+// UpdateV3AppRequest describes the changes to a V3 app, empty fields are
+// left unchanged.
+type UpdateV3AppRequest struct {
+	Name      string       `json:"name,omitempty"`
+	Lifecycle *V3Lifecycle `json:"lifecycle,omitempty"`
+}
+
+// CreateV3App creates a V3 app, see CreateV3AppRequest.
+func (c *Client) CreateV3App(r CreateV3AppRequest) (*V3App, error) {
+	req := struct {
+		Name                 string                         `json:"name"`
+		EnvironmentVariables map[string]string              `json:"environment_variables,omitempty"`
+		Lifecycle            *V3Lifecycle                   `json:"lifecycle,omitempty"`
+		Relationships        map[string]V3ToOneRelationship `json:"relationships"`
+	}{
+		Name:                 r.Name,
+		EnvironmentVariables: r.EnvironmentVariables,
+		Lifecycle:            r.Lifecycle,
+		Relationships: map[string]V3ToOneRelationship{
+			"space": {Data: &V3Relationship{GUID: r.SpaceGUID}},
+		},
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding app")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v3/apps", buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error creating app %s", r.Name)
+	}
+	var app V3App
+	if err := decodeV3Response(resp, http.StatusCreated, &app); err != nil {
+		return nil, errors.Wrapf(err, "Error creating app %s", r.Name)
+	}
+	return &app, nil
+}
+
+// GetV3App returns the V3 app identified by guid.
+func (c *Client) GetV3App(guid string) (*V3App, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/apps/"+guid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting app %s", guid)
+	}
+	var app V3App
+	if err := decodeV3Response(resp, http.StatusOK, &app); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting app %s", guid)
+	}
+	return &app, nil
+}
+
+// ListV3Apps returns all the V3 apps the user has access to.
+func (c *Client) ListV3Apps() ([]V3App, error) {
+	return c.ListV3AppsByQuery(url.Values{})
+}
+
+// ListV3AppsByQuery returns the V3 apps matching query, which supports the
+// names, guids, space_guids, organization_guids and stacks filters as comma
+// separated lists, and label_selector, see LabelSelector.Query.
+func (c *Client) ListV3AppsByQuery(query url.Values) ([]V3App, error) {
+	var apps []V3App
+	requestURL := "/v3/apps"
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	err := c.listV3Resources(requestURL, func(resources json.RawMessage) error {
+		var page []V3App
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		apps = append(apps, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error requesting apps")
+	}
+	return apps, nil
+}
+
+// UpdateV3App updates the V3 app identified by guid, see UpdateV3AppRequest.
+func (c *Client) UpdateV3App(guid string, r UpdateV3AppRequest) (*V3App, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(r); err != nil {
+		return nil, errors.Wrap(err, "Error encoding app")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("PATCH", "/v3/apps/"+guid, buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error updating app %s", guid)
+	}
+	var app V3App
+	if err := decodeV3Response(resp, http.StatusOK, &app); err != nil {
+		return nil, errors.Wrapf(err, "Error updating app %s", guid)
+	}
+	return &app, nil
+}
+
+// DeleteV3App deletes the V3 app identified by guid, and waits for the job
+// the deletion enqueued.
+func (c *Client) DeleteV3App(guid string) error {
+	resp, err := c.DoRequest(c.NewRequest("DELETE", "/v3/apps/"+guid))
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting app %s", guid)
+	}
+	location := resp.Header.Get("Location")
+	if err := decodeV3Response(resp, http.StatusAccepted, nil); err != nil {
+		return errors.Wrapf(err, "Error deleting app %s", guid)
+	}
+	return c.waitForJobLocation(location)
+}
+
+// StartV3App starts the V3 app identified by appGUID. It is not named
+// StartApp like StopApp and RestartApp since that name is taken by the
+// deprecated call returning a V3DockerAppResponse.
+func (c *Client) StartV3App(appGUID string) (*V3App, error) {
+	return c.v3AppAction(appGUID, "start")
+}
+
+// StopApp stops the V3 app identified by appGUID.
+func (c *Client) StopApp(appGUID string) (*V3App, error) {
+	return c.v3AppAction(appGUID, "stop")
+}
+
+// RestartApp stops and starts the V3 app identified by appGUID, which
+// causes downtime.
+func (c *Client) RestartApp(appGUID string) (*V3App, error) {
+	return c.v3AppAction(appGUID, "restart")
+}
+
+func (c *Client) v3AppAction(guid, action string) (*V3App, error) {
+	resp, err := c.DoRequest(c.NewRequest("POST", "/v3/apps/"+guid+"/actions/"+action))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting %s of app %s", action, guid)
+	}
+	var app V3App
+	if err := decodeV3Response(resp, http.StatusOK, &app); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting %s of app %s", action, guid)
+	}
+	return &app, nil
+}
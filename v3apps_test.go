@@ -0,0 +1,206 @@
+package cfclient
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateV3App(t *testing.T) {
+	Convey("Create V3 buildpack app", t, func() {
+		body := `{"name":"my_app","lifecycle":{"type":"buildpack","data":{"buildpacks":["java_buildpack"],"stack":"cflinuxfs2"}},"relationships":{"space":{"data":{"guid":"2f35885d-0c9d-4423-83ad-fd05066f8576"}}}}`
+		setup(MockRoute{"POST", "/v3/apps", v3AppStartedPayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.CreateV3App(CreateV3AppRequest{
+			Name:      "my_app",
+			SpaceGUID: "2f35885d-0c9d-4423-83ad-fd05066f8576",
+			Lifecycle: &V3Lifecycle{
+				Type: V3LifecycleBuildpack,
+				Data: V3LifecycleData{Buildpacks: []string{"java_buildpack"}, Stack: "cflinuxfs2"},
+			},
+		})
+		So(err, ShouldBeNil)
+		So(app.GUID, ShouldEqual, "1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(app.Lifecycle.Data.Buildpacks, ShouldResemble, []string{"java_buildpack"})
+		So(app.Relationships["space"].Data.GUID, ShouldEqual, "2f35885d-0c9d-4423-83ad-fd05066f8576")
+	})
+
+	Convey("Create V3 docker app", t, func() {
+		body := `{"name":"docker_app","environment_variables":{"PORT":"8080"},"lifecycle":{"type":"docker","data":{}},"relationships":{"space":{"data":{"guid":"2f35885d-0c9d-4423-83ad-fd05066f8576"}}}}`
+		setup(MockRoute{"POST", "/v3/apps", v3DockerAppStoppedPayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.CreateV3App(CreateV3AppRequest{
+			Name:                 "docker_app",
+			SpaceGUID:            "2f35885d-0c9d-4423-83ad-fd05066f8576",
+			EnvironmentVariables: map[string]string{"PORT": "8080"},
+			Lifecycle:            &V3Lifecycle{Type: V3LifecycleDocker},
+		})
+		So(err, ShouldBeNil)
+		So(app.Lifecycle.Type, ShouldEqual, V3LifecycleDocker)
+		So(app.State, ShouldEqual, AppStopped)
+	})
+}
+
+func TestGetV3App(t *testing.T) {
+	Convey("Get V3 app", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446", v3AppStartedPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.GetV3App("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(app.Name, ShouldEqual, "my_app")
+		So(app.State, ShouldEqual, AppStarted)
+		So(app.Lifecycle.Data.Stack, ShouldEqual, "cflinuxfs2")
+		So(app.Links["self"].Href, ShouldEqual, "https://api.example.org/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446")
+	})
+}
+
+func TestListV3Apps(t *testing.T) {
+	Convey("List V3 apps", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		mux.HandleFunc("/v3/apps", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("space_guids") != "2f35885d-0c9d-4423-83ad-fd05066f8576" {
+				t.Errorf("Unexpected query %s", r.URL.RawQuery)
+			}
+			if r.URL.Query().Get("page") == "2" {
+				w.Write([]byte(v3AppsSecondPagePayload))
+				return
+			}
+			w.Write([]byte(v3AppsFirstPagePayload))
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		query := url.Values{}
+		query.Set("space_guids", "2f35885d-0c9d-4423-83ad-fd05066f8576")
+		apps, err := client.ListV3AppsByQuery(query)
+		So(err, ShouldBeNil)
+		So(len(apps), ShouldEqual, 2)
+		So(apps[0].Name, ShouldEqual, "my_app")
+		So(apps[1].Name, ShouldEqual, "docker_app")
+	})
+}
+
+func TestUpdateV3App(t *testing.T) {
+	Convey("Update V3 app", t, func() {
+		body := `{"name":"my_app"}`
+		setup(MockRoute{"PATCH", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446", v3AppStartedPayload, "", http.StatusOK, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.UpdateV3App("1cb006ee-fb05-47e1-b541-c34179ddc446", UpdateV3AppRequest{Name: "my_app"})
+		So(err, ShouldBeNil)
+		So(app.Name, ShouldEqual, "my_app")
+	})
+}
+
+func TestDeleteV3App(t *testing.T) {
+	Convey("Delete V3 app", t, func() {
+		setup(MockRoute{"DELETE", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446", "", "", http.StatusAccepted, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DeleteV3App("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Delete V3 app and wait for the job", t, func() {
+		setup(MockRoute{"GET", "/v3/jobs/c33a5caf-77e0-4d6e-b587-5555d339bc9a", v3AppDeleteJobFailedPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		mux.HandleFunc("/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", server.URL+"/v3/jobs/c33a5caf-77e0-4d6e-b587-5555d339bc9a")
+			w.WriteHeader(http.StatusAccepted)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DeleteV3App("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldNotBeNil)
+		_, ok := err.(JobFailedError)
+		So(ok, ShouldBeTrue)
+	})
+}
+
+func TestV3AppActions(t *testing.T) {
+	Convey("Start, stop and restart V3 apps", t, func() {
+		setupMultiple([]MockRoute{
+			{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/actions/start", v3AppStartedPayload, "", http.StatusOK, "", nil},
+			{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/actions/restart", v3AppStartedPayload, "", http.StatusOK, "", nil},
+			{"POST", "/v3/apps/02b4ec9b-94c7-4468-9c23-4e906191a0f8/actions/stop", v3DockerAppStoppedPayload, "", http.StatusOK, "", nil},
+		}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		app, err := client.StartV3App("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(app.State, ShouldEqual, AppStarted)
+
+		app, err = client.RestartApp("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(app.State, ShouldEqual, AppStarted)
+
+		app, err = client.StopApp("02b4ec9b-94c7-4468-9c23-4e906191a0f8")
+		So(err, ShouldBeNil)
+		So(app.State, ShouldEqual, AppStopped)
+	})
+
+	Convey("Start V3 app with the deprecated call", t, func() {
+		setup(MockRoute{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/actions/start", v3AppStartedPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.StartApp("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+	})
+}
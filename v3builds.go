@@ -0,0 +1,179 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// V3BuildStaging is the state of builds being staged
+	V3BuildStaging = "STAGING"
+	// V3BuildStaged is the state of builds which produced a droplet
+	V3BuildStaged = "STAGED"
+	// V3BuildFailed is the state of builds whose staging failed
+	V3BuildFailed = "FAILED"
+)
+
+const (
+	defaultBuildTimeout  = 15 * time.Minute
+	defaultBuildInterval = 2 * time.Second
+)
+
+// V3Build is the staging of a V3 package into a droplet.
+type V3Build struct {
+	GUID              string         `json:"guid"`
+	State             string         `json:"state"`
+	StagingMemoryInMB int            `json:"staging_memory_in_mb"`
+	StagingDiskInMB   int            `json:"staging_disk_in_mb"`
+	Error             string         `json:"error"`
+	Lifecycle         V3Lifecycle    `json:"lifecycle"`
+	Package           V3Relationship `json:"package"`
+	// Droplet is nil until the build is STAGED.
+	Droplet   *V3Relationship   `json:"droplet"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  V3Metadata        `json:"metadata"`
+	Links     map[string]V3Link `json:"links"`
+}
+
+// CreateV3BuildRequest describes a V3 build to create. Lifecycle and the
+// staging limits default to the ones of the app.
+type CreateV3BuildRequest struct {
+	PackageGUID       string
+	Lifecycle         *V3Lifecycle
+	StagingMemoryInMB int
+	StagingDiskInMB   int
+}
+
+// WaitForBuildOptions configures WaitForBuild. Zero values are replaced by
+// their defaults.
+type WaitForBuildOptions struct {
+	// Timeout defaults to 15 minutes.
+	Timeout time.Duration
+	// Interval is the time between two polls of the build, defaults to 2
+	// seconds.
+	Interval time.Duration
+}
+
+// StagingError is returned by WaitForBuild when staging failed. It carries
+// the settings the build was staged with to help telling why.
+type StagingError struct {
+	BuildGUID   string
+	PackageGUID string
+	// Message is the error reported by the Cloud Controller, e.g.
+	// "StagingError - Staging error: staging failed".
+	Message           string
+	StagingMemoryInMB int
+	StagingDiskInMB   int
+	Lifecycle         V3Lifecycle
+}
+
+func (e StagingError) Error() string {
+	return fmt.Sprintf("cfclient: build %s failed (%s lifecycle, %d MB memory, %d MB disk): %s",
+		e.BuildGUID, e.Lifecycle.Type, e.StagingMemoryInMB, e.StagingDiskInMB, e.Message)
+}
+
+// CreateV3Build starts staging a package, see CreateV3BuildRequest.
+func (c *Client) CreateV3Build(r CreateV3BuildRequest) (*V3Build, error) {
+	req := struct {
+		Package           V3Relationship `json:"package"`
+		Lifecycle         *V3Lifecycle   `json:"lifecycle,omitempty"`
+		StagingMemoryInMB int            `json:"staging_memory_in_mb,omitempty"`
+		StagingDiskInMB   int            `json:"staging_disk_in_mb,omitempty"`
+	}{
+		Package:           V3Relationship{GUID: r.PackageGUID},
+		Lifecycle:         r.Lifecycle,
+		StagingMemoryInMB: r.StagingMemoryInMB,
+		StagingDiskInMB:   r.StagingDiskInMB,
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding build")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v3/builds", buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error creating build of package %s", r.PackageGUID)
+	}
+	var build V3Build
+	if err := decodeV3Response(resp, http.StatusCreated, &build); err != nil {
+		return nil, errors.Wrapf(err, "Error creating build of package %s", r.PackageGUID)
+	}
+	return &build, nil
+}
+
+// GetV3Build returns the V3 build identified by guid.
+func (c *Client) GetV3Build(guid string) (*V3Build, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/builds/"+guid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting build %s", guid)
+	}
+	var build V3Build
+	if err := decodeV3Response(resp, http.StatusOK, &build); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting build %s", guid)
+	}
+	return &build, nil
+}
+
+// WaitForBuild polls the build identified by guid until it is STAGED or
+// FAILED. A failed build is returned along with a StagingError.
+func (c *Client) WaitForBuild(guid string, opts WaitForBuildOptions) (*V3Build, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultBuildTimeout
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultBuildInterval
+	}
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		build, err := c.GetV3Build(guid)
+		if err != nil {
+			return nil, err
+		}
+		switch build.State {
+		case V3BuildStaged:
+			return build, nil
+		case V3BuildFailed:
+			return build, StagingError{
+				BuildGUID:         build.GUID,
+				PackageGUID:       build.Package.GUID,
+				Message:           build.Error,
+				StagingMemoryInMB: build.StagingMemoryInMB,
+				StagingDiskInMB:   build.StagingDiskInMB,
+				Lifecycle:         build.Lifecycle,
+			}
+		}
+		if time.Now().After(deadline) {
+			return build, errors.Errorf("Timed out after %s waiting for build %s, state: %s", opts.Timeout, guid, build.State)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
+
+// StageApp stages the package identified by pkgGuid of the app identified by
+// appGuid with the settings of the app, and waits for the build with the
+// default WaitForBuildOptions. The package must belong to the app. The
+// droplet of the returned build still has to be assigned to the app.
+func (c *Client) StageApp(appGuid, pkgGuid string) (*V3Build, error) {
+	pkg, err := c.GetV3Package(pkgGuid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error staging app %s", appGuid)
+	}
+	if !strings.HasSuffix(pkg.Links["app"].Href, "/v3/apps/"+appGuid) {
+		return nil, errors.Errorf("Error staging app %s, package %s belongs to another app", appGuid, pkgGuid)
+	}
+	build, err := c.CreateV3Build(CreateV3BuildRequest{PackageGUID: pkgGuid})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error staging app %s", appGuid)
+	}
+	build, err = c.WaitForBuild(build.GUID, WaitForBuildOptions{})
+	if _, failed := err.(StagingError); err != nil && !failed {
+		return build, errors.Wrapf(err, "Error staging app %s", appGuid)
+	}
+	return build, err
+}
@@ -0,0 +1,157 @@
+package cfclient
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateV3Build(t *testing.T) {
+	Convey("Create V3 build", t, func() {
+		body := `{"package":{"guid":"44f7c078-0934-470f-9883-4fcddc5b8f13"},"staging_memory_in_mb":1024}`
+		setup(MockRoute{"POST", "/v3/builds", v3BuildStagingPayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		build, err := client.CreateV3Build(CreateV3BuildRequest{
+			PackageGUID:       "44f7c078-0934-470f-9883-4fcddc5b8f13",
+			StagingMemoryInMB: 1024,
+		})
+		So(err, ShouldBeNil)
+		So(build.GUID, ShouldEqual, "585bc3c1-3743-497d-88b0-403ad6b56d16")
+		So(build.State, ShouldEqual, V3BuildStaging)
+		So(build.Droplet, ShouldBeNil)
+		So(build.Lifecycle.Data.Buildpacks, ShouldResemble, []string{"ruby_buildpack"})
+	})
+}
+
+func TestWaitForBuild(t *testing.T) {
+	Convey("Wait for build to be staged", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		var polls int32
+		mux.HandleFunc("/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16", func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&polls, 1) < 3 {
+				fmt.Fprint(w, v3BuildStagingPayload)
+				return
+			}
+			fmt.Fprint(w, v3BuildStagedPayload)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		build, err := client.WaitForBuild("585bc3c1-3743-497d-88b0-403ad6b56d16", WaitForBuildOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(atomic.LoadInt32(&polls), ShouldEqual, 3)
+		So(build.State, ShouldEqual, V3BuildStaged)
+		So(build.Droplet.GUID, ShouldEqual, "be8a6d4f-a2c0-4b8e-a5d1-5c8a8d0b1e39")
+	})
+
+	Convey("Wait for failed build", t, func() {
+		setup(MockRoute{"GET", "/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16", v3BuildFailedPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		build, err := client.WaitForBuild("585bc3c1-3743-497d-88b0-403ad6b56d16", WaitForBuildOptions{})
+		So(err, ShouldNotBeNil)
+		So(build.State, ShouldEqual, V3BuildFailed)
+		stagingErr, ok := err.(StagingError)
+		So(ok, ShouldBeTrue)
+		So(stagingErr.Message, ShouldEqual, "StagingError - Staging error: staging failed")
+		So(stagingErr.PackageGUID, ShouldEqual, "44f7c078-0934-470f-9883-4fcddc5b8f13")
+		So(stagingErr.StagingDiskInMB, ShouldEqual, 4096)
+		So(stagingErr.Lifecycle.Data.Stack, ShouldEqual, "cflinuxfs2")
+		So(err.Error(), ShouldContainSubstring, "staging failed")
+	})
+
+	Convey("Wait for build times out", t, func() {
+		setup(MockRoute{"GET", "/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16", v3BuildStagingPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.WaitForBuild("585bc3c1-3743-497d-88b0-403ad6b56d16", WaitForBuildOptions{Timeout: time.Millisecond, Interval: time.Millisecond})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Timed out")
+	})
+}
+
+func TestStageApp(t *testing.T) {
+	Convey("Stage app", t, func() {
+		body := `{"package":{"guid":"44f7c078-0934-470f-9883-4fcddc5b8f13"}}`
+		mocks := []MockRoute{
+			{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageReadyPayload, "", http.StatusOK, "", nil},
+			{"POST", "/v3/builds", v3BuildStagingPayload, "", http.StatusCreated, "", &body},
+			{"GET", "/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16", v3BuildStagedPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		build, err := client.StageApp("1cb006ee-fb05-47e1-b541-c34179ddc446", "44f7c078-0934-470f-9883-4fcddc5b8f13")
+		So(err, ShouldBeNil)
+		So(build.Droplet.GUID, ShouldEqual, "be8a6d4f-a2c0-4b8e-a5d1-5c8a8d0b1e39")
+	})
+
+	Convey("Stage app with failed build", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageReadyPayload, "", http.StatusOK, "", nil},
+			{"POST", "/v3/builds", v3BuildStagingPayload, "", http.StatusCreated, "", nil},
+			{"GET", "/v3/builds/585bc3c1-3743-497d-88b0-403ad6b56d16", v3BuildFailedPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.StageApp("1cb006ee-fb05-47e1-b541-c34179ddc446", "44f7c078-0934-470f-9883-4fcddc5b8f13")
+		_, ok := err.(StagingError)
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("Stage app with the package of another app", t, func() {
+		setup(MockRoute{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageReadyPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.StageApp("02b4ec9b-94c7-4468-9c23-4e906191a0f8", "44f7c078-0934-470f-9883-4fcddc5b8f13")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "belongs to another app")
+	})
+}
@@ -0,0 +1,283 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	// V3DeploymentRolling replaces the instances a few at a time
+	V3DeploymentRolling = "rolling"
+	// V3DeploymentCanary starts a single instance of the new droplet and
+	// pauses until the deployment is continued
+	V3DeploymentCanary = "canary"
+)
+
+const (
+	// V3DeploymentActive is the status value of deployments in progress
+	V3DeploymentActive = "ACTIVE"
+	// V3DeploymentFinalized is the status value of deployments which ended
+	V3DeploymentFinalized = "FINALIZED"
+)
+
+const (
+	// V3DeploymentDeploying is the status reason of active deployments
+	// replacing instances
+	V3DeploymentDeploying = "DEPLOYING"
+	// V3DeploymentPaused is the status reason of canary deployments waiting
+	// to be continued
+	V3DeploymentPaused = "PAUSED"
+	// V3DeploymentCanceling is the status reason of active deployments being
+	// rolled back
+	V3DeploymentCanceling = "CANCELING"
+	// V3DeploymentDeployed is the status reason of deployments which
+	// completed
+	V3DeploymentDeployed = "DEPLOYED"
+	// V3DeploymentCanceled is the status reason of deployments which were
+	// rolled back
+	V3DeploymentCanceled = "CANCELED"
+	// V3DeploymentSuperseded is the status reason of deployments replaced by
+	// a newer one
+	V3DeploymentSuperseded = "SUPERSEDED"
+)
+
+const defaultDeploymentWatchInterval = 2 * time.Second
+
+// V3Deployment replaces the instances of the web process of a V3 app with
+// instances running another droplet, without downtime.
+type V3Deployment struct {
+	GUID            string                         `json:"guid"`
+	Status          V3DeploymentStatus             `json:"status"`
+	Strategy        string                         `json:"strategy"`
+	Options         V3DeploymentOptions            `json:"options"`
+	Droplet         V3Relationship                 `json:"droplet"`
+	PreviousDroplet V3Relationship                 `json:"previous_droplet"`
+	NewProcesses    []V3DeploymentProcess          `json:"new_processes"`
+	Revision        *V3DeploymentRevision          `json:"revision"`
+	CreatedAt       time.Time                      `json:"created_at"`
+	UpdatedAt       time.Time                      `json:"updated_at"`
+	Relationships   map[string]V3ToOneRelationship `json:"relationships"`
+	Metadata        V3Metadata                     `json:"metadata"`
+	Links           map[string]V3Link              `json:"links"`
+}
+
+// V3DeploymentStatus is the progress of a deployment. Value is either
+// V3DeploymentActive or V3DeploymentFinalized and Reason tells why.
+type V3DeploymentStatus struct {
+	Value   string                    `json:"value"`
+	Reason  string                    `json:"reason"`
+	Details V3DeploymentStatusDetails `json:"details"`
+}
+
+// V3DeploymentStatusDetails holds the timestamps reported along the status,
+// empty until they happened.
+type V3DeploymentStatusDetails struct {
+	LastSuccessfulHealthcheck string `json:"last_successful_healthcheck"`
+	LastStatusChange          string `json:"last_status_change"`
+}
+
+// V3DeploymentOptions tunes the deployment strategy.
+type V3DeploymentOptions struct {
+	// MaxInFlight is the number of instances replaced at a time.
+	MaxInFlight int `json:"max_in_flight,omitempty"`
+	// Canary only applies to V3DeploymentCanary.
+	Canary *V3CanaryOptions `json:"canary,omitempty"`
+}
+
+// V3CanaryOptions lists the steps of a canary deployment, the deployment
+// pauses after each of them.
+type V3CanaryOptions struct {
+	Steps []V3CanaryStep `json:"steps"`
+}
+
+// V3CanaryStep is a step of a canary deployment, InstanceWeight being the
+// percentage of instances running the new droplet.
+type V3CanaryStep struct {
+	InstanceWeight int `json:"instance_weight"`
+}
+
+// V3DeploymentProcess is a process created by a deployment.
+type V3DeploymentProcess struct {
+	GUID string `json:"guid"`
+	Type string `json:"type"`
+}
+
+// V3DeploymentRevision is the revision deployed.
+type V3DeploymentRevision struct {
+	GUID    string `json:"guid"`
+	Version int    `json:"version"`
+}
+
+// CreateV3DeploymentRequest describes a V3 deployment to create. It deploys
+// either DropletGUID or RevisionGUID, or the current droplet of the app when
+// both are empty. Strategy defaults to V3DeploymentRolling.
+type CreateV3DeploymentRequest struct {
+	AppGUID      string
+	DropletGUID  string
+	RevisionGUID string
+	Strategy     string
+	Options      V3DeploymentOptions
+}
+
+// DeploymentTransition is sent by WatchDeployment when the status reason of
+// a deployment changes. Transitions which could not be observed only carry
+// Err.
+type DeploymentTransition struct {
+	Deployment *V3Deployment
+	// PreviousReason is empty for the first transition.
+	PreviousReason string
+	Reason         string
+	Err            error
+}
+
+// CreateV3Deployment starts a deployment, see CreateV3DeploymentRequest.
+func (c *Client) CreateV3Deployment(r CreateV3DeploymentRequest) (*V3Deployment, error) {
+	req := struct {
+		Droplet       *V3Relationship                `json:"droplet,omitempty"`
+		Revision      *V3Relationship                `json:"revision,omitempty"`
+		Strategy      string                         `json:"strategy,omitempty"`
+		Options       *V3DeploymentOptions           `json:"options,omitempty"`
+		Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	}{
+		Strategy: r.Strategy,
+		Relationships: map[string]V3ToOneRelationship{
+			"app": {Data: &V3Relationship{GUID: r.AppGUID}},
+		},
+	}
+	if r.DropletGUID != "" {
+		req.Droplet = &V3Relationship{GUID: r.DropletGUID}
+	}
+	if r.RevisionGUID != "" {
+		req.Revision = &V3Relationship{GUID: r.RevisionGUID}
+	}
+	if r.Options.MaxInFlight > 0 || r.Options.Canary != nil {
+		req.Options = &r.Options
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding deployment")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v3/deployments", buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error creating deployment of app %s", r.AppGUID)
+	}
+	var deployment V3Deployment
+	if err := decodeV3Response(resp, http.StatusCreated, &deployment); err != nil {
+		return nil, errors.Wrapf(err, "Error creating deployment of app %s", r.AppGUID)
+	}
+	return &deployment, nil
+}
+
+// GetV3Deployment returns the V3 deployment identified by guid.
+func (c *Client) GetV3Deployment(guid string) (*V3Deployment, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/deployments/"+guid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting deployment %s", guid)
+	}
+	var deployment V3Deployment
+	if err := decodeV3Response(resp, http.StatusOK, &deployment); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting deployment %s", guid)
+	}
+	return &deployment, nil
+}
+
+// ListV3DeploymentsByApp returns the deployments of the V3 app identified by
+// appGUID. query supports the status_values and status_reasons filters as
+// comma separated lists, e.g. status_values=ACTIVE.
+func (c *Client) ListV3DeploymentsByApp(appGUID string, query url.Values) ([]V3Deployment, error) {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("app_guids", appGUID)
+	var deployments []V3Deployment
+	err := c.listV3Resources("/v3/deployments?"+q.Encode(), func(resources json.RawMessage) error {
+		var page []V3Deployment
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		deployments = append(deployments, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting deployments of app %s", appGUID)
+	}
+	return deployments, nil
+}
+
+// ContinueV3Deployment resumes the PAUSED canary deployment identified by
+// guid.
+func (c *Client) ContinueV3Deployment(guid string) error {
+	return c.v3DeploymentAction(guid, "continue")
+}
+
+// CancelV3Deployment rolls back the deployment identified by guid to the
+// previous droplet.
+func (c *Client) CancelV3Deployment(guid string) error {
+	return c.v3DeploymentAction(guid, "cancel")
+}
+
+func (c *Client) v3DeploymentAction(guid, action string) error {
+	resp, err := c.DoRequest(c.NewRequest("POST", "/v3/deployments/"+guid+"/actions/"+action))
+	if err != nil {
+		return errors.Wrapf(err, "Error requesting %s of deployment %s", action, guid)
+	}
+	if err := decodeV3Response(resp, http.StatusOK, nil); err != nil {
+		return errors.Wrapf(err, "Error requesting %s of deployment %s", action, guid)
+	}
+	return nil
+}
+
+// WatchDeployment polls the deployment identified by guid every interval,
+// defaulting to 2 seconds, and sends a transition whenever its status reason
+// changes, starting with the current one. The channel is closed once the
+// deployment is FINALIZED, e.g. DEPLOYED or CANCELED, or when ctx is done.
+// Paused canary deployments are watched until continued or canceled.
+func (c *Client) WatchDeployment(ctx context.Context, guid string, interval time.Duration) <-chan DeploymentTransition {
+	if interval <= 0 {
+		interval = defaultDeploymentWatchInterval
+	}
+	transitions := make(chan DeploymentTransition)
+	go func() {
+		defer close(transitions)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var reason string
+		for {
+			deployment, err := c.GetV3Deployment(guid)
+			var transition *DeploymentTransition
+			if err != nil {
+				transition = &DeploymentTransition{Err: err}
+			} else if deployment.Status.Reason != reason {
+				transition = &DeploymentTransition{
+					Deployment:     deployment,
+					PreviousReason: reason,
+					Reason:         deployment.Status.Reason,
+				}
+				reason = deployment.Status.Reason
+			}
+			if transition != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case transitions <- *transition:
+				}
+			}
+			if err == nil && deployment.Status.Value == V3DeploymentFinalized {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return transitions
+}
@@ -0,0 +1,188 @@
+package cfclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+)
+
+func TestCreateV3Deployment(t *testing.T) {
+	Convey("Create rolling V3 deployment of a droplet", t, func() {
+		body := `{"droplet":{"guid":"44ccfa61-dbcf-4a0d-82fe-f668e9d2a962"},"relationships":{"app":{"data":{"guid":"1cb006ee-fb05-47e1-b541-c34179ddc446"}}}}`
+		setup(MockRoute{"POST", "/v3/deployments", v3DeploymentDeployingPayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		deployment, err := client.CreateV3Deployment(CreateV3DeploymentRequest{
+			AppGUID:     "1cb006ee-fb05-47e1-b541-c34179ddc446",
+			DropletGUID: "44ccfa61-dbcf-4a0d-82fe-f668e9d2a962",
+		})
+		So(err, ShouldBeNil)
+		So(deployment.GUID, ShouldEqual, "59c3d133-2b83-46f3-960e-7765a129aea4")
+		So(deployment.Status.Value, ShouldEqual, V3DeploymentActive)
+		So(deployment.Status.Reason, ShouldEqual, V3DeploymentDeploying)
+		So(deployment.Strategy, ShouldEqual, V3DeploymentRolling)
+		So(deployment.PreviousDroplet.GUID, ShouldEqual, "cc6bc315-bd06-49ce-92c2-bc3ad45268c2")
+		So(deployment.NewProcesses[0].Type, ShouldEqual, "web")
+		So(deployment.Revision.Version, ShouldEqual, 1)
+	})
+
+	Convey("Create canary V3 deployment of a revision", t, func() {
+		body := `{"revision":{"guid":"56126cba-656a-4eba-a81e-7e9951b2df57"},"strategy":"canary","options":{"max_in_flight":2,"canary":{"steps":[{"instance_weight":20}]}},"relationships":{"app":{"data":{"guid":"1cb006ee-fb05-47e1-b541-c34179ddc446"}}}}`
+		setup(MockRoute{"POST", "/v3/deployments", v3CanaryDeploymentPausedPayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		deployment, err := client.CreateV3Deployment(CreateV3DeploymentRequest{
+			AppGUID:      "1cb006ee-fb05-47e1-b541-c34179ddc446",
+			RevisionGUID: "56126cba-656a-4eba-a81e-7e9951b2df57",
+			Strategy:     V3DeploymentCanary,
+			Options: V3DeploymentOptions{
+				MaxInFlight: 2,
+				Canary:      &V3CanaryOptions{Steps: []V3CanaryStep{{InstanceWeight: 20}}},
+			},
+		})
+		So(err, ShouldBeNil)
+		So(deployment.Status.Reason, ShouldEqual, V3DeploymentPaused)
+		So(deployment.Options.Canary.Steps[0].InstanceWeight, ShouldEqual, 20)
+	})
+}
+
+func TestListV3DeploymentsByApp(t *testing.T) {
+	Convey("List active V3 deployments of an app", t, func() {
+		setup(MockRoute{"GET", "/v3/deployments", v3DeploymentsPayload, "", http.StatusOK, "app_guids=1cb006ee-fb05-47e1-b541-c34179ddc446&status_values=ACTIVE", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		query := url.Values{"status_values": {V3DeploymentActive}}
+		deployments, err := client.ListV3DeploymentsByApp("1cb006ee-fb05-47e1-b541-c34179ddc446", query)
+		So(err, ShouldBeNil)
+		So(len(deployments), ShouldEqual, 1)
+		So(deployments[0].Status.Reason, ShouldEqual, V3DeploymentDeploying)
+		So(query.Get("app_guids"), ShouldEqual, "")
+	})
+}
+
+func TestV3DeploymentActions(t *testing.T) {
+	Convey("Continue and cancel V3 deployment", t, func() {
+		mocks := []MockRoute{
+			{"POST", "/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4/actions/continue", "", "", http.StatusOK, "", nil},
+			{"POST", "/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4/actions/cancel", "", "", http.StatusUnprocessableEntity, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.ContinueV3Deployment("59c3d133-2b83-46f3-960e-7765a129aea4")
+		So(err, ShouldBeNil)
+
+		err = client.CancelV3Deployment("59c3d133-2b83-46f3-960e-7765a129aea4")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestWatchDeployment(t *testing.T) {
+	Convey("Watch V3 deployment until deployed", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		payloads := []string{
+			v3CanaryDeploymentPausedPayload,
+			v3CanaryDeploymentPausedPayload,
+			v3DeploymentDeployingPayload,
+			v3DeploymentDeployedPayload,
+		}
+		var polls int32
+		mux.HandleFunc("/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4", func(w http.ResponseWriter, r *http.Request) {
+			i := int(atomic.AddInt32(&polls, 1)) - 1
+			if i >= len(payloads) {
+				i = len(payloads) - 1
+			}
+			fmt.Fprint(w, payloads[i])
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		var transitions []DeploymentTransition
+		for transition := range client.WatchDeployment(context.Background(), "59c3d133-2b83-46f3-960e-7765a129aea4", time.Millisecond) {
+			transitions = append(transitions, transition)
+		}
+		So(len(transitions), ShouldEqual, 3)
+		So(transitions[0].PreviousReason, ShouldEqual, "")
+		So(transitions[0].Reason, ShouldEqual, V3DeploymentPaused)
+		So(transitions[1].PreviousReason, ShouldEqual, V3DeploymentPaused)
+		So(transitions[1].Reason, ShouldEqual, V3DeploymentDeploying)
+		So(transitions[2].Reason, ShouldEqual, V3DeploymentDeployed)
+		So(transitions[2].Deployment.Status.Value, ShouldEqual, V3DeploymentFinalized)
+		So(atomic.LoadInt32(&polls), ShouldEqual, 4)
+	})
+
+	Convey("Watch V3 deployment reports errors until canceled", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		mux.HandleFunc("/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		transitions := client.WatchDeployment(ctx, "59c3d133-2b83-46f3-960e-7765a129aea4", time.Millisecond)
+		transition := <-transitions
+		So(transition.Err, ShouldNotBeNil)
+		So(transition.Deployment, ShouldBeNil)
+		cancel()
+		for range transitions {
+		}
+	})
+
+	Convey("Watch canceled V3 deployment", t, func() {
+		setup(MockRoute{"GET", "/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4", v3CanaryDeploymentCanceledPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		var reasons []string
+		for transition := range client.WatchDeployment(context.Background(), "59c3d133-2b83-46f3-960e-7765a129aea4", 0) {
+			reasons = append(reasons, transition.Reason)
+		}
+		So(reasons, ShouldResemble, []string{V3DeploymentCanceled})
+	})
+}
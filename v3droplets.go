@@ -0,0 +1,255 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// V3DropletAwaitingUpload is the state of droplets created for an upload
+	V3DropletAwaitingUpload = "AWAITING_UPLOAD"
+	// V3DropletProcessingUpload is the state of droplets whose upload is
+	// being processed
+	V3DropletProcessingUpload = "PROCESSING_UPLOAD"
+	// V3DropletStaged is the state of droplets which can be run
+	V3DropletStaged = "STAGED"
+	// V3DropletCopying is the state of droplets being copied from another app
+	V3DropletCopying = "COPYING"
+	// V3DropletFailed is the state of droplets whose staging or upload failed
+	V3DropletFailed = "FAILED"
+	// V3DropletExpired is the state of droplets whose bits were deleted
+	V3DropletExpired = "EXPIRED"
+)
+
+// V3Droplet is the result of staging a V3 package, ready to be run by an app.
+type V3Droplet struct {
+	GUID              string            `json:"guid"`
+	State             string            `json:"state"`
+	Error             string            `json:"error"`
+	Lifecycle         V3Lifecycle       `json:"lifecycle"`
+	ExecutionMetadata string            `json:"execution_metadata"`
+	ProcessTypes      map[string]string `json:"process_types"`
+	// Checksum, Buildpacks and Stack only apply to the buildpack lifecycle.
+	Checksum   *V3Checksum           `json:"checksum"`
+	Buildpacks []V3DetectedBuildpack `json:"buildpacks"`
+	Stack      string                `json:"stack"`
+	// Image only applies to the docker lifecycle.
+	Image     string            `json:"image"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  V3Metadata        `json:"metadata"`
+	Links     map[string]V3Link `json:"links"`
+}
+
+// V3DetectedBuildpack is a buildpack which staged a droplet.
+type V3DetectedBuildpack struct {
+	Name          string `json:"name"`
+	DetectOutput  string `json:"detect_output"`
+	BuildpackName string `json:"buildpack_name"`
+	Version       string `json:"version"`
+}
+
+// GetV3Droplet returns the V3 droplet identified by guid.
+func (c *Client) GetV3Droplet(guid string) (*V3Droplet, error) {
+	return c.getV3Droplet("/v3/droplets/"+guid, "droplet "+guid)
+}
+
+// GetCurrentV3Droplet returns the droplet run by the V3 app identified by
+// appGUID.
+func (c *Client) GetCurrentV3Droplet(appGUID string) (*V3Droplet, error) {
+	return c.getV3Droplet("/v3/apps/"+appGUID+"/droplets/current", "current droplet of app "+appGUID)
+}
+
+func (c *Client) getV3Droplet(requestURL, description string) (*V3Droplet, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", requestURL))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting %s", description)
+	}
+	var droplet V3Droplet
+	if err := decodeV3Response(resp, http.StatusOK, &droplet); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting %s", description)
+	}
+	return &droplet, nil
+}
+
+// ListV3DropletsByApp returns the droplets of the V3 app identified by
+// appGUID. query supports the states filter as a comma separated list,
+// current=true, and order_by=created_at or -created_at.
+func (c *Client) ListV3DropletsByApp(appGUID string, query url.Values) ([]V3Droplet, error) {
+	droplets, err := c.listV3Droplets("/v3/apps/"+appGUID+"/droplets", query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting droplets of app %s", appGUID)
+	}
+	return droplets, nil
+}
+
+// ListV3DropletsByPackage returns the droplets staged from the package
+// identified by pkgGUID. query supports the states filter as a comma
+// separated list.
+func (c *Client) ListV3DropletsByPackage(pkgGUID string, query url.Values) ([]V3Droplet, error) {
+	droplets, err := c.listV3Droplets("/v3/packages/"+pkgGUID+"/droplets", query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting droplets of package %s", pkgGUID)
+	}
+	return droplets, nil
+}
+
+func (c *Client) listV3Droplets(requestURL string, query url.Values) ([]V3Droplet, error) {
+	var droplets []V3Droplet
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	err := c.listV3Resources(requestURL, func(resources json.RawMessage) error {
+		var page []V3Droplet
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		droplets = append(droplets, page...)
+		return nil
+	})
+	return droplets, err
+}
+
+// SetCurrentV3Droplet makes the V3 app identified by appGUID run the droplet
+// identified by dropletGUID, once its processes are restarted.
+func (c *Client) SetCurrentV3Droplet(appGUID, dropletGUID string) error {
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(V3ToOneRelationship{Data: &V3Relationship{GUID: dropletGUID}}); err != nil {
+		return errors.Wrap(err, "Error encoding droplet")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("PATCH", "/v3/apps/"+appGUID+"/relationships/current_droplet", buf))
+	if err != nil {
+		return errors.Wrapf(err, "Error setting droplet %s of app %s", dropletGUID, appGUID)
+	}
+	if err := decodeV3Response(resp, http.StatusOK, nil); err != nil {
+		return errors.Wrapf(err, "Error setting droplet %s of app %s", dropletGUID, appGUID)
+	}
+	return nil
+}
+
+// CopyV3Droplet copies the droplet identified by sourceGUID into a new droplet
+// of the app identified by appGUID. The copy is in the COPYING state until
+// the bits have been copied.
+func (c *Client) CopyV3Droplet(sourceGUID, appGUID string) (*V3Droplet, error) {
+	req := struct {
+		Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	}{
+		Relationships: map[string]V3ToOneRelationship{
+			"app": {Data: &V3Relationship{GUID: appGUID}},
+		},
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding droplet")
+	}
+	requestURL := "/v3/droplets?" + url.Values{"source_guid": {sourceGUID}}.Encode()
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", requestURL, buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error copying droplet %s to app %s", sourceGUID, appGUID)
+	}
+	var droplet V3Droplet
+	if err := decodeV3Response(resp, http.StatusCreated, &droplet); err != nil {
+		return nil, errors.Wrapf(err, "Error copying droplet %s to app %s", sourceGUID, appGUID)
+	}
+	return &droplet, nil
+}
+
+// CreateV3Droplet creates an empty droplet for the app identified by appGUID,
+// to upload a droplet staged elsewhere with UploadV3DropletBits. processTypes
+// maps the process types to their start command, e.g. web to "rackup".
+func (c *Client) CreateV3Droplet(appGUID string, processTypes map[string]string) (*V3Droplet, error) {
+	req := struct {
+		ProcessTypes  map[string]string              `json:"process_types,omitempty"`
+		Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	}{
+		ProcessTypes: processTypes,
+		Relationships: map[string]V3ToOneRelationship{
+			"app": {Data: &V3Relationship{GUID: appGUID}},
+		},
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding droplet")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v3/droplets", buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error creating droplet for app %s", appGUID)
+	}
+	var droplet V3Droplet
+	if err := decodeV3Response(resp, http.StatusCreated, &droplet); err != nil {
+		return nil, errors.Wrapf(err, "Error creating droplet for app %s", appGUID)
+	}
+	return &droplet, nil
+}
+
+// UploadV3DropletBits uploads a gzipped tarball of a staged droplet into the
+// droplet identified by guid, and waits for the upload to be processed.
+func (c *Client) UploadV3DropletBits(guid string, tarball io.Reader) error {
+	resp, err := c.uploadMultipart("POST", "/v3/droplets/"+guid+"/upload", nil, "bits", "droplet.tgz", tarball)
+	if err != nil {
+		return errors.Wrapf(err, "Error uploading bits of droplet %s", guid)
+	}
+	location := resp.Header.Get("Location")
+	if err := decodeV3Response(resp, http.StatusAccepted, nil); err != nil {
+		return errors.Wrapf(err, "Error uploading bits of droplet %s", guid)
+	}
+	return c.waitForJobLocation(location)
+}
+
+// DeleteV3Droplet deletes the droplet identified by guid, and waits for the
+// job the deletion enqueued.
+func (c *Client) DeleteV3Droplet(guid string) error {
+	resp, err := c.DoRequest(c.NewRequest("DELETE", "/v3/droplets/"+guid))
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting droplet %s", guid)
+	}
+	location := resp.Header.Get("Location")
+	if err := decodeV3Response(resp, http.StatusAccepted, nil); err != nil {
+		return errors.Wrapf(err, "Error deleting droplet %s", guid)
+	}
+	return c.waitForJobLocation(location)
+}
+
+// PruneV3Droplets deletes the droplets of the V3 app identified by appGUID
+// but the keep most recent ones, and returns the guids of the deleted
+// droplets. Only the current droplet and STAGED droplets count towards keep,
+// so FAILED and EXPIRED droplets are always deleted. The current droplet and
+// the droplets still being copied or uploaded are never deleted.
+func (c *Client) PruneV3Droplets(appGUID string, keep int) ([]string, error) {
+	current, err := c.ListV3DropletsByApp(appGUID, url.Values{"current": {"true"}})
+	if err != nil {
+		return nil, err
+	}
+	droplets, err := c.ListV3DropletsByApp(appGUID, url.Values{"order_by": {"-created_at"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	kept := len(current)
+	for _, droplet := range droplets {
+		if len(current) > 0 && droplet.GUID == current[0].GUID {
+			continue
+		}
+		switch droplet.State {
+		case V3DropletAwaitingUpload, V3DropletProcessingUpload, V3DropletCopying:
+			continue
+		case V3DropletStaged:
+			if kept < keep {
+				kept++
+				continue
+			}
+		}
+		if err := c.DeleteV3Droplet(droplet.GUID); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, droplet.GUID)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,298 @@
+package cfclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetV3Droplet(t *testing.T) {
+	Convey("Get V3 droplet", t, func() {
+		setup(MockRoute{"GET", "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16", v3DropletPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		droplet, err := client.GetV3Droplet("585bc3c1-3743-497d-88b0-403ad6b56d16")
+		So(err, ShouldBeNil)
+		So(droplet.State, ShouldEqual, V3DropletStaged)
+		So(droplet.ProcessTypes["web"], ShouldEqual, "rackup")
+		So(droplet.Checksum.Type, ShouldEqual, "sha256")
+		So(droplet.Buildpacks[0].BuildpackName, ShouldEqual, "ruby")
+		So(droplet.Links["assign_current_droplet"].Method, ShouldEqual, "PATCH")
+	})
+
+	Convey("Get current V3 droplet", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets/current", v3DropletPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		droplet, err := client.GetCurrentV3Droplet("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(droplet.GUID, ShouldEqual, "585bc3c1-3743-497d-88b0-403ad6b56d16")
+	})
+}
+
+func TestListV3Droplets(t *testing.T) {
+	Convey("List V3 droplets of an app", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets", v3AppDropletsPayload, "", http.StatusOK, "order_by=-created_at", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		droplets, err := client.ListV3DropletsByApp("1cb006ee-fb05-47e1-b541-c34179ddc446", url.Values{"order_by": {"-created_at"}})
+		So(err, ShouldBeNil)
+		So(len(droplets), ShouldEqual, 5)
+		So(droplets[0].State, ShouldEqual, V3DropletCopying)
+	})
+
+	Convey("List V3 droplets of a package", t, func() {
+		setup(MockRoute{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13/droplets", v3AppCurrentDropletListPayload, "", http.StatusOK, "states=STAGED", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		droplets, err := client.ListV3DropletsByPackage("44f7c078-0934-470f-9883-4fcddc5b8f13", url.Values{"states": {V3DropletStaged}})
+		So(err, ShouldBeNil)
+		So(len(droplets), ShouldEqual, 1)
+		So(droplets[0].GUID, ShouldEqual, "585bc3c1-3743-497d-88b0-403ad6b56d16")
+	})
+}
+
+func TestSetCurrentV3Droplet(t *testing.T) {
+	Convey("Set current V3 droplet", t, func() {
+		body := `{"data":{"guid":"585bc3c1-3743-497d-88b0-403ad6b56d16"}}`
+		mocks := []MockRoute{
+			{"PATCH", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/relationships/current_droplet", `{"data":{"guid":"585bc3c1-3743-497d-88b0-403ad6b56d16"}}`, "", http.StatusOK, "", &body},
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446", v3AppStartedPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.SetCurrentV3Droplet("1cb006ee-fb05-47e1-b541-c34179ddc446", "585bc3c1-3743-497d-88b0-403ad6b56d16")
+		So(err, ShouldBeNil)
+
+		app, err := client.AssignDropletToApp("1cb006ee-fb05-47e1-b541-c34179ddc446", "585bc3c1-3743-497d-88b0-403ad6b56d16")
+		So(err, ShouldBeNil)
+		So(app.GUID, ShouldEqual, "1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(app.Name, ShouldEqual, "my_app")
+	})
+
+	Convey("Set current V3 droplet of another app", t, func() {
+		setup(MockRoute{"PATCH", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/relationships/current_droplet", "", "", http.StatusUnprocessableEntity, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.AssignDropletToApp("1cb006ee-fb05-47e1-b541-c34179ddc446", "585bc3c1-3743-497d-88b0-403ad6b56d16")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestCopyV3Droplet(t *testing.T) {
+	Convey("Copy V3 droplet", t, func() {
+		body := `{"relationships":{"app":{"data":{"guid":"02b4ec9b-94c7-4468-9c23-4e906191a0f8"}}}}`
+		setup(MockRoute{"POST", "/v3/droplets", v3DropletCopyingPayload, "", http.StatusCreated, "source_guid=585bc3c1-3743-497d-88b0-403ad6b56d16", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		droplet, err := client.CopyV3Droplet("585bc3c1-3743-497d-88b0-403ad6b56d16", "02b4ec9b-94c7-4468-9c23-4e906191a0f8")
+		So(err, ShouldBeNil)
+		So(droplet.GUID, ShouldEqual, "fdf3851c-def8-4de1-87f1-6d4543189e22")
+		So(droplet.State, ShouldEqual, V3DropletCopying)
+	})
+}
+
+func TestUploadV3Droplet(t *testing.T) {
+	Convey("Create V3 droplet and upload its bits", t, func() {
+		body := `{"process_types":{"web":"rackup"},"relationships":{"app":{"data":{"guid":"1cb006ee-fb05-47e1-b541-c34179ddc446"}}}}`
+		mocks := []MockRoute{
+			{"POST", "/v3/droplets", v3DropletAwaitingUploadPayload, "", http.StatusCreated, "", &body},
+			{"GET", "/v3/jobs/d5d6d1c2-5b0e-4c1e-9f0a-3c1a0a6c7d5e", v3DropletUploadJobCompletePayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		var uploaded, filename string
+		mux.HandleFunc("/v3/droplets/3e3e8d8f-6c3e-4e3a-a2b6-1ef4c0d1f0a1/upload", func(w http.ResponseWriter, r *http.Request) {
+			f, header, err := r.FormFile("bits")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			b, _ := ioutil.ReadAll(f)
+			uploaded, filename = string(b), header.Filename
+			w.Header().Set("Location", server.URL+"/v3/jobs/d5d6d1c2-5b0e-4c1e-9f0a-3c1a0a6c7d5e")
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, v3DropletAwaitingUploadPayload)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		droplet, err := client.CreateV3Droplet("1cb006ee-fb05-47e1-b541-c34179ddc446", map[string]string{"web": "rackup"})
+		So(err, ShouldBeNil)
+		So(droplet.State, ShouldEqual, V3DropletAwaitingUpload)
+
+		err = client.UploadV3DropletBits(droplet.GUID, strings.NewReader("droplet-tarball"))
+		So(err, ShouldBeNil)
+		So(uploaded, ShouldEqual, "droplet-tarball")
+		So(filename, ShouldEqual, "droplet.tgz")
+	})
+}
+
+func TestPruneV3Droplets(t *testing.T) {
+	Convey("Prune V3 droplets", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		mux.HandleFunc("/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets", func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.RawQuery {
+			case "current=true":
+				fmt.Fprint(w, v3AppCurrentDropletListPayload)
+			case "order_by=-created_at":
+				fmt.Fprint(w, v3AppDropletsPayload)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		})
+		var lock sync.Mutex
+		var deleted []string
+		mux.HandleFunc("/v3/droplets/", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			lock.Lock()
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v3/droplets/"))
+			lock.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pruned, err := client.PruneV3Droplets("1cb006ee-fb05-47e1-b541-c34179ddc446", 2)
+		So(err, ShouldBeNil)
+		So(pruned, ShouldResemble, []string{"2a8e2c43-0f1e-4c48-a2a4-5a7f8cd8e3b2", "9b6e7e5d-6d1a-4b1c-8e0f-3f2c1b0a9d8e"})
+		lock.Lock()
+		defer lock.Unlock()
+		So(deleted, ShouldResemble, pruned)
+	})
+
+	Convey("Prune V3 droplets not counting failed ones", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		mux.HandleFunc("/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("current") == "true" {
+				fmt.Fprint(w, v3AppCurrentDropletListPayload)
+				return
+			}
+			fmt.Fprint(w, v3AppDropletsPayload)
+		})
+		var lock sync.Mutex
+		var deleted []string
+		mux.HandleFunc("/v3/droplets/", func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v3/droplets/"))
+			lock.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pruned, err := client.PruneV3Droplets("1cb006ee-fb05-47e1-b541-c34179ddc446", 3)
+		So(err, ShouldBeNil)
+		So(pruned, ShouldResemble, []string{"2a8e2c43-0f1e-4c48-a2a4-5a7f8cd8e3b2"})
+		lock.Lock()
+		defer lock.Unlock()
+		So(deleted, ShouldResemble, pruned)
+	})
+
+	Convey("Prune V3 droplets keeping only the current one", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		mux.HandleFunc("/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/droplets", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("current") == "true" {
+				fmt.Fprint(w, v3AppCurrentDropletListPayload)
+				return
+			}
+			fmt.Fprint(w, v3AppDropletsPayload)
+		})
+		mux.HandleFunc("/v3/droplets/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pruned, err := client.PruneV3Droplets("1cb006ee-fb05-47e1-b541-c34179ddc446", 0)
+		So(err, ShouldBeNil)
+		So(len(pruned), ShouldEqual, 3)
+	})
+}
+
+func TestDeleteV3Droplet(t *testing.T) {
+	Convey("Delete V3 droplet", t, func() {
+		setup(MockRoute{"DELETE", "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16", "", "", http.StatusAccepted, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DeleteV3Droplet("585bc3c1-3743-497d-88b0-403ad6b56d16")
+		So(err, ShouldBeNil)
+	})
+}
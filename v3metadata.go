@@ -0,0 +1,173 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// V3MetadataResource is a type of V3 resource carrying metadata, as named in
+// its endpoint.
+type V3MetadataResource string
+
+const (
+	// MetadataApps are the V3 apps
+	MetadataApps V3MetadataResource = "apps"
+	// MetadataSpaces are the spaces
+	MetadataSpaces V3MetadataResource = "spaces"
+	// MetadataOrgs are the organizations
+	MetadataOrgs V3MetadataResource = "organizations"
+	// MetadataDomains are the domains, shared or private
+	MetadataDomains V3MetadataResource = "domains"
+	// MetadataRoutes are the routes
+	MetadataRoutes V3MetadataResource = "routes"
+	// MetadataServiceInstances are the service instances, managed or user
+	// provided
+	MetadataServiceInstances V3MetadataResource = "service_instances"
+	// MetadataBuildpacks are the admin buildpacks
+	MetadataBuildpacks V3MetadataResource = "buildpacks"
+	// MetadataStacks are the stacks
+	MetadataStacks V3MetadataResource = "stacks"
+)
+
+const maxAnnotationValueLength = 5000
+
+// V3Metadata holds the labels and annotations of a V3 resource. Labels can
+// be queried with a LabelSelector, annotations are free form.
+type V3Metadata struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// V3MetadataUpdate describes the changes to the metadata of a V3 resource.
+// The labels and annotations it does not mention are left unchanged.
+type V3MetadataUpdate struct {
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+// SetLabel sets the label key to value.
+func (m *V3MetadataUpdate) SetLabel(key, value string) {
+	if m.Labels == nil {
+		m.Labels = map[string]*string{}
+	}
+	m.Labels[key] = &value
+}
+
+// RemoveLabel removes the label key.
+func (m *V3MetadataUpdate) RemoveLabel(key string) {
+	if m.Labels == nil {
+		m.Labels = map[string]*string{}
+	}
+	m.Labels[key] = nil
+}
+
+// SetAnnotation sets the annotation key to value.
+func (m *V3MetadataUpdate) SetAnnotation(key, value string) {
+	if m.Annotations == nil {
+		m.Annotations = map[string]*string{}
+	}
+	m.Annotations[key] = &value
+}
+
+// RemoveAnnotation removes the annotation key.
+func (m *V3MetadataUpdate) RemoveAnnotation(key string) {
+	if m.Annotations == nil {
+		m.Annotations = map[string]*string{}
+	}
+	m.Annotations[key] = nil
+}
+
+func (m V3MetadataUpdate) validate() error {
+	for key, value := range m.Labels {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if value != nil {
+			if err := validateLabelValue(*value); err != nil {
+				return err
+			}
+		}
+	}
+	for key, value := range m.Annotations {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if value != nil && len(*value) > maxAnnotationValueLength {
+			return errors.Errorf("Invalid annotation %s: the value must be at most %d characters", key, maxAnnotationValueLength)
+		}
+	}
+	return nil
+}
+
+// V3LabeledResource is a V3 resource as returned by ListV3ByLabelSelector.
+type V3LabeledResource struct {
+	GUID     string     `json:"guid"`
+	Name     string     `json:"name"`
+	Metadata V3Metadata `json:"metadata"`
+}
+
+// GetV3Metadata returns the metadata of the resource of type resource
+// identified by guid.
+func (c *Client) GetV3Metadata(resource V3MetadataResource, guid string) (*V3Metadata, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/"+string(resource)+"/"+guid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting metadata of %s %s", resource, guid)
+	}
+	var labeled V3LabeledResource
+	if err := decodeV3Response(resp, http.StatusOK, &labeled); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting metadata of %s %s", resource, guid)
+	}
+	return &labeled.Metadata, nil
+}
+
+// UpdateV3Metadata applies update to the metadata of the resource of type
+// resource identified by guid, and returns the resulting metadata. The keys
+// and values are validated before sending the update.
+func (c *Client) UpdateV3Metadata(resource V3MetadataResource, guid string, update V3MetadataUpdate) (*V3Metadata, error) {
+	if err := update.validate(); err != nil {
+		return nil, err
+	}
+	req := struct {
+		Metadata V3MetadataUpdate `json:"metadata"`
+	}{update}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding metadata")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("PATCH", "/v3/"+string(resource)+"/"+guid, buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error updating metadata of %s %s", resource, guid)
+	}
+	var labeled V3LabeledResource
+	if err := decodeV3Response(resp, http.StatusOK, &labeled); err != nil {
+		return nil, errors.Wrapf(err, "Error updating metadata of %s %s", resource, guid)
+	}
+	return &labeled.Metadata, nil
+}
+
+// ListV3ByLabelSelector returns the resources of type resource matching
+// selector, with their guid, name if they have one, and metadata. The typed list calls
+// accepting a query, such as ListV3AppsByQuery, take the selector through
+// LabelSelector.Query.
+func (c *Client) ListV3ByLabelSelector(resource V3MetadataResource, selector *LabelSelector) ([]V3LabeledResource, error) {
+	query, err := selector.Query()
+	if err != nil {
+		return nil, err
+	}
+	var resources []V3LabeledResource
+	err = c.listV3Resources("/v3/"+string(resource)+"?"+query.Encode(), func(page json.RawMessage) error {
+		var labeled []V3LabeledResource
+		if err := json.Unmarshal(page, &labeled); err != nil {
+			return err
+		}
+		resources = append(resources, labeled...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting %s matching %s", resource, selector)
+	}
+	return resources, nil
+}
@@ -0,0 +1,112 @@
+package cfclient
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetV3Metadata(t *testing.T) {
+	Convey("Get V3 metadata", t, func() {
+		setup(MockRoute{"GET", "/v3/spaces/2f35885d-0c9d-4423-83ad-fd05066f8576", v3SpaceLabeledPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		metadata, err := client.GetV3Metadata(MetadataSpaces, "2f35885d-0c9d-4423-83ad-fd05066f8576")
+		So(err, ShouldBeNil)
+		So(metadata.Labels, ShouldResemble, map[string]string{"env": "prod", "team": "payments"})
+		So(metadata.Annotations["contact"], ShouldEqual, "payments@example.com")
+	})
+}
+
+func TestUpdateV3Metadata(t *testing.T) {
+	Convey("Update V3 metadata", t, func() {
+		body := `{"metadata":{"labels":{"env":"stage","team":null},"annotations":{"runbook":"https://wiki.example.com/payments"}}}`
+		setup(MockRoute{"PATCH", "/v3/spaces/2f35885d-0c9d-4423-83ad-fd05066f8576", v3SpaceRelabeledPayload, "", http.StatusOK, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		var update V3MetadataUpdate
+		update.SetLabel("env", "stage")
+		update.RemoveLabel("team")
+		update.SetAnnotation("runbook", "https://wiki.example.com/payments")
+		metadata, err := client.UpdateV3Metadata(MetadataSpaces, "2f35885d-0c9d-4423-83ad-fd05066f8576", update)
+		So(err, ShouldBeNil)
+		So(metadata.Labels, ShouldResemble, map[string]string{"env": "stage"})
+		So(len(metadata.Annotations), ShouldEqual, 2)
+	})
+
+	Convey("Update V3 metadata with invalid keys and values", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		var invalidLabel V3MetadataUpdate
+		invalidLabel.SetLabel("env", "not valid")
+		var invalidKey V3MetadataUpdate
+		invalidKey.RemoveAnnotation("_contact")
+		var tooLong V3MetadataUpdate
+		tooLong.SetAnnotation("notes", strings.Repeat("x", 5001))
+		for _, update := range []V3MetadataUpdate{invalidLabel, invalidKey, tooLong} {
+			_, err = client.UpdateV3Metadata(MetadataApps, "1cb006ee-fb05-47e1-b541-c34179ddc446", update)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestListV3ByLabelSelector(t *testing.T) {
+	Convey("List V3 resources by label selector", t, func() {
+		setup(MockRoute{"GET", "/v3/apps", v3LabeledAppsPayload, "", http.StatusOK, "label_selector=env in (prod,stage),!deprecated", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		selector := NewLabelSelector().In("env", "prod", "stage").NotExists("deprecated")
+		resources, err := client.ListV3ByLabelSelector(MetadataApps, selector)
+		So(err, ShouldBeNil)
+		So(len(resources), ShouldEqual, 1)
+		So(resources[0].Name, ShouldEqual, "my_app")
+		So(resources[0].Metadata.Labels["env"], ShouldEqual, "prod")
+
+		query, err := selector.Query()
+		So(err, ShouldBeNil)
+		apps, err := client.ListV3AppsByQuery(query)
+		So(err, ShouldBeNil)
+		So(apps[0].Metadata.Labels["env"], ShouldEqual, "prod")
+	})
+
+	Convey("List V3 resources by invalid label selector", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.ListV3ByLabelSelector(MetadataApps, NewLabelSelector().Exists("-env"))
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,322 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// V3PackageBits is the type of packages holding uploaded app bits
+	V3PackageBits = "bits"
+	// V3PackageDocker is the type of packages referencing a docker image
+	V3PackageDocker = "docker"
+)
+
+const (
+	// V3PackageAwaitingUpload is the state of bits packages without bits
+	V3PackageAwaitingUpload = "AWAITING_UPLOAD"
+	// V3PackageProcessingUpload is the state of bits packages whose bits are
+	// being processed
+	V3PackageProcessingUpload = "PROCESSING_UPLOAD"
+	// V3PackageReady is the state of packages which can be staged
+	V3PackageReady = "READY"
+	// V3PackageFailed is the state of packages whose bits failed processing
+	V3PackageFailed = "FAILED"
+	// V3PackageCopying is the state of packages whose bits are being copied
+	// from another package
+	V3PackageCopying = "COPYING"
+	// V3PackageExpired is the state of packages whose bits were deleted
+	V3PackageExpired = "EXPIRED"
+)
+
+const (
+	defaultPackageTimeout  = 5 * time.Minute
+	defaultPackageInterval = time.Second
+)
+
+// V3Package is a V3 package of either type.
+type V3Package struct {
+	GUID      string            `json:"guid"`
+	Type      string            `json:"type"`
+	Data      V3PackageData     `json:"data"`
+	State     string            `json:"state"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  V3Metadata        `json:"metadata"`
+	Links     map[string]V3Link `json:"links"`
+}
+
+// V3PackageData holds the settings specific to the package type. Checksum and
+// Error only apply to bits packages, the others to docker packages.
+type V3PackageData struct {
+	Checksum *V3Checksum `json:"checksum,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Image    string      `json:"image,omitempty"`
+	Username string      `json:"username,omitempty"`
+	Password string      `json:"password,omitempty"`
+}
+
+// CreateV3PackageRequest describes a V3 package to create. Type defaults to
+// V3PackageBits; the docker fields only apply to V3PackageDocker.
+type CreateV3PackageRequest struct {
+	AppGUID        string
+	Type           string
+	DockerImage    string
+	DockerUsername string
+	DockerPassword string
+}
+
+// v3ResourceMatch is a file as described to the V3 resource match and
+// package upload endpoints.
+type v3ResourceMatch struct {
+	Checksum struct {
+		Value string `json:"value"`
+	} `json:"checksum"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+	Path        string `json:"path,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+}
+
+func newV3ResourceMatches(resources []BitsResource) []v3ResourceMatch {
+	matches := make([]v3ResourceMatch, 0, len(resources))
+	for _, resource := range resources {
+		var match v3ResourceMatch
+		match.Checksum.Value = resource.Sha1
+		match.SizeInBytes = resource.Size
+		match.Path = resource.Fn
+		match.Mode = resource.Mode
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// CreateV3Package creates a V3 package, see CreateV3PackageRequest. Bits
+// packages are created in the AWAITING_UPLOAD state.
+func (c *Client) CreateV3Package(r CreateV3PackageRequest) (*V3Package, error) {
+	req := struct {
+		Type          string                         `json:"type"`
+		Data          *V3PackageData                 `json:"data,omitempty"`
+		Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	}{
+		Type: r.Type,
+		Relationships: map[string]V3ToOneRelationship{
+			"app": {Data: &V3Relationship{GUID: r.AppGUID}},
+		},
+	}
+	if req.Type == "" {
+		req.Type = V3PackageBits
+	}
+	if req.Type == V3PackageDocker {
+		req.Data = &V3PackageData{Image: r.DockerImage, Username: r.DockerUsername, Password: r.DockerPassword}
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding package")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v3/packages", buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error creating package for app %s", r.AppGUID)
+	}
+	var pkg V3Package
+	if err := decodeV3Response(resp, http.StatusCreated, &pkg); err != nil {
+		return nil, errors.Wrapf(err, "Error creating package for app %s", r.AppGUID)
+	}
+	return &pkg, nil
+}
+
+// GetV3Package returns the V3 package identified by guid.
+func (c *Client) GetV3Package(guid string) (*V3Package, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/packages/"+guid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting package %s", guid)
+	}
+	var pkg V3Package
+	if err := decodeV3Response(resp, http.StatusOK, &pkg); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting package %s", guid)
+	}
+	return &pkg, nil
+}
+
+// ListV3PackagesByApp returns the packages of the V3 app identified by
+// appGUID. query supports the states and types filters as comma separated
+// lists, e.g. states=READY.
+func (c *Client) ListV3PackagesByApp(appGUID string, query url.Values) ([]V3Package, error) {
+	var packages []V3Package
+	requestURL := "/v3/apps/" + appGUID + "/packages"
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	err := c.listV3Resources(requestURL, func(resources json.RawMessage) error {
+		var page []V3Package
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		packages = append(packages, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting packages of app %s", appGUID)
+	}
+	return packages, nil
+}
+
+// CopyV3Package copies the bits of the package identified by sourceGUID into
+// a new package of the app identified by appGUID. The copy is in the COPYING
+// state until the bits have been copied, see WaitForV3PackageReady.
+func (c *Client) CopyV3Package(sourceGUID, appGUID string) (*V3Package, error) {
+	req := struct {
+		Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	}{
+		Relationships: map[string]V3ToOneRelationship{
+			"app": {Data: &V3Relationship{GUID: appGUID}},
+		},
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding package")
+	}
+	requestURL := "/v3/packages?" + url.Values{"source_guid": {sourceGUID}}.Encode()
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", requestURL, buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error copying package %s to app %s", sourceGUID, appGUID)
+	}
+	var pkg V3Package
+	if err := decodeV3Response(resp, http.StatusCreated, &pkg); err != nil {
+		return nil, errors.Wrapf(err, "Error copying package %s to app %s", sourceGUID, appGUID)
+	}
+	return &pkg, nil
+}
+
+// MatchV3Resources returns the resources which the Cloud Controller resource
+// cache already holds, using the V3 endpoint.
+func (c *Client) MatchV3Resources(resources []BitsResource) ([]BitsResource, error) {
+	req := struct {
+		Resources []v3ResourceMatch `json:"resources"`
+	}{newV3ResourceMatches(resources)}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding resources")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v3/resource_matches", buf))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error matching resources")
+	}
+	var matches struct {
+		Resources []v3ResourceMatch `json:"resources"`
+	}
+	if err := decodeV3Response(resp, http.StatusCreated, &matches); err != nil {
+		return nil, errors.Wrap(err, "Error matching resources")
+	}
+	matched := make([]BitsResource, 0, len(matches.Resources))
+	for _, match := range matches.Resources {
+		matched = append(matched, BitsResource{
+			Sha1: match.Checksum.Value,
+			Size: match.SizeInBytes,
+			Fn:   match.Path,
+			Mode: match.Mode,
+		})
+	}
+	return matched, nil
+}
+
+// UploadV3PackageBits uploads the content of dir as the bits of the package
+// identified by guid. Files listed in the .cfignore file of dir are skipped,
+// as are files already known to the Cloud Controller resource cache. The
+// package is processing the bits when UploadV3PackageBits returns, see
+// WaitForV3PackageReady.
+func (c *Client) UploadV3PackageBits(guid, dir string) (*V3Package, error) {
+	zipFile, cached, err := buildAppZip(dir, c.MatchV3Resources)
+	if err != nil {
+		return nil, err
+	}
+	defer removeTempFile(zipFile)
+	return c.uploadV3Package(guid, cached, zipFile)
+}
+
+// UploadV3PackageZip uploads an already built zip archive as the bits of the
+// package identified by guid, see UploadV3PackageBits.
+func (c *Client) UploadV3PackageZip(guid string, zipFile io.Reader) (*V3Package, error) {
+	return c.uploadV3Package(guid, nil, zipFile)
+}
+
+func (c *Client) uploadV3Package(guid string, resources []BitsResource, zipFile io.Reader) (*V3Package, error) {
+	resourcesJSON, err := json.Marshal(newV3ResourceMatches(resources))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error encoding resources")
+	}
+	resp, err := c.uploadMultipart("POST", "/v3/packages/"+guid+"/upload", resourcesJSON, "bits", "bits.zip", zipFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error uploading bits of package %s", guid)
+	}
+	var pkg V3Package
+	if err := decodeV3Response(resp, http.StatusOK, &pkg); err != nil {
+		return nil, errors.Wrapf(err, "Error uploading bits of package %s", guid)
+	}
+	return &pkg, nil
+}
+
+// WaitForV3PackageOptions configures WaitForV3PackageReady. Zero values are
+// replaced by their defaults.
+type WaitForV3PackageOptions struct {
+	// Timeout defaults to 5 minutes.
+	Timeout time.Duration
+	// Interval is the time between two polls of the package, defaults to 1
+	// second.
+	Interval time.Duration
+}
+
+// WaitForV3PackageReady polls the package identified by guid until it is
+// READY, which may take a while after an upload or a copy. An error is
+// returned if the package FAILED or EXPIRED, or is not ready in time.
+func (c *Client) WaitForV3PackageReady(guid string, opts WaitForV3PackageOptions) (*V3Package, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultPackageTimeout
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultPackageInterval
+	}
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		pkg, err := c.GetV3Package(guid)
+		if err != nil {
+			return nil, err
+		}
+		switch pkg.State {
+		case V3PackageReady:
+			return pkg, nil
+		case V3PackageFailed:
+			if pkg.Data.Error != "" {
+				return pkg, errors.Errorf("Package %s failed: %s", guid, pkg.Data.Error)
+			}
+			return pkg, errors.Errorf("Package %s failed", guid)
+		case V3PackageExpired:
+			return pkg, errors.Errorf("Package %s expired", guid)
+		}
+		if time.Now().After(deadline) {
+			return pkg, errors.Errorf("Timed out after %s waiting for package %s, state: %s", opts.Timeout, guid, pkg.State)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
+
+// DownloadV3Package streams the bits of the package identified by guid into
+// w and verifies them against the checksum recorded by the Cloud Controller.
+// See DownloadAppBits for redirect handling.
+func (c *Client) DownloadV3Package(guid string, w io.Writer) error {
+	pkg, err := c.GetV3Package(guid)
+	if err != nil {
+		return err
+	}
+	checksum := pkg.Data.Checksum
+	if checksum != nil && checksum.Value == "" {
+		checksum = nil
+	}
+	return c.downloadBlob(fmt.Sprintf("/v3/packages/%s/download", guid), w, checksum)
+}
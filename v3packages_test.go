@@ -0,0 +1,302 @@
+package cfclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateV3Package(t *testing.T) {
+	Convey("Create V3 bits package", t, func() {
+		body := `{"type":"bits","relationships":{"app":{"data":{"guid":"1cb006ee-fb05-47e1-b541-c34179ddc446"}}}}`
+		setup(MockRoute{"POST", "/v3/packages", v3PackageAwaitingUploadPayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pkg, err := client.CreateV3Package(CreateV3PackageRequest{AppGUID: "1cb006ee-fb05-47e1-b541-c34179ddc446"})
+		So(err, ShouldBeNil)
+		So(pkg.GUID, ShouldEqual, "44f7c078-0934-470f-9883-4fcddc5b8f13")
+		So(pkg.Type, ShouldEqual, V3PackageBits)
+		So(pkg.State, ShouldEqual, V3PackageAwaitingUpload)
+		So(pkg.Data.Checksum.Type, ShouldEqual, "sha256")
+		So(pkg.Links["upload"].Method, ShouldEqual, "POST")
+	})
+
+	Convey("Create V3 docker package", t, func() {
+		body := `{"type":"docker","data":{"image":"registry/image:latest","username":"username","password":"secret"},"relationships":{"app":{"data":{"guid":"02b4ec9b-94c7-4468-9c23-4e906191a0f8"}}}}`
+		setup(MockRoute{"POST", "/v3/packages", v3DockerPackagePayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pkg, err := client.CreateV3Package(CreateV3PackageRequest{
+			AppGUID:        "02b4ec9b-94c7-4468-9c23-4e906191a0f8",
+			Type:           V3PackageDocker,
+			DockerImage:    "registry/image:latest",
+			DockerUsername: "username",
+			DockerPassword: "secret",
+		})
+		So(err, ShouldBeNil)
+		So(pkg.State, ShouldEqual, V3PackageReady)
+		So(pkg.Data.Image, ShouldEqual, "registry/image:latest")
+		So(pkg.Data.Checksum, ShouldBeNil)
+	})
+}
+
+func TestUploadV3PackageBits(t *testing.T) {
+	Convey("Upload V3 package bits", t, func() {
+		dir := writeTestAppDir()
+		defer os.RemoveAll(dir)
+
+		setup(MockRoute{"POST", "/v3/resource_matches", v3ResourceMatchesPayload, "", http.StatusCreated, "", nil}, t)
+		defer teardown()
+		var resources []v3ResourceMatch
+		var zipped []string
+		mux.HandleFunc("/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13/upload", func(w http.ResponseWriter, r *http.Request) {
+			if err := json.Unmarshal([]byte(r.FormValue("resources")), &resources); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f, _, err := r.FormFile("bits")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			b, _ := ioutil.ReadAll(f)
+			zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for _, zf := range zr.File {
+				zipped = append(zipped, zf.Name)
+			}
+			fmt.Fprint(w, v3PackageProcessingPayload)
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pkg, err := client.UploadV3PackageBits("44f7c078-0934-470f-9883-4fcddc5b8f13", dir)
+		So(err, ShouldBeNil)
+		So(pkg.State, ShouldEqual, V3PackageProcessingUpload)
+		So(len(resources), ShouldEqual, 1)
+		So(resources[0].Checksum.Value, ShouldEqual, "3d7735835e9194bf6b6dfdf967e2b77809f33657")
+		So(resources[0].Path, ShouldEqual, "lib/cached.js")
+		So(zipped, ShouldContain, "app.js")
+		So(zipped, ShouldNotContain, "lib/cached.js")
+	})
+
+	Convey("Upload V3 package zip rejected", t, func() {
+		setup(MockRoute{"POST", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13/upload", "", "", http.StatusUnprocessableEntity, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.UploadV3PackageZip("44f7c078-0934-470f-9883-4fcddc5b8f13", bytes.NewReader([]byte{}))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestMatchV3Resources(t *testing.T) {
+	Convey("Match V3 resources", t, func() {
+		body := `{"resources":[{"checksum":{"value":"3d7735835e9194bf6b6dfdf967e2b77809f33657"},"size_in_bytes":33,"path":"lib/cached.js","mode":"644"},{"checksum":{"value":"0a4d55a8d778e5022fab701977c5d840bbc486d0"},"size_in_bytes":12,"path":"app.js"}]}`
+		setup(MockRoute{"POST", "/v3/resource_matches", v3ResourceMatchesPayload, "", http.StatusCreated, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		matched, err := client.MatchV3Resources([]BitsResource{
+			{Sha1: "3d7735835e9194bf6b6dfdf967e2b77809f33657", Size: 33, Fn: "lib/cached.js", Mode: "644"},
+			{Sha1: "0a4d55a8d778e5022fab701977c5d840bbc486d0", Size: 12, Fn: "app.js"},
+		})
+		So(err, ShouldBeNil)
+		So(matched, ShouldResemble, []BitsResource{
+			{Sha1: "3d7735835e9194bf6b6dfdf967e2b77809f33657", Size: 33, Fn: "lib/cached.js", Mode: "644"},
+		})
+	})
+}
+
+func TestWaitForV3PackageReady(t *testing.T) {
+	Convey("Wait for V3 package to be ready", t, func() {
+		setup(MockRoute{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageReadyPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pkg, err := client.WaitForV3PackageReady("44f7c078-0934-470f-9883-4fcddc5b8f13", WaitForV3PackageOptions{})
+		So(err, ShouldBeNil)
+		So(pkg.Data.Checksum.Value, ShouldEqual, "ce127868b68204acf32d8fb14429592c91e42d503219e9dae74a2fafffdad059")
+	})
+
+	Convey("Wait for V3 package to be processed", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		polls := 0
+		mux.HandleFunc("/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			if polls < 3 {
+				w.Write([]byte(v3PackageProcessingPayload))
+				return
+			}
+			w.Write([]byte(v3PackageReadyPayload))
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pkg, err := client.WaitForV3PackageReady("44f7c078-0934-470f-9883-4fcddc5b8f13", WaitForV3PackageOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(polls, ShouldEqual, 3)
+		So(pkg.State, ShouldEqual, V3PackageReady)
+	})
+
+	Convey("Wait for failed V3 package", t, func() {
+		setup(MockRoute{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageFailedPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pkg, err := client.WaitForV3PackageReady("44f7c078-0934-470f-9883-4fcddc5b8f13", WaitForV3PackageOptions{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Invalid zip file")
+		So(pkg.State, ShouldEqual, V3PackageFailed)
+	})
+
+	Convey("Wait for V3 package times out", t, func() {
+		setup(MockRoute{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageProcessingPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.WaitForV3PackageReady("44f7c078-0934-470f-9883-4fcddc5b8f13", WaitForV3PackageOptions{
+			Timeout:  5 * time.Millisecond,
+			Interval: time.Millisecond,
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "PROCESSING_UPLOAD")
+	})
+}
+
+func TestCopyV3Package(t *testing.T) {
+	Convey("Copy V3 package", t, func() {
+		body := `{"relationships":{"app":{"data":{"guid":"02b4ec9b-94c7-4468-9c23-4e906191a0f8"}}}}`
+		setup(MockRoute{"POST", "/v3/packages", v3PackageCopyingPayload, "", http.StatusCreated, "source_guid=44f7c078-0934-470f-9883-4fcddc5b8f13", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		pkg, err := client.CopyV3Package("44f7c078-0934-470f-9883-4fcddc5b8f13", "02b4ec9b-94c7-4468-9c23-4e906191a0f8")
+		So(err, ShouldBeNil)
+		So(pkg.GUID, ShouldEqual, "fec72fc1-e453-4463-a86d-5df426f337a3")
+		So(pkg.State, ShouldEqual, V3PackageCopying)
+	})
+}
+
+func TestListV3PackagesByApp(t *testing.T) {
+	Convey("List V3 packages of an app", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/packages", v3AppPackagesPayload, "", http.StatusOK, "states=READY", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		packages, err := client.ListV3PackagesByApp("1cb006ee-fb05-47e1-b541-c34179ddc446", url.Values{"states": {V3PackageReady}})
+		So(err, ShouldBeNil)
+		So(len(packages), ShouldEqual, 2)
+		So(packages[0].Type, ShouldEqual, V3PackageBits)
+		So(packages[1].Type, ShouldEqual, V3PackageDocker)
+	})
+}
+
+func TestDownloadV3Package(t *testing.T) {
+	Convey("Download V3 package and verify its checksum", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageReadyPayload, "", http.StatusOK, "", nil},
+			{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13/download", "package-content", "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		buf := bytes.NewBuffer(nil)
+		err = client.DownloadV3Package("44f7c078-0934-470f-9883-4fcddc5b8f13", buf)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "package-content")
+	})
+
+	Convey("Download V3 package with checksum mismatch", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13", v3PackageReadyPayload, "", http.StatusOK, "", nil},
+			{"GET", "/v3/packages/44f7c078-0934-470f-9883-4fcddc5b8f13/download", "corrupted", "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DownloadV3Package("44f7c078-0934-470f-9883-4fcddc5b8f13", bytes.NewBuffer(nil))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Checksum mismatch")
+	})
+}
@@ -0,0 +1,213 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// V3HealthCheckPort checks that the process accepts TCP connections
+	V3HealthCheckPort = "port"
+	// V3HealthCheckProcess only checks that the process is running
+	V3HealthCheckProcess = "process"
+	// V3HealthCheckHTTP checks that the endpoint of the process answers 200
+	V3HealthCheckHTTP = "http"
+)
+
+// V3Process is a process type of a V3 app, e.g. web or worker, which runs
+// its own instances.
+type V3Process struct {
+	GUID        string            `json:"guid"`
+	Type        string            `json:"type"`
+	Command     string            `json:"command"`
+	Instances   int               `json:"instances"`
+	MemoryInMB  int               `json:"memory_in_mb"`
+	DiskInMB    int               `json:"disk_in_mb"`
+	HealthCheck V3HealthCheck     `json:"health_check"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Metadata    V3Metadata        `json:"metadata"`
+	Links       map[string]V3Link `json:"links"`
+}
+
+// V3HealthCheck tells how the platform checks that a process instance is
+// healthy.
+type V3HealthCheck struct {
+	// Type is one of V3HealthCheckPort, V3HealthCheckProcess and
+	// V3HealthCheckHTTP.
+	Type string            `json:"type"`
+	Data V3HealthCheckData `json:"data"`
+}
+
+// V3HealthCheckData holds the health check settings, zero values being left
+// to the platform defaults.
+type V3HealthCheckData struct {
+	// Timeout is the number of seconds an instance has to become healthy
+	// after starting.
+	Timeout int `json:"timeout,omitempty"`
+	// InvocationTimeout is the number of seconds a single check may last.
+	InvocationTimeout int `json:"invocation_timeout,omitempty"`
+	// Endpoint is the path checked by V3HealthCheckHTTP.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// V3ProcessStats is the state and resource usage of a process instance.
+type V3ProcessStats struct {
+	Type  string         `json:"type"`
+	Index int            `json:"index"`
+	State string         `json:"state"`
+	Usage V3ProcessUsage `json:"usage"`
+	Host  string         `json:"host"`
+	// Uptime is the number of seconds since the instance started.
+	Uptime           int64  `json:"uptime"`
+	MemQuota         uint64 `json:"mem_quota"`
+	DiskQuota        uint64 `json:"disk_quota"`
+	FdsQuota         uint64 `json:"fds_quota"`
+	IsolationSegment string `json:"isolation_segment"`
+	Details          string `json:"details"`
+}
+
+// V3ProcessUsage is the resource usage of a process instance, empty when the
+// instance is not running.
+type V3ProcessUsage struct {
+	Time time.Time `json:"time"`
+	// CPU is the share of a host core used, between 0 and the number of
+	// cores.
+	CPU float64 `json:"cpu"`
+	// CPUEntitlement is the share of the CPU the instance is entitled to
+	// which it used, above 1 when it uses spare CPU of the host. It is only
+	// reported by recent platforms.
+	CPUEntitlement float64 `json:"cpu_entitlement"`
+	Mem            uint64  `json:"mem"`
+	Disk           uint64  `json:"disk"`
+}
+
+// ListV3ProcessesByApp returns the processes of the V3 app identified by
+// appGUID.
+func (c *Client) ListV3ProcessesByApp(appGUID string) ([]V3Process, error) {
+	var processes []V3Process
+	err := c.listV3Resources("/v3/apps/"+appGUID+"/processes", func(resources json.RawMessage) error {
+		var page []V3Process
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		processes = append(processes, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting processes of app %s", appGUID)
+	}
+	return processes, nil
+}
+
+// GetV3Process returns the V3 process identified by guid.
+func (c *Client) GetV3Process(guid string) (*V3Process, error) {
+	return c.getV3Process("/v3/processes/"+guid, "process "+guid)
+}
+
+// GetV3AppProcess returns the process of type processType, e.g. web, of the
+// V3 app identified by appGUID.
+func (c *Client) GetV3AppProcess(appGUID, processType string) (*V3Process, error) {
+	return c.getV3Process("/v3/apps/"+appGUID+"/processes/"+processType, processType+" process of app "+appGUID)
+}
+
+func (c *Client) getV3Process(requestURL, description string) (*V3Process, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", requestURL))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting %s", description)
+	}
+	var process V3Process
+	if err := decodeV3Response(resp, http.StatusOK, &process); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting %s", description)
+	}
+	return &process, nil
+}
+
+// ScaleProcess scales the process of type processType of the V3 app
+// identified by appGUID. A negative instances leaves the number of instances
+// unchanged, as do zero memoryInMB and diskInMB for the memory and disk
+// limits. Changing the limits restarts the instances.
+func (c *Client) ScaleProcess(appGUID, processType string, instances, memoryInMB, diskInMB int) (*V3Process, error) {
+	req := struct {
+		Instances  *int `json:"instances,omitempty"`
+		MemoryInMB int  `json:"memory_in_mb,omitempty"`
+		DiskInMB   int  `json:"disk_in_mb,omitempty"`
+	}{
+		MemoryInMB: memoryInMB,
+		DiskInMB:   diskInMB,
+	}
+	if instances >= 0 {
+		req.Instances = &instances
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding scale")
+	}
+	requestURL := "/v3/apps/" + appGUID + "/processes/" + processType + "/actions/scale"
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", requestURL, buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error scaling %s process of app %s", processType, appGUID)
+	}
+	var process V3Process
+	if err := decodeV3Response(resp, http.StatusAccepted, &process); err != nil {
+		return nil, errors.Wrapf(err, "Error scaling %s process of app %s", processType, appGUID)
+	}
+	return &process, nil
+}
+
+// UpdateProcessHealthCheck replaces the health check of the process
+// identified by processGUID. It applies to the instances started afterwards.
+func (c *Client) UpdateProcessHealthCheck(processGUID string, healthCheck V3HealthCheck) (*V3Process, error) {
+	req := struct {
+		HealthCheck V3HealthCheck `json:"health_check"`
+	}{healthCheck}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
+		return nil, errors.Wrap(err, "Error encoding health check")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("PATCH", "/v3/processes/"+processGUID, buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error updating health check of process %s", processGUID)
+	}
+	var process V3Process
+	if err := decodeV3Response(resp, http.StatusOK, &process); err != nil {
+		return nil, errors.Wrapf(err, "Error updating health check of process %s", processGUID)
+	}
+	return &process, nil
+}
+
+// GetV3ProcessStats returns the stats of the instances of the process
+// identified by processGUID, ordered by index.
+func (c *Client) GetV3ProcessStats(processGUID string) ([]V3ProcessStats, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/processes/"+processGUID+"/stats"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting stats of process %s", processGUID)
+	}
+	var stats struct {
+		Resources []V3ProcessStats `json:"resources"`
+	}
+	if err := decodeV3Response(resp, http.StatusOK, &stats); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting stats of process %s", processGUID)
+	}
+	return stats.Resources, nil
+}
+
+// TerminateProcessInstance stops the instance at index of the process of type
+// processType of the V3 app identified by appGUID. The platform starts a new
+// instance to replace it.
+func (c *Client) TerminateProcessInstance(appGUID, processType string, index int) error {
+	requestURL := fmt.Sprintf("/v3/apps/%s/processes/%s/instances/%d", appGUID, processType, index)
+	resp, err := c.DoRequest(c.NewRequest("DELETE", requestURL))
+	if err != nil {
+		return errors.Wrapf(err, "Error terminating instance %d of %s process of app %s", index, processType, appGUID)
+	}
+	if err := decodeV3Response(resp, http.StatusNoContent, nil); err != nil {
+		return errors.Wrapf(err, "Error terminating instance %d of %s process of app %s", index, processType, appGUID)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package cfclient
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListV3ProcessesByApp(t *testing.T) {
+	Convey("List V3 processes of an app", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes", v3AppProcessesPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		processes, err := client.ListV3ProcessesByApp("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(len(processes), ShouldEqual, 2)
+		So(processes[0].Type, ShouldEqual, "web")
+		So(processes[0].Instances, ShouldEqual, 5)
+		So(processes[0].HealthCheck.Type, ShouldEqual, V3HealthCheckPort)
+		So(processes[1].Command, ShouldEqual, "bundle exec rake worker")
+	})
+}
+
+func TestGetV3Process(t *testing.T) {
+	Convey("Get V3 process", t, func() {
+		setup(MockRoute{"GET", "/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82", v3WebProcessPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		process, err := client.GetV3Process("6a901b7c-9417-4dc1-8189-d3234aa0ab82")
+		So(err, ShouldBeNil)
+		So(process.MemoryInMB, ShouldEqual, 256)
+		So(process.Links["scale"].Method, ShouldEqual, "POST")
+	})
+
+	Convey("Get V3 process of an app by type", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes/worker", v3WorkerProcessPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		process, err := client.GetV3AppProcess("1cb006ee-fb05-47e1-b541-c34179ddc446", "worker")
+		So(err, ShouldBeNil)
+		So(process.GUID, ShouldEqual, "3fccacd9-4b02-4b96-8d02-8e865865e9eb")
+		So(process.HealthCheck.Type, ShouldEqual, V3HealthCheckProcess)
+	})
+}
+
+func TestScaleProcess(t *testing.T) {
+	Convey("Scale V3 process", t, func() {
+		body := `{"instances":3,"memory_in_mb":512}`
+		setup(MockRoute{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes/web/actions/scale", v3WebProcessScaledPayload, "", http.StatusAccepted, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		process, err := client.ScaleProcess("1cb006ee-fb05-47e1-b541-c34179ddc446", "web", 3, 512, 0)
+		So(err, ShouldBeNil)
+		So(process.Instances, ShouldEqual, 3)
+		So(process.MemoryInMB, ShouldEqual, 512)
+	})
+
+	Convey("Scale V3 process to zero instances", t, func() {
+		body := `{"instances":0}`
+		setup(MockRoute{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes/worker/actions/scale", v3WorkerProcessPayload, "", http.StatusAccepted, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.ScaleProcess("1cb006ee-fb05-47e1-b541-c34179ddc446", "worker", 0, 0, 0)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Scale V3 process limits only", t, func() {
+		body := `{"disk_in_mb":2048}`
+		setup(MockRoute{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes/web/actions/scale", "", "", http.StatusUnprocessableEntity, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.ScaleProcess("1cb006ee-fb05-47e1-b541-c34179ddc446", "web", -1, 0, 2048)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestUpdateProcessHealthCheck(t *testing.T) {
+	Convey("Update V3 process health check", t, func() {
+		body := `{"health_check":{"type":"http","data":{"timeout":120,"invocation_timeout":5,"endpoint":"/health"}}}`
+		setup(MockRoute{"PATCH", "/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82", v3WebProcessHTTPHealthCheckPayload, "", http.StatusOK, "", &body}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		process, err := client.UpdateProcessHealthCheck("6a901b7c-9417-4dc1-8189-d3234aa0ab82", V3HealthCheck{
+			Type: V3HealthCheckHTTP,
+			Data: V3HealthCheckData{Timeout: 120, InvocationTimeout: 5, Endpoint: "/health"},
+		})
+		So(err, ShouldBeNil)
+		So(process.HealthCheck.Data.Endpoint, ShouldEqual, "/health")
+		So(process.HealthCheck.Data.InvocationTimeout, ShouldEqual, 5)
+	})
+}
+
+func TestGetV3ProcessStats(t *testing.T) {
+	Convey("Get V3 process stats", t, func() {
+		setup(MockRoute{"GET", "/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82/stats", v3ProcessStatsPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		stats, err := client.GetV3ProcessStats("6a901b7c-9417-4dc1-8189-d3234aa0ab82")
+		So(err, ShouldBeNil)
+		So(len(stats), ShouldEqual, 2)
+		So(stats[0].State, ShouldEqual, "RUNNING")
+		So(stats[0].Usage.CPUEntitlement, ShouldEqual, 0.0125)
+		So(stats[0].Usage.Mem, ShouldEqual, 19177472)
+		So(stats[0].Uptime, ShouldEqual, 9042)
+		So(stats[0].IsolationSegment, ShouldEqual, "example_iso_segment")
+		So(stats[1].State, ShouldEqual, "DOWN")
+		So(stats[1].Usage.Time.IsZero(), ShouldBeTrue)
+		So(stats[1].Details, ShouldEqual, "insufficient resources")
+	})
+}
+
+func TestTerminateProcessInstance(t *testing.T) {
+	Convey("Terminate V3 process instance", t, func() {
+		setup(MockRoute{"DELETE", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes/web/instances/1", "", "", http.StatusNoContent, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.TerminateProcessInstance("1cb006ee-fb05-47e1-b541-c34179ddc446", "web", 1)
+		So(err, ShouldBeNil)
+
+		err = client.TerminateProcessInstance("1cb006ee-fb05-47e1-b541-c34179ddc446", "web", 7)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,166 @@
+package cfclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultRollbackTimeout  = 15 * time.Minute
+	defaultRollbackInterval = 2 * time.Second
+)
+
+// V3Revision is a snapshot of the droplet, environment variables and process
+// commands of a V3 app, recorded by the Cloud Controller when they change.
+type V3Revision struct {
+	GUID    string `json:"guid"`
+	Version int    `json:"version"`
+	// Description tells what changed since the previous revision.
+	Description string         `json:"description"`
+	Deployable  bool           `json:"deployable"`
+	Droplet     V3Relationship `json:"droplet"`
+	// Processes maps the process types to their settings in the revision.
+	Processes     map[string]V3RevisionProcess   `json:"processes"`
+	CreatedAt     time.Time                      `json:"created_at"`
+	UpdatedAt     time.Time                      `json:"updated_at"`
+	Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	Metadata      V3Metadata                     `json:"metadata"`
+	Links         map[string]V3Link              `json:"links"`
+}
+
+// V3RevisionProcess is a process type as recorded by a revision.
+type V3RevisionProcess struct {
+	Command string `json:"command"`
+}
+
+// ListV3RevisionsByApp returns the revisions of the V3 app identified by
+// appGUID. query supports the versions filter as a comma separated list.
+func (c *Client) ListV3RevisionsByApp(appGUID string, query url.Values) ([]V3Revision, error) {
+	requestURL := "/v3/apps/" + appGUID + "/revisions"
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	revisions, err := c.listV3Revisions(requestURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting revisions of app %s", appGUID)
+	}
+	return revisions, nil
+}
+
+// ListDeployedV3Revisions returns the revisions which instances of the V3 app
+// identified by appGUID run, more than one during a deployment.
+func (c *Client) ListDeployedV3Revisions(appGUID string) ([]V3Revision, error) {
+	revisions, err := c.listV3Revisions("/v3/apps/" + appGUID + "/revisions/deployed")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting deployed revisions of app %s", appGUID)
+	}
+	return revisions, nil
+}
+
+func (c *Client) listV3Revisions(requestURL string) ([]V3Revision, error) {
+	var revisions []V3Revision
+	err := c.listV3Resources(requestURL, func(resources json.RawMessage) error {
+		var page []V3Revision
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		revisions = append(revisions, page...)
+		return nil
+	})
+	return revisions, err
+}
+
+// GetV3Revision returns the V3 revision identified by guid.
+func (c *Client) GetV3Revision(guid string) (*V3Revision, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/revisions/"+guid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting revision %s", guid)
+	}
+	var revision V3Revision
+	if err := decodeV3Response(resp, http.StatusOK, &revision); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting revision %s", guid)
+	}
+	return &revision, nil
+}
+
+// GetV3RevisionEnvVars returns the user provided environment variables the
+// revision identified by guid was recorded with.
+func (c *Client) GetV3RevisionEnvVars(guid string) (map[string]string, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/revisions/"+guid+"/environment_variables"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting environment variables of revision %s", guid)
+	}
+	env, err := decodeV3AppEnvVars(resp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting environment variables of revision %s", guid)
+	}
+	return env, nil
+}
+
+// GetV3RevisionDroplet returns the droplet of the revision identified by
+// guid.
+func (c *Client) GetV3RevisionDroplet(guid string) (*V3Droplet, error) {
+	revision, err := c.GetV3Revision(guid)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetV3Droplet(revision.Droplet.GUID)
+}
+
+// RollbackOptions configures how RollbackToRevision waits for the deployment.
+// Zero values are replaced by their defaults.
+type RollbackOptions struct {
+	// Timeout defaults to 15 minutes.
+	Timeout time.Duration
+	// Interval is the time between two polls of the deployment, defaults to
+	// 2 seconds.
+	Interval time.Duration
+}
+
+// RollbackToRevision deploys the revision identified by revisionGuid to the
+// V3 app identified by appGuid with a rolling deployment, and waits for the
+// deployment to complete. Errors while polling the deployment are retried
+// until the timeout. An error is returned if the deployment is canceled or
+// superseded instead.
+func (c *Client) RollbackToRevision(appGuid, revisionGuid string, opts RollbackOptions) (*V3Deployment, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultRollbackTimeout
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultRollbackInterval
+	}
+	deployment, err := c.CreateV3Deployment(CreateV3DeploymentRequest{
+		AppGUID:      appGuid,
+		RevisionGUID: revisionGuid,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error rolling back app %s to revision %s", appGuid, revisionGuid)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	var lastErr error
+	for transition := range c.WatchDeployment(ctx, deployment.GUID, opts.Interval) {
+		if transition.Err != nil {
+			lastErr = transition.Err
+			continue
+		}
+		lastErr = nil
+		deployment = transition.Deployment
+	}
+	if deployment.Status.Value != V3DeploymentFinalized {
+		if lastErr != nil {
+			return deployment, errors.Wrapf(lastErr, "Timed out after %s rolling back app %s to revision %s", opts.Timeout, appGuid, revisionGuid)
+		}
+		return deployment, errors.Errorf("Timed out after %s rolling back app %s to revision %s", opts.Timeout, appGuid, revisionGuid)
+	}
+	if deployment.Status.Reason != V3DeploymentDeployed {
+		return deployment, errors.Errorf("Rollback of app %s to revision %s %s", appGuid, revisionGuid, deployment.Status.Reason)
+	}
+	return deployment, nil
+}
@@ -0,0 +1,185 @@
+package cfclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListV3RevisionsByApp(t *testing.T) {
+	Convey("List V3 revisions of an app", t, func() {
+		setupMultiple([]MockRoute{}, t)
+		defer teardown()
+		mux.HandleFunc("/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions", func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.RawQuery {
+			case "per_page=1":
+				fmt.Fprint(w, v3AppRevisionsFirstPagePayload)
+			case "page=2&per_page=1":
+				fmt.Fprint(w, v3AppRevisionsSecondPagePayload)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		revisions, err := client.ListV3RevisionsByApp("1cb006ee-fb05-47e1-b541-c34179ddc446", url.Values{"per_page": {"1"}})
+		So(err, ShouldBeNil)
+		So(len(revisions), ShouldEqual, 2)
+		So(revisions[0].Version, ShouldEqual, 1)
+		So(revisions[0].Processes["web"].Command, ShouldEqual, "bundle exec rackup")
+		So(revisions[1].Description, ShouldEqual, "New droplet deployed.")
+	})
+
+	Convey("List deployed V3 revisions of an app", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/revisions/deployed", v3DeployedRevisionsPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		revisions, err := client.ListDeployedV3Revisions("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(len(revisions), ShouldEqual, 1)
+		So(revisions[0].Deployable, ShouldBeTrue)
+	})
+}
+
+func TestGetV3Revision(t *testing.T) {
+	Convey("Get V3 revision with its environment variables and droplet", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/revisions/56126cba-656a-4eba-a81e-7e9951b2df57", v3RevisionPayload, "", http.StatusOK, "", nil},
+			{"GET", "/v3/revisions/56126cba-656a-4eba-a81e-7e9951b2df57/environment_variables", v3RevisionEnvVarsPayload, "", http.StatusOK, "", nil},
+			{"GET", "/v3/droplets/585bc3c1-3743-497d-88b0-403ad6b56d16", v3DropletPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		revision, err := client.GetV3Revision("56126cba-656a-4eba-a81e-7e9951b2df57")
+		So(err, ShouldBeNil)
+		So(revision.Description, ShouldEqual, "Initial revision.")
+		So(revision.Relationships["app"].Data.GUID, ShouldEqual, "1cb006ee-fb05-47e1-b541-c34179ddc446")
+
+		env, err := client.GetV3RevisionEnvVars("56126cba-656a-4eba-a81e-7e9951b2df57")
+		So(err, ShouldBeNil)
+		So(env, ShouldResemble, map[string]string{"RAILS_ENV": "production"})
+
+		droplet, err := client.GetV3RevisionDroplet("56126cba-656a-4eba-a81e-7e9951b2df57")
+		So(err, ShouldBeNil)
+		So(droplet.GUID, ShouldEqual, "585bc3c1-3743-497d-88b0-403ad6b56d16")
+	})
+}
+
+func TestRollbackToRevision(t *testing.T) {
+	Convey("Rollback to V3 revision", t, func() {
+		body := `{"revision":{"guid":"56126cba-656a-4eba-a81e-7e9951b2df57"},"relationships":{"app":{"data":{"guid":"1cb006ee-fb05-47e1-b541-c34179ddc446"}}}}`
+		mocks := []MockRoute{
+			{"POST", "/v3/deployments", v3DeploymentDeployingPayload, "", http.StatusCreated, "", &body},
+			{"GET", "/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4", v3DeploymentDeployedPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		deployment, err := client.RollbackToRevision("1cb006ee-fb05-47e1-b541-c34179ddc446", "56126cba-656a-4eba-a81e-7e9951b2df57", RollbackOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(deployment.Status.Reason, ShouldEqual, V3DeploymentDeployed)
+	})
+
+	Convey("Rollback to V3 revision canceled", t, func() {
+		mocks := []MockRoute{
+			{"POST", "/v3/deployments", v3DeploymentDeployingPayload, "", http.StatusCreated, "", nil},
+			{"GET", "/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4", v3CanaryDeploymentCanceledPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		deployment, err := client.RollbackToRevision("1cb006ee-fb05-47e1-b541-c34179ddc446", "56126cba-656a-4eba-a81e-7e9951b2df57", RollbackOptions{Interval: time.Millisecond})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "CANCELED")
+		So(deployment.Status.Reason, ShouldEqual, V3DeploymentCanceled)
+	})
+
+	Convey("Rollback to V3 revision retrying a failed poll", t, func() {
+		setupMultiple([]MockRoute{
+			{"POST", "/v3/deployments", v3DeploymentDeployingPayload, "", http.StatusCreated, "", nil},
+		}, t)
+		defer teardown()
+		var lock sync.Mutex
+		polls := 0
+		mux.HandleFunc("/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4", func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Write([]byte(v3DeploymentDeployedPayload))
+		})
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		deployment, err := client.RollbackToRevision("1cb006ee-fb05-47e1-b541-c34179ddc446", "56126cba-656a-4eba-a81e-7e9951b2df57", RollbackOptions{Interval: time.Millisecond})
+		So(err, ShouldBeNil)
+		So(deployment.Status.Reason, ShouldEqual, V3DeploymentDeployed)
+		lock.Lock()
+		defer lock.Unlock()
+		So(polls, ShouldEqual, 2)
+	})
+
+	Convey("Rollback to V3 revision failing to watch the deployment", t, func() {
+		mocks := []MockRoute{
+			{"POST", "/v3/deployments", v3DeploymentDeployingPayload, "", http.StatusCreated, "", nil},
+			{"GET", "/v3/deployments/59c3d133-2b83-46f3-960e-7765a129aea4", "", "", http.StatusNotFound, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.RollbackToRevision("1cb006ee-fb05-47e1-b541-c34179ddc446", "56126cba-656a-4eba-a81e-7e9951b2df57", RollbackOptions{
+			Timeout:  20 * time.Millisecond,
+			Interval: time.Millisecond,
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Timed out")
+	})
+}
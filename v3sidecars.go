@@ -0,0 +1,183 @@
+package cfclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// V3Sidecar is an additional process run in the containers of some process
+// types of a V3 app, e.g. an APM agent.
+type V3Sidecar struct {
+	GUID         string   `json:"guid"`
+	Name         string   `json:"name"`
+	Command      string   `json:"command"`
+	ProcessTypes []string `json:"process_types"`
+	// MemoryInMB is the memory reserved for the sidecar out of the memory of
+	// the process, zero when it shares the memory of the process.
+	MemoryInMB int `json:"memory_in_mb"`
+	// Origin is user for sidecars created through the API and buildpack for
+	// the ones declared by buildpacks.
+	Origin        string                         `json:"origin"`
+	Relationships map[string]V3ToOneRelationship `json:"relationships"`
+	CreatedAt     time.Time                      `json:"created_at"`
+	UpdatedAt     time.Time                      `json:"updated_at"`
+}
+
+// CreateV3SidecarRequest describes a sidecar to create. MemoryInMB is
+// optional.
+type CreateV3SidecarRequest struct {
+	Name         string   `json:"name"`
+	Command      string   `json:"command"`
+	ProcessTypes []string `json:"process_types"`
+	MemoryInMB   int      `json:"memory_in_mb,omitempty"`
+}
+
+// UpdateV3SidecarRequest describes the changes to a sidecar, empty fields
+// are left unchanged.
+type UpdateV3SidecarRequest struct {
+	Name         string   `json:"name,omitempty"`
+	Command      string   `json:"command,omitempty"`
+	ProcessTypes []string `json:"process_types,omitempty"`
+	MemoryInMB   int      `json:"memory_in_mb,omitempty"`
+}
+
+// CreateV3Sidecar creates a sidecar for the V3 app identified by appGUID. The
+// memory of the sidecar is checked beforehand to be less than the memory of
+// the processes it runs with, so that they are left some memory.
+func (c *Client) CreateV3Sidecar(appGUID string, r CreateV3SidecarRequest) (*V3Sidecar, error) {
+	if r.MemoryInMB > 0 {
+		if err := c.validateSidecarMemory(appGUID, r.ProcessTypes, r.MemoryInMB); err != nil {
+			return nil, err
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(r); err != nil {
+		return nil, errors.Wrap(err, "Error encoding sidecar")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("POST", "/v3/apps/"+appGUID+"/sidecars", buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error creating sidecar %s of app %s", r.Name, appGUID)
+	}
+	var sidecar V3Sidecar
+	if err := decodeV3Response(resp, http.StatusCreated, &sidecar); err != nil {
+		return nil, errors.Wrapf(err, "Error creating sidecar %s of app %s", r.Name, appGUID)
+	}
+	return &sidecar, nil
+}
+
+// GetV3Sidecar returns the sidecar identified by guid.
+func (c *Client) GetV3Sidecar(guid string) (*V3Sidecar, error) {
+	resp, err := c.DoRequest(c.NewRequest("GET", "/v3/sidecars/"+guid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting sidecar %s", guid)
+	}
+	var sidecar V3Sidecar
+	if err := decodeV3Response(resp, http.StatusOK, &sidecar); err != nil {
+		return nil, errors.Wrapf(err, "Error requesting sidecar %s", guid)
+	}
+	return &sidecar, nil
+}
+
+// UpdateV3Sidecar updates the sidecar identified by guid, see
+// UpdateV3SidecarRequest. A new memory is checked as in CreateV3Sidecar.
+func (c *Client) UpdateV3Sidecar(guid string, r UpdateV3SidecarRequest) (*V3Sidecar, error) {
+	if r.MemoryInMB > 0 {
+		sidecar, err := c.GetV3Sidecar(guid)
+		if err != nil {
+			return nil, err
+		}
+		processTypes := r.ProcessTypes
+		if len(processTypes) == 0 {
+			processTypes = sidecar.ProcessTypes
+		}
+		app := sidecar.Relationships["app"]
+		if app.Data == nil {
+			return nil, errors.Errorf("Sidecar %s has no app", guid)
+		}
+		if err := c.validateSidecarMemory(app.Data.GUID, processTypes, r.MemoryInMB); err != nil {
+			return nil, err
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(r); err != nil {
+		return nil, errors.Wrap(err, "Error encoding sidecar")
+	}
+	resp, err := c.DoRequest(c.NewRequestWithBody("PATCH", "/v3/sidecars/"+guid, buf))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error updating sidecar %s", guid)
+	}
+	var sidecar V3Sidecar
+	if err := decodeV3Response(resp, http.StatusOK, &sidecar); err != nil {
+		return nil, errors.Wrapf(err, "Error updating sidecar %s", guid)
+	}
+	return &sidecar, nil
+}
+
+// DeleteV3Sidecar deletes the sidecar identified by guid.
+func (c *Client) DeleteV3Sidecar(guid string) error {
+	resp, err := c.DoRequest(c.NewRequest("DELETE", "/v3/sidecars/"+guid))
+	if err != nil {
+		return errors.Wrapf(err, "Error deleting sidecar %s", guid)
+	}
+	if err := decodeV3Response(resp, http.StatusNoContent, nil); err != nil {
+		return errors.Wrapf(err, "Error deleting sidecar %s", guid)
+	}
+	return nil
+}
+
+// ListV3SidecarsByApp returns the sidecars of the V3 app identified by
+// appGUID.
+func (c *Client) ListV3SidecarsByApp(appGUID string) ([]V3Sidecar, error) {
+	sidecars, err := c.listV3Sidecars("/v3/apps/" + appGUID + "/sidecars")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting sidecars of app %s", appGUID)
+	}
+	return sidecars, nil
+}
+
+// ListV3SidecarsByProcess returns the sidecars run with the process
+// identified by processGUID.
+func (c *Client) ListV3SidecarsByProcess(processGUID string) ([]V3Sidecar, error) {
+	sidecars, err := c.listV3Sidecars("/v3/processes/" + processGUID + "/sidecars")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error requesting sidecars of process %s", processGUID)
+	}
+	return sidecars, nil
+}
+
+func (c *Client) listV3Sidecars(requestURL string) ([]V3Sidecar, error) {
+	var sidecars []V3Sidecar
+	err := c.listV3Resources(requestURL, func(resources json.RawMessage) error {
+		var page []V3Sidecar
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		sidecars = append(sidecars, page...)
+		return nil
+	})
+	return sidecars, err
+}
+
+// validateSidecarMemory checks that memoryInMB is less than the memory of
+// the processes of the given types of the app. Types without a process yet
+// are not checked.
+func (c *Client) validateSidecarMemory(appGUID string, processTypes []string, memoryInMB int) error {
+	processes, err := c.ListV3ProcessesByApp(appGUID)
+	if err != nil {
+		return err
+	}
+	memory := make(map[string]int, len(processes))
+	for _, process := range processes {
+		memory[process.Type] = process.MemoryInMB
+	}
+	for _, processType := range processTypes {
+		if processMemory, ok := memory[processType]; ok && memoryInMB >= processMemory {
+			return errors.Errorf("Sidecar memory of %d MB must be less than the %d MB of the %s process of app %s", memoryInMB, processMemory, processType, appGUID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package cfclient
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCreateV3Sidecar(t *testing.T) {
+	Convey("Create V3 sidecar", t, func() {
+		body := `{"name":"apm-agent","command":"./apm-agent","process_types":["web","worker"],"memory_in_mb":128}`
+		mocks := []MockRoute{
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes", v3AppProcessesPayload, "", http.StatusOK, "", nil},
+			{"POST", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/sidecars", v3SidecarPayload, "", http.StatusCreated, "", &body},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		sidecar, err := client.CreateV3Sidecar("1cb006ee-fb05-47e1-b541-c34179ddc446", CreateV3SidecarRequest{
+			Name:         "apm-agent",
+			Command:      "./apm-agent",
+			ProcessTypes: []string{"web", "worker"},
+			MemoryInMB:   128,
+		})
+		So(err, ShouldBeNil)
+		So(sidecar.GUID, ShouldEqual, "885a8cb3-c07b-4856-b448-eeb10bf36236")
+		So(sidecar.ProcessTypes, ShouldResemble, []string{"web", "worker"})
+		So(sidecar.MemoryInMB, ShouldEqual, 128)
+		So(sidecar.Origin, ShouldEqual, "user")
+	})
+
+	Convey("Create V3 sidecar using all the memory of a process", t, func() {
+		setup(MockRoute{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes", v3AppProcessesPayload, "", http.StatusOK, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.CreateV3Sidecar("1cb006ee-fb05-47e1-b541-c34179ddc446", CreateV3SidecarRequest{
+			Name:         "apm-agent",
+			Command:      "./apm-agent",
+			ProcessTypes: []string{"worker"},
+			MemoryInMB:   256,
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "worker process")
+	})
+}
+
+func TestUpdateV3Sidecar(t *testing.T) {
+	Convey("Update V3 sidecar", t, func() {
+		body := `{"command":"./apm-agent --verbose","process_types":["web"],"memory_in_mb":64}`
+		mocks := []MockRoute{
+			{"GET", "/v3/sidecars/885a8cb3-c07b-4856-b448-eeb10bf36236", v3SidecarPayload, "", http.StatusOK, "", nil},
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes", v3AppProcessesPayload, "", http.StatusOK, "", nil},
+			{"PATCH", "/v3/sidecars/885a8cb3-c07b-4856-b448-eeb10bf36236", v3SidecarUpdatedPayload, "", http.StatusOK, "", &body},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		sidecar, err := client.UpdateV3Sidecar("885a8cb3-c07b-4856-b448-eeb10bf36236", UpdateV3SidecarRequest{
+			Command:      "./apm-agent --verbose",
+			ProcessTypes: []string{"web"},
+			MemoryInMB:   64,
+		})
+		So(err, ShouldBeNil)
+		So(sidecar.Command, ShouldEqual, "./apm-agent --verbose")
+		So(sidecar.MemoryInMB, ShouldEqual, 64)
+	})
+
+	Convey("Update V3 sidecar memory beyond its processes", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/sidecars/885a8cb3-c07b-4856-b448-eeb10bf36236", v3SidecarPayload, "", http.StatusOK, "", nil},
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/processes", v3AppProcessesPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		_, err = client.UpdateV3Sidecar("885a8cb3-c07b-4856-b448-eeb10bf36236", UpdateV3SidecarRequest{MemoryInMB: 512})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "512 MB")
+	})
+}
+
+func TestListV3Sidecars(t *testing.T) {
+	Convey("List V3 sidecars of an app and of a process", t, func() {
+		mocks := []MockRoute{
+			{"GET", "/v3/apps/1cb006ee-fb05-47e1-b541-c34179ddc446/sidecars", v3SidecarsPayload, "", http.StatusOK, "", nil},
+			{"GET", "/v3/processes/6a901b7c-9417-4dc1-8189-d3234aa0ab82/sidecars", v3SidecarsPayload, "", http.StatusOK, "", nil},
+		}
+		setupMultiple(mocks, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		sidecars, err := client.ListV3SidecarsByApp("1cb006ee-fb05-47e1-b541-c34179ddc446")
+		So(err, ShouldBeNil)
+		So(len(sidecars), ShouldEqual, 2)
+		So(sidecars[1].Origin, ShouldEqual, "buildpack")
+		So(sidecars[1].MemoryInMB, ShouldEqual, 0)
+
+		sidecars, err = client.ListV3SidecarsByProcess("6a901b7c-9417-4dc1-8189-d3234aa0ab82")
+		So(err, ShouldBeNil)
+		So(len(sidecars), ShouldEqual, 2)
+	})
+}
+
+func TestDeleteV3Sidecar(t *testing.T) {
+	Convey("Delete V3 sidecar", t, func() {
+		setup(MockRoute{"DELETE", "/v3/sidecars/885a8cb3-c07b-4856-b448-eeb10bf36236", "", "", http.StatusNoContent, "", nil}, t)
+		defer teardown()
+		c := &Config{
+			ApiAddress: server.URL,
+			Token:      "foobar",
+		}
+		client, err := NewClient(c)
+		So(err, ShouldBeNil)
+
+		err = client.DeleteV3Sidecar("885a8cb3-c07b-4856-b448-eeb10bf36236")
+		So(err, ShouldBeNil)
+	})
+}